@@ -4,8 +4,18 @@ import "time"
 
 type RawEmails struct {
 	RawEmail []RawEmail `json:"emails"`
+	// Откуда пришла пачка: SourceLive, SourceBackfill или SourceReprocess; пусто — SourceLive
+	Source string `json:"source,omitempty"`
 }
 
+// Источники писем. От источника зависит очередь и приоритет в analyzer: живая почта
+// не должна ждать, пока разберётся первичная синхронизация чужого ящика.
+const (
+	SourceLive      = "live"
+	SourceBackfill  = "backfill"
+	SourceReprocess = "reprocess"
+)
+
 type RawEmail struct {
 	EmailID   string `json:"email_id"`
 	UserID    string `json:"user_id"`
@@ -15,13 +25,194 @@ type RawEmail struct {
 	Text      string `json:"body_text"`
 	Date      string `json:"date_received"`
 	TimeStamp string `json:"sync_timestamp"`
+	Timezone  string `json:"user_timezone,omitempty"`
+	// Язык задач пользователя (ISO 639-1) или LanguageAuto; пусто — язык analyzer по умолчанию
+	Language string `json:"user_language,omitempty"`
+	// Служебные заголовки (List-Unsubscribe, Precedence, Auto-Submitted и т.п.) для классификации
+	Headers map[string]string `json:"headers,omitempty"`
+	// Части text/calendar и вложения .ics без изменений; analyzer разбирает их без модели
+	Calendars []string `json:"calendars,omitempty"`
+	// HTML-часть письма: в ней бывает разметка schema.org (бронирования, доставки, счета)
+	HTML string `json:"body_html,omitempty"`
+}
+
+// LanguageAuto — писать заголовок и описание задачи на языке самого письма
+const LanguageAuto = "auto"
+
+// EmailWorkItem — одно письмо из пачки RawEmails. Analyzer раскладывает пачку на такие элементы,
+// чтобы повторялась обработка только упавших писем, а не всей пачки.
+type EmailWorkItem struct {
+	Email RawEmail `json:"email"`
+	// MessageId исходной пачки, для трассировки
+	BatchID string `json:"batch_id,omitempty"`
+	Source  string `json:"source,omitempty"`
 }
 
+// BulkEmailWorkItem — письмо первичной синхронизации или повторной обработки. Тот же EmailWorkItem,
+// но отдельный тип даёт ему отдельную очередь bulk_email_work_item_queue.
+type BulkEmailWorkItem EmailWorkItem
+
 type ParsedEmails struct {
 	UserID      string    `json:"user_id"`
 	EmailID     string    `json:"email_id"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	Deadline    time.Time `json:"deadline"`
+	Timezone    string    `json:"timezone,omitempty"`
 	From        string    `json:"from_address"`
+	// Версия промпта, по которой сделано извлечение — для сравнения версий между собой
+	PromptVersion string      `json:"prompt_version,omitempty"`
+	Confidence    *Confidence `json:"confidence,omitempty"`
+	// StatusNeedsReview, если уверенность ниже порога; пусто — обычная задача
+	Status        string   `json:"status,omitempty"`
+	ReviewReasons []string `json:"review_reasons,omitempty"`
+	// Встречи из письма. Письмо-приглашение без других дел приходит с пустым Title и только событиями.
+	Events []Event `json:"events,omitempty"`
+	// UID и SEQUENCE задачи из VTODO: по ним collector обновляет или закрывает уже созданную задачу
+	CalendarUID string `json:"calendar_uid,omitempty"`
+	Sequence    int    `json:"sequence,omitempty"`
+	// Правило повторения RFC 5545 без DTSTART ("FREQ=WEEKLY;BYDAY=MO"): первое повторение — Deadline
+	RRule string `json:"rrule,omitempty"`
+	// Категория из таксономии analyzer (work, finance, travel...); пусто — модель не выбрала подходящую
+	Category string `json:"category,omitempty"`
+	// Свободные теги в нижнем регистре: проект, контрагент, тема
+	Tags []string `json:"tags,omitempty"`
+	// Признаки важности письма; nil — модель их не оценивала (шаблонные задачи, календарь)
+	Importance *Importance `json:"importance,omitempty"`
+}
+
+// Event — встреча или мероприятие со временем начала, в отличие от задачи со сроком
+type Event struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Start       time.Time `json:"start"`
+	// nil — время окончания в письме не указано
+	End          *time.Time `json:"end,omitempty"`
+	AllDay       bool       `json:"all_day,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	JoinURL      string     `json:"join_url,omitempty"`
+	Participants []string   `json:"participants,omitempty"`
+	// Только у событий из календарных приглашений
+	UID      string `json:"uid,omitempty"`
+	Sequence int    `json:"sequence,omitempty"`
+	// StatusCancelled — встреча отменена; пусто — запланирована
+	Status string `json:"status,omitempty"`
+}
+
+// Confidence — уверенность в извлечённых полях от 0 до 1: самооценка модели с поправкой на проверки
+type Confidence struct {
+	Title    float64 `json:"title"`
+	Deadline float64 `json:"deadline"`
+}
+
+// Importance — признаки важности, которые видны только из текста письма. Приоритет задачи по ним
+// и по близости дедлайна считает collector.
+type Importance struct {
+	// В письме есть срочность: "срочно", "ASAP", "прод лежит"
+	Urgent bool `json:"urgent,omitempty"`
+	// Кто написал: SenderManager, SenderClient, SenderColleague, SenderAutomated; пусто — непонятно
+	SenderRole string `json:"sender_role,omitempty"`
+	// Приоритет, который автор указал сам: PriorityHigh или PriorityLow
+	Priority string `json:"priority,omitempty"`
+}
+
+const (
+	SenderManager   = "manager"
+	SenderClient    = "client"
+	SenderColleague = "colleague"
+	SenderAutomated = "automated"
+)
+
+const (
+	PriorityHigh = "high"
+	PriorityLow  = "low"
+)
+
+// Задача, которую пользователь должен подтвердить, исправить или отклонить
+const StatusNeedsReview = "needs_review"
+
+// Календарь закрыл задачу или встречу: CANCEL или STATUS:CANCELLED / STATUS:COMPLETED
+const (
+	StatusCancelled = "cancelled"
+	StatusCompleted = "completed"
+)
+
+// TaskCorrection публикует collector, когда пользователь исправил заголовок или срок задачи,
+// извлечённой моделью. Analyzer показывает похожие исправления модели как примеры.
+type TaskCorrection struct {
+	UserID  string `json:"user_id"`
+	EmailID string `json:"email_id"`
+	TaskID  string `json:"task_id"`
+	// Что извлекла модель и что оставил пользователь
+	Original  TaskFields `json:"original"`
+	Corrected TaskFields `json:"corrected"`
+	// Зона пользователя, в которой показывать сроки
+	Timezone    string    `json:"timezone,omitempty"`
+	CorrectedAt time.Time `json:"corrected_at"`
+}
+
+// TaskFields — поля задачи, которые пользователь исправляет чаще всего
+type TaskFields struct {
+	Title string `json:"title"`
+	// nil — задача без срока
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// TaskCompletion публикует analyzer, когда новое письмо похоже на завершение ранее извлечённой задачи:
+// "оплата получена", "посылка доставлена", "спасибо, отчёт получил". Collector закрывает задачу сам,
+// если уверенность не ниже порога, иначе отправляет её на проверку.
+type TaskCompletion struct {
+	UserID string `json:"user_id"`
+	// Письмо, из которого была извлечена задача. Из одного письма бывает несколько задач: тогда задачу
+	// определяет UID, а без UID — заголовок, с которым analyzer её опубликовал
+	TaskEmailID     string `json:"task_email_id"`
+	TaskCalendarUID string `json:"task_calendar_uid,omitempty"`
+	TaskTitle       string `json:"task_title,omitempty"`
+	// Письмо, которое её завершает
+	EmailID string `json:"email_id"`
+	// CompletionPayment, CompletionDelivery или CompletionAcknowledgement и фраза письма, по которой он найден
+	Signal     string  `json:"signal"`
+	Phrase     string  `json:"phrase,omitempty"`
+	Confidence float64 `json:"confidence"`
+	// Почему письмо отнесено к задаче: общая переписка, тот же отправитель, совпадение заголовка
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+const (
+	CompletionPayment         = "payment"
+	CompletionDelivery        = "delivery"
+	CompletionAcknowledgement = "acknowledgement"
+)
+
+// LLMUsage — расход токенов на одно обращение к модели, публикуется в обменник llm_usage
+type LLMUsage struct {
+	UserID           string    `json:"user_id"`
+	EmailID          string    `json:"email_id"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+// Ярлыки классификатора analyzer
+const (
+	LabelActionable    = "actionable"
+	LabelInformational = "informational"
+	LabelSpam          = "spam"
+)
+
+// Итог обработки письма analyzer: ярлык классификатора и что с письмом сделано
+const (
+	StatusExtracted = "extracted"
+	StatusSkipped   = "skipped"
+)
+
+// EmailProcessed публикуется для каждого письма, в том числе пропущенного классификатором
+type EmailProcessed struct {
+	UserID  string   `json:"user_id"`
+	EmailID string   `json:"email_id"`
+	Label   string   `json:"label"`
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons,omitempty"`
 }
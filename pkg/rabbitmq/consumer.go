@@ -17,6 +17,41 @@ type IConsumer[T any] interface {
 	IsConsumed(msg interface{}) bool
 }
 
+// Job — полученное сообщение вместе с его обработкой, подтверждением и повтором при ошибке
+type Job struct {
+	Delivery amqp.Delivery
+	// Очередь, из которой пришло сообщение
+	Queue string
+	run   func()
+}
+
+// Run обрабатывает сообщение тем же обработчиком, что и консьюмер без планировщика
+func (j *Job) Run() {
+	j.run()
+}
+
+// Scheduler принимает полученные сообщения и сам решает, в каком порядке их обрабатывать.
+// Сообщения в нём не подтверждены, поэтому при остановке сервиса брокер вернёт их в очередь.
+type Scheduler interface {
+	Push(job *Job)
+}
+
+type ConsumerOption func(*consumerOptions)
+
+type consumerOptions struct {
+	scheduler Scheduler
+	prefetch  int
+}
+
+// WithScheduler отдаёт сообщения планировщику вместо обработки по порядку. prefetch — сколько
+// неподтверждённых сообщений брокер пришлёт заранее: среди них планировщик и выбирает следующее.
+func WithScheduler(scheduler Scheduler, prefetch int) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.scheduler = scheduler
+		o.prefetch = prefetch
+	}
+}
+
 type Consumer[T any] struct {
 	cfg              *RabbitMQConfig
 	conn             *amqp.Connection
@@ -25,6 +60,8 @@ type Consumer[T any] struct {
 	ctx              context.Context
 	consumedMessages map[string]bool
 	mu               sync.Mutex
+	scheduler        Scheduler
+	prefetch         int
 }
 
 func (c *Consumer[T]) ConsumeMessage(msg interface{}, dependencies T) error {
@@ -63,14 +100,25 @@ func (c *Consumer[T]) ConsumeMessage(msg interface{}, dependencies T) error {
 		nil,   // arguments
 	)
 
+	if err != nil {
+		c.log.Error(c.ctx, "Error in declaring queue to consume message", "error", err)
+		return err
+	}
+
+	policy := c.cfg.RetryPolicy()
+	if err := declareRetryTopology(ch, q.Name, policy); err != nil {
+		c.log.Error(c.ctx, "Error in declaring retry queues", "queue", q.Name, "error", err)
+		return err
+	}
+
 	err = ch.Qos(
-		1,
+		max(1, c.prefetch),
 		0,
 		false,
 	)
 
 	if err != nil {
-		c.log.Error(c.ctx, "Error in declaring queue to consume message")
+		c.log.Error(c.ctx, "Error in setting QoS to consume message", "error", err)
 		return err
 	}
 
@@ -119,32 +167,13 @@ func (c *Consumer[T]) ConsumeMessage(msg interface{}, dependencies T) error {
 					return
 				}
 
-				//For ServiceAnalyzer it will be function related to LLM processing
-				//For Collector it will be function, which stores values into DB
-				err := c.handler(q.Name, delivery, dependencies)
-				if err != nil {
-					c.log.Error(savedCtx, "Handler error", "error", err, "error_string", err.Error())
-					// Пытаемся сделать Nack, но не падаем если канал уже закрыт
-					if nackErr := delivery.Nack(false, true); nackErr != nil {
-						c.log.Warn(savedCtx, "Failed to Nack delivery", "error", nackErr)
-					}
-					continue
-				}
-
-				// Если обработка успешна, делаем Ack
-				if ackErr := delivery.Ack(false); ackErr != nil {
-					c.log.Error(savedCtx, "Failed to Ack delivery", "error", ackErr)
-					// Если Ack не удался, пытаемся сделать Nack
-					if nackErr := delivery.Nack(false, true); nackErr != nil {
-						c.log.Warn(savedCtx, "Failed to Nack delivery after Ack error", "error", nackErr)
-					}
+				if c.scheduler != nil {
+					c.scheduler.Push(&Job{Delivery: delivery, Queue: q.Name, run: func() {
+						c.handle(savedCtx, ch, q.Name, snakeTypeName, policy, delivery, dependencies)
+					}})
 					continue
 				}
-
-				// Отмечаем сообщение как обработанное только после успешного Ack
-				c.mu.Lock()
-				c.consumedMessages[snakeTypeName] = true
-				c.mu.Unlock()
+				c.handle(savedCtx, ch, q.Name, snakeTypeName, policy, delivery, dependencies)
 			}
 		}
 
@@ -154,6 +183,55 @@ func (c *Consumer[T]) ConsumeMessage(msg interface{}, dependencies T) error {
 	return nil
 }
 
+// handle обрабатывает одно сообщение и подтверждает его или отправляет на повтор.
+// С планировщиком вызывается из его воркеров: методы канала можно вызывать конкурентно.
+func (c *Consumer[T]) handle(ctx context.Context, ch *amqp.Channel, queue, snakeTypeName string, policy RetryPolicy, delivery amqp.Delivery, dependencies T) {
+	//For ServiceAnalyzer it will be function related to LLM processing
+	//For Collector it will be function, which stores values into DB
+	err := c.handler(queue, delivery, dependencies)
+	if err != nil {
+		c.log.Error(ctx, "Handler error", "error", err, "error_string", err.Error())
+		c.retry(ctx, ch, queue, policy, delivery, err)
+		return
+	}
+
+	// Если обработка успешна, делаем Ack
+	if ackErr := delivery.Ack(false); ackErr != nil {
+		c.log.Error(ctx, "Failed to Ack delivery", "error", ackErr)
+		// Если Ack не удался, пытаемся сделать Nack
+		if nackErr := delivery.Nack(false, true); nackErr != nil {
+			c.log.Warn(ctx, "Failed to Nack delivery after Ack error", "error", nackErr)
+		}
+		return
+	}
+
+	// Отмечаем сообщение как обработанное только после успешного Ack
+	c.mu.Lock()
+	c.consumedMessages[snakeTypeName] = true
+	c.mu.Unlock()
+}
+
+// retry переотправляет сообщение с задержкой вместо мгновенного возврата в очередь,
+// чтобы "ядовитое" сообщение не блокировало консьюмер с prefetch 1
+func (c *Consumer[T]) retry(ctx context.Context, ch *amqp.Channel, queue string, policy RetryPolicy, delivery amqp.Delivery, handlerErr error) {
+	dead, err := handleFailure(ch, queue, policy, delivery, handlerErr)
+	if err != nil {
+		c.log.Error(ctx, "Failed to schedule retry, requeueing", "queue", queue, "error", err)
+		// Пытаемся сделать Nack, но не падаем если канал уже закрыт
+		if nackErr := delivery.Nack(false, true); nackErr != nil {
+			c.log.Warn(ctx, "Failed to Nack delivery", "error", nackErr)
+		}
+		return
+	}
+
+	if dead {
+		c.log.Warn(ctx, "Message moved to dead letters", "queue", queue, "message_id", delivery.MessageId)
+	}
+	if ackErr := delivery.Ack(false); ackErr != nil {
+		c.log.Warn(ctx, "Failed to Ack delivery after scheduling retry", "error", ackErr)
+	}
+}
+
 func (c *Consumer[T]) IsConsumed(msg interface{}) bool {
 	timeOutTime := 20 * time.Second
 	startTime := time.Now()
@@ -180,8 +258,14 @@ func (c *Consumer[T]) IsConsumed(msg interface{}) bool {
 	}
 }
 
-func NewConsumer[T any](ctx context.Context, cfg *RabbitMQConfig, conn *amqp.Connection, log *logger.CurrentLogger, handler func(queue string, msg amqp.Delivery, dependencies T) error) IConsumer[T] {
+func NewConsumer[T any](ctx context.Context, cfg *RabbitMQConfig, conn *amqp.Connection, log *logger.CurrentLogger, handler func(queue string, msg amqp.Delivery, dependencies T) error, opts ...ConsumerOption) IConsumer[T] {
+	var o consumerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	return &Consumer[T]{
+		scheduler:        o.scheduler,
+		prefetch:         o.prefetch,
 		ctx:              ctx,
 		cfg:              cfg,
 		conn:             conn,
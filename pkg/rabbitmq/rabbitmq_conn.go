@@ -17,6 +17,10 @@ type RabbitMQConfig struct {
 	Password     string `env:"PASSWORD" env-default:"rabbit"`
 	ExchangeName string `env:"EXCHANGE" env-default:"donotmatter"`
 	Kind         string `env:"KIND" env-default:"direct"`
+	// Повторы при ошибке обработчика: после MaxAttempts сообщение уходит в dead_letters
+	MaxAttempts   int           `env:"MAX_ATTEMPTS" env-default:"5"`
+	RetryDelay    time.Duration `env:"RETRY_DELAY" env-default:"5s"`
+	MaxRetryDelay time.Duration `env:"MAX_RETRY_DELAY" env-default:"5m"`
 }
 
 // Initialize new channel for rabbitmq
@@ -0,0 +1,142 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Заголовки, которыми консьюмер помечает сообщения при повторной обработке
+const (
+	HeaderRetryCount    = "x-retry-count"
+	HeaderLastError     = "x-last-error"
+	HeaderOriginalQueue = "x-original-queue"
+	HeaderFailedAt      = "x-failed-at"
+	// Сообщение вручную возвращено из мёртвых писем
+	HeaderRequeued = "x-requeued"
+
+	// Обменник, куда попадают сообщения, исчерпавшие попытки; ключ маршрутизации — имя исходной очереди
+	DeadLetterExchange = "dead_letters"
+
+	defaultMaxAttempts   = 5
+	defaultRetryDelay    = 5 * time.Second
+	defaultMaxRetryDelay = 5 * time.Minute
+)
+
+// RetryPolicy — сколько раз и с какой задержкой повторять обработку сообщения
+type RetryPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+	MaxDelay    time.Duration
+}
+
+// RetryPolicy возвращает политику из конфига, подставляя значения по умолчанию для незаданных полей
+func (c *RabbitMQConfig) RetryPolicy() RetryPolicy {
+	p := RetryPolicy{MaxAttempts: c.MaxAttempts, Delay: c.RetryDelay, MaxDelay: c.MaxRetryDelay}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.Delay <= 0 {
+		p.Delay = defaultRetryDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultMaxRetryDelay
+	}
+	return p
+}
+
+// Backoff — задержка перед попыткой номер attempt+1: Delay, 2*Delay, 4*Delay... но не больше MaxDelay
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// RetryQueueName — очередь ожидания для задержки delay. Задержка входит в имя,
+// чтобы смена настроек не конфликтовала с уже объявленными очередями.
+func RetryQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, delay)
+}
+
+func DeadLetterQueueName(queue string) string {
+	return queue + ".dead"
+}
+
+// RetryCount читает счётчик попыток из заголовков; AMQP может вернуть число разных типов
+func RetryCount(headers amqp.Table) int {
+	switch v := headers[HeaderRetryCount].(type) {
+	case int:
+		return v
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+	return 0
+}
+
+// declareRetryTopology объявляет очереди ожидания и очередь мёртвых писем для queue.
+// Очереди ожидания без консьюмеров: по истечении TTL сообщение возвращается в queue через обменник по умолчанию.
+func declareRetryTopology(ch *amqp.Channel, queue string, policy RetryPolicy) error {
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		delay := policy.Backoff(attempt)
+		_, err := ch.QueueDeclare(RetryQueueName(queue, delay), true, false, false, false, amqp.Table{
+			"x-message-ttl":             int64(delay / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := ch.ExchangeDeclare(DeadLetterExchange, "direct", true, false, false, false, nil); err != nil {
+		return err
+	}
+	dead, err := ch.QueueDeclare(DeadLetterQueueName(queue), true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	return ch.QueueBind(dead.Name, queue, DeadLetterExchange, false, nil)
+}
+
+// failurePublishing готовит копию сообщения для следующей попытки или для очереди мёртвых писем
+func failurePublishing(delivery amqp.Delivery, queue string, attempt int, handlerErr error) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range delivery.Headers {
+		headers[k] = v
+	}
+	headers[HeaderRetryCount] = int32(attempt)
+	headers[HeaderLastError] = handlerErr.Error()
+	headers[HeaderOriginalQueue] = queue
+	headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	return amqp.Publishing{
+		Headers:       headers,
+		ContentType:   delivery.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     delivery.MessageId,
+		CorrelationId: delivery.CorrelationId,
+		Timestamp:     delivery.Timestamp,
+		Body:          delivery.Body,
+	}
+}
+
+// handleFailure отправляет сообщение в очередь ожидания или, если попытки исчерпаны, в dead_letters
+func handleFailure(ch *amqp.Channel, queue string, policy RetryPolicy, delivery amqp.Delivery, handlerErr error) (dead bool, err error) {
+	attempt := RetryCount(delivery.Headers) + 1
+	msg := failurePublishing(delivery, queue, attempt, handlerErr)
+
+	if attempt >= policy.MaxAttempts {
+		return true, ch.Publish(DeadLetterExchange, queue, false, false, msg)
+	}
+	return false, ch.Publish("", RetryQueueName(queue, policy.Backoff(attempt)), false, false, msg)
+}
@@ -0,0 +1,77 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Defaults(t *testing.T) {
+	policy := (&RabbitMQConfig{}).RetryPolicy()
+
+	assert.Equal(t, RetryPolicy{MaxAttempts: 5, Delay: 5 * time.Second, MaxDelay: 5 * time.Minute}, policy)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, Delay: time.Second, MaxDelay: 10 * time.Second}
+
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 2*time.Second, policy.Backoff(2))
+	assert.Equal(t, 8*time.Second, policy.Backoff(4))
+	assert.Equal(t, 10*time.Second, policy.Backoff(5))
+	assert.Equal(t, 10*time.Second, policy.Backoff(9))
+}
+
+func TestRetryCount(t *testing.T) {
+	assert.Equal(t, 0, RetryCount(nil))
+	assert.Equal(t, 2, RetryCount(amqp.Table{HeaderRetryCount: int32(2)}))
+	assert.Equal(t, 3, RetryCount(amqp.Table{HeaderRetryCount: int64(3)}))
+	assert.Equal(t, 0, RetryCount(amqp.Table{HeaderRetryCount: "3"}))
+}
+
+func TestFailurePublishing_KeepsMessageAndAddsHeaders(t *testing.T) {
+	delivery := amqp.Delivery{
+		Headers:       amqp.Table{"custom": "value", HeaderRetryCount: int32(1)},
+		ContentType:   "application/json",
+		MessageId:     "message-1",
+		CorrelationId: "correlation-1",
+		Body:          []byte(`{"emails":[]}`),
+	}
+
+	msg := failurePublishing(delivery, "raw_emails_queue", 2, errors.New("invalid JSON"))
+
+	assert.Equal(t, "message-1", msg.MessageId)
+	assert.Equal(t, "correlation-1", msg.CorrelationId)
+	assert.Equal(t, delivery.Body, msg.Body)
+	assert.Equal(t, "value", msg.Headers["custom"])
+	assert.Equal(t, 2, RetryCount(msg.Headers))
+	assert.Equal(t, "invalid JSON", msg.Headers[HeaderLastError])
+	assert.Equal(t, "raw_emails_queue", msg.Headers[HeaderOriginalQueue])
+	// Исходная доставка не меняется
+	assert.Equal(t, 1, RetryCount(delivery.Headers))
+}
+
+func TestQueueNames(t *testing.T) {
+	assert.Equal(t, "raw_emails_queue.retry.10s", RetryQueueName("raw_emails_queue", 10*time.Second))
+	assert.Equal(t, "raw_emails_queue.dead", DeadLetterQueueName("raw_emails_queue"))
+}
+
+func TestToDeadLetter(t *testing.T) {
+	letter := toDeadLetter("raw_emails_queue", amqp.Delivery{
+		MessageId: "message-1",
+		Headers:   amqp.Table{HeaderRetryCount: int32(5), HeaderLastError: "boom", HeaderFailedAt: "2025-12-10T10:00:00Z"},
+		Body:      []byte("{}"),
+	})
+
+	assert.Equal(t, DeadLetter{
+		MessageID: "message-1",
+		Queue:     "raw_emails_queue",
+		Attempts:  5,
+		LastError: "boom",
+		FailedAt:  "2025-12-10T10:00:00Z",
+		Body:      "{}",
+	}, letter)
+}
@@ -9,12 +9,15 @@ import (
 )
 
 type Config struct {
-	Host     string
-	Port     int
-	Password string
-	DB       int
+	Host     string `env:"HOST" env-default:"localhost"`
+	Port     int    `env:"PORT" env-default:"6379"`
+	Password string `env:"PASSWORD" env-default:""`
+	DB       int    `env:"DB" env-default:"0"`
 }
 
+// Nil возвращается из Get, если ключа нет
+const Nil = redis.Nil
+
 type Client struct {
 	client *redis.Client
 }
@@ -53,6 +56,11 @@ func (c *Client) Get(ctx context.Context, key string) (string, error) {
 	return c.client.Get(ctx, key).Result()
 }
 
+// SetNX записывает значение, только если ключа ещё нет, и сообщает, удалось ли это
+func (c *Client) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, value, ttl).Result()
+}
+
 func (c *Client) Exists(ctx context.Context, key string) (bool, error) {
 	count, err := c.client.Exists(ctx, key).Result()
 	if err != nil {
@@ -64,3 +72,19 @@ func (c *Client) Exists(ctx context.Context, key string) (bool, error) {
 func (c *Client) Delete(ctx context.Context, key string) error {
 	return c.client.Del(ctx, key).Err()
 }
+
+func (c *Client) HIncrBy(ctx context.Context, key, field string, incr int64) error {
+	return c.client.HIncrBy(ctx, key, field, incr).Err()
+}
+
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return c.client.HGetAll(ctx, key).Result()
+}
+
+func (c *Client) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return c.client.Expire(ctx, key, ttl).Err()
+}
+
+func (c *Client) HSet(ctx context.Context, key string, values map[string]string) error {
+	return c.client.HSet(ctx, key, values).Err()
+}
@@ -0,0 +1,166 @@
+// Команда eval прогоняет размеченный корпус писем через извлечение и считает метрики качества.
+//
+//	go run ./cmd/eval -corpus internal/eval/testdata/corpus.jsonl -provider replay -replay internal/eval/testdata/replay.json -out report.json
+//	go run ./cmd/eval -corpus corpus.jsonl -provider mistral -record replay.json -baseline report.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/logger/zaplogger"
+	"reminder-hub/services/analyzer/internal/ai_agent/mistral"
+	"reminder-hub/services/analyzer/internal/deadline"
+	"reminder-hub/services/analyzer/internal/eval"
+	"reminder-hub/services/analyzer/internal/prompt"
+	"reminder-hub/services/analyzer/internal/redact"
+
+	"github.com/tmc/langchaingo/llms"
+	lcmistral "github.com/tmc/langchaingo/llms/mistral"
+	"go.uber.org/fx"
+)
+
+// lifecycle нужен логгеру, который рассчитан на fx; хуки выполняются при выходе
+type lifecycle struct {
+	hooks []fx.Hook
+}
+
+func (l *lifecycle) Append(hook fx.Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+func (l *lifecycle) stop() {
+	for _, hook := range l.hooks {
+		if hook.OnStop != nil {
+			_ = hook.OnStop(context.Background())
+		}
+	}
+}
+
+type options struct {
+	corpus         string
+	provider       string
+	replay         string
+	record         string
+	model          string
+	promptVersion  string
+	promptDir      string
+	timezone       string
+	tolerance      time.Duration
+	titleThreshold float64
+	redact         bool
+	out            string
+	baseline       string
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.corpus, "corpus", "", "labelled corpus in JSONL")
+	flag.StringVar(&opts.provider, "provider", "replay", "model provider: replay or mistral")
+	flag.StringVar(&opts.replay, "replay", "", "recorded responses for the replay provider")
+	flag.StringVar(&opts.record, "record", "", "save live responses to this file for later replay")
+	flag.StringVar(&opts.model, "model", "open-mistral-7b", "model name for the live provider")
+	flag.StringVar(&opts.promptVersion, "prompt-version", "v1", "prompt version to evaluate")
+	flag.StringVar(&opts.promptDir, "prompt-dir", "", "directory with additional prompt templates")
+	flag.StringVar(&opts.timezone, "timezone", deadline.DefaultTimezone, "timezone for cases without one")
+	flag.DurationVar(&opts.tolerance, "tolerance", time.Hour, "deadline tolerance")
+	flag.Float64Var(&opts.titleThreshold, "title-threshold", 0.5, "minimum title similarity for an item to count as found")
+	flag.BoolVar(&opts.redact, "redact", true, "redact PII as the service does")
+	flag.StringVar(&opts.out, "out", "", "write the JSON report to this file")
+	flag.StringVar(&opts.baseline, "baseline", "", "previous report to compare against")
+	flag.Parse()
+
+	if err := run(opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(opts options) error {
+	if opts.corpus == "" {
+		return errors.New("-corpus is required")
+	}
+
+	cases, err := eval.LoadCorpus(opts.corpus)
+	if err != nil {
+		return err
+	}
+
+	llm, recorder, err := newModel(opts)
+	if err != nil {
+		return err
+	}
+
+	prompts, err := prompt.New(context.Background(), &prompt.Config{Dir: opts.promptDir, ActiveVersion: opts.promptVersion}, nil)
+	if err != nil {
+		return err
+	}
+
+	var redactor *redact.Redactor
+	if opts.redact {
+		redactor = redact.New(&redact.Config{Enabled: true, Phones: true, Cards: true, IBANs: true, Passports: true, OTP: true})
+	}
+
+	lc := &lifecycle{}
+	defer lc.stop()
+	// production-уровень логов, чтобы отладочный вывод не смешивался с отчётом
+	evalLog := logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(lc, "production"))
+
+	agent := mistral.NewWithModel(llm, opts.model, &deadline.Config{DefaultTimezone: opts.timezone}, prompts, redactor)
+	report := eval.Run(context.Background(), cases, agent, eval.Options{Tolerance: opts.tolerance, TitleThreshold: opts.titleThreshold}, evalLog)
+	report.Run.Provider = opts.provider
+	report.Run.Model = opts.model
+	report.Run.PromptVersion = opts.promptVersion
+	report.Run.Corpus = opts.corpus
+
+	if recorder != nil {
+		if err := recorder.Save(opts.record); err != nil {
+			return err
+		}
+	}
+	if opts.out != "" {
+		if err := report.Save(opts.out); err != nil {
+			return err
+		}
+	}
+
+	var baseline *eval.Report
+	if opts.baseline != "" {
+		if baseline, err = eval.LoadReport(opts.baseline); err != nil {
+			return err
+		}
+	}
+	eval.Compare(os.Stdout, report, baseline)
+	return nil
+}
+
+func newModel(opts options) (llms.Model, *eval.Recorder, error) {
+	switch opts.provider {
+	case "replay":
+		if opts.replay == "" {
+			return nil, nil, errors.New("-replay is required for the replay provider")
+		}
+		replay, err := eval.LoadReplay(opts.replay)
+		return replay, nil, err
+	case "mistral":
+		apiKey := os.Getenv("MISTRAL_API_KEY")
+		if apiKey == "" {
+			return nil, nil, errors.New("MISTRAL_API_KEY is required for the mistral provider")
+		}
+		llm, err := lcmistral.New(lcmistral.WithAPIKey(apiKey), lcmistral.WithModel(opts.model))
+		if err != nil {
+			return nil, nil, err
+		}
+		if opts.record == "" {
+			return llm, nil, nil
+		}
+		recorder := eval.NewRecorder(llm)
+		return recorder, recorder, nil
+	}
+	return nil, nil, fmt.Errorf("unknown provider %q", opts.provider)
+}
@@ -5,11 +5,26 @@ import (
 	"reminder-hub/pkg/rabbitmq"
 	aiagent "reminder-hub/services/analyzer/internal/ai_agent"
 	"reminder-hub/services/analyzer/internal/ai_agent/mistral"
+	"reminder-hub/services/analyzer/internal/api"
+	"reminder-hub/services/analyzer/internal/budget"
+	"reminder-hub/services/analyzer/internal/cache"
+	"reminder-hub/services/analyzer/internal/category"
+	"reminder-hub/services/analyzer/internal/cleaner"
+	"reminder-hub/services/analyzer/internal/completion"
+	"reminder-hub/services/analyzer/internal/confidence"
 	"reminder-hub/services/analyzer/internal/config"
+	"reminder-hub/services/analyzer/internal/correction"
+	"reminder-hub/services/analyzer/internal/fairqueue"
 	"reminder-hub/services/analyzer/internal/middleware/configurations"
+	"reminder-hub/services/analyzer/internal/prompt"
 	rc "reminder-hub/services/analyzer/internal/rabbitmq"
+	"reminder-hub/services/analyzer/internal/ratelimit"
+	"reminder-hub/services/analyzer/internal/redact"
+	"reminder-hub/services/analyzer/internal/redisclient"
+	"reminder-hub/services/analyzer/internal/routing"
 	"reminder-hub/services/analyzer/internal/server"
 	"reminder-hub/services/analyzer/internal/server/echoserver"
+	"reminder-hub/services/analyzer/internal/workitem"
 
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/fx"
@@ -24,12 +39,28 @@ func main() {
 				echoserver.NewEchoServer,
 				rabbitmq.NewRabbitMQConn,
 				rabbitmq.NewPublisher,
+				rabbitmq.NewDeadLetters,
 				validator.New,
+				prompt.NewStore,
+				redisclient.New,
+				cache.NewRedisCache,
+				budget.NewTracker,
+				redact.New,
+				workitem.NewStore,
+				confidence.New,
+				ratelimit.New,
+				fairqueue.New,
+				category.New,
+				routing.New,
+				cleaner.New,
+				correction.NewStore,
+				completion.NewIndex,
 				mistral.NewMistralConn,
 				aiagent.NewAgent,
 			),
 			fx.Invoke(server.RunServers),
 			fx.Invoke(configurations.ConfigMiddlewares),
+			fx.Invoke(api.ConfigRoutes),
 			fx.Invoke(rc.ConfigConsumers),
 		),
 	).Run()
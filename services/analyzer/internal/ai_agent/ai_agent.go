@@ -10,6 +10,8 @@ import (
 
 type AiAgent interface {
 	ConvertEmail(ctx context.Context, queue string, msg amqp.Delivery, dependencies *delivery.AnalyzerDeliveryBase) error
+	ProcessWorkItem(ctx context.Context, queue string, msg amqp.Delivery, dependencies *delivery.AnalyzerDeliveryBase) error
+	LearnCorrection(ctx context.Context, queue string, msg amqp.Delivery, dependencies *delivery.AnalyzerDeliveryBase) error
 }
 
 type Agent struct {
@@ -23,3 +25,16 @@ func NewAgent(mistralAgent *mistral.MistralAgent) *Agent {
 func (a *Agent) ConvertEmail(queue string, msg amqp.Delivery, dependencies *delivery.AnalyzerDeliveryBase) error {
 	return a.mistralAgent.ConvertEmail(dependencies.Ctx, queue, msg, dependencies)
 }
+
+func (a *Agent) ProcessWorkItem(queue string, msg amqp.Delivery, dependencies *delivery.AnalyzerDeliveryBase) error {
+	return a.mistralAgent.ProcessWorkItem(dependencies.Ctx, queue, msg, dependencies)
+}
+
+func (a *Agent) LearnCorrection(queue string, msg amqp.Delivery, dependencies *delivery.AnalyzerDeliveryBase) error {
+	return a.mistralAgent.LearnCorrection(dependencies.Ctx, queue, msg, dependencies)
+}
+
+// WaitReady держит воркер, пока основная модель не готова принять вызов
+func (a *Agent) WaitReady(ctx context.Context) error {
+	return a.mistralAgent.WaitReady(ctx)
+}
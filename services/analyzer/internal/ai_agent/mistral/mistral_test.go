@@ -9,6 +9,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"reminder-hub/pkg/logger"
 	"reminder-hub/pkg/logger/zaplogger"
+	"reminder-hub/services/analyzer/internal/classifier"
+	"reminder-hub/services/analyzer/internal/deadline"
+	"reminder-hub/services/analyzer/internal/injection"
 	"go.uber.org/fx"
 )
 
@@ -45,7 +48,14 @@ func TestMistralConfig_DefaultModel(t *testing.T) {
 	cfg.SetAPI("test-key")
 	
 	// Проверяем, что модель устанавливается по умолчанию
-	_, err := NewMistralConn(ctx, cfg, logger)
+	_, err := NewMistralConn(MistralParams{
+		Ctx:        ctx,
+		Config:     cfg,
+		Deadline:   &deadline.Config{},
+		Classifier: &classifier.Config{},
+		Injection:  &injection.Config{},
+		Log:        logger,
+	})
 	// Ожидаем ошибку от mistral.New, но модель должна быть установлена
 	if err != nil {
 		// Это нормально, так как мы не подключаемся к реальному API
@@ -63,7 +73,14 @@ func TestNewMistralConn_EmptyAPIKey(t *testing.T) {
 	logger := logger.NewCurrentLogger(adapter)
 	ctx := context.Background()
 	
-	agent, err := NewMistralConn(ctx, cfg, logger)
+	agent, err := NewMistralConn(MistralParams{
+		Ctx:        ctx,
+		Config:     cfg,
+		Deadline:   &deadline.Config{},
+		Classifier: &classifier.Config{},
+		Injection:  &injection.Config{},
+		Log:        logger,
+	})
 	
 	assert.Nil(t, agent)
 	assert.Error(t, err)
@@ -85,7 +102,14 @@ func TestNewMistralConn_WithAPIKey(t *testing.T) {
 	
 	// Этот тест может упасть, если нет реального подключения к Mistral API
 	// Но мы проверяем, что функция пытается создать соединение
-	agent, err := NewMistralConn(ctx, cfg, logger)
+	agent, err := NewMistralConn(MistralParams{
+		Ctx:        ctx,
+		Config:     cfg,
+		Deadline:   &deadline.Config{},
+		Classifier: &classifier.Config{},
+		Injection:  &injection.Config{},
+		Log:        logger,
+	})
 	
 	// Если ошибка, это нормально для unit-теста без реального API
 	if err != nil {
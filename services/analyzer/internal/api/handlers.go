@@ -0,0 +1,353 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/models"
+	rmq "reminder-hub/pkg/rabbitmq"
+	"reminder-hub/services/analyzer/internal/ai_agent/mistral"
+	"reminder-hub/services/analyzer/internal/budget"
+	"reminder-hub/services/analyzer/internal/cache"
+	"reminder-hub/services/analyzer/internal/cleaner"
+	"reminder-hub/services/analyzer/internal/locale"
+	"reminder-hub/services/analyzer/internal/prompt"
+	"reminder-hub/services/analyzer/internal/redact"
+	"reminder-hub/services/analyzer/internal/routing"
+	"reminder-hub/services/analyzer/internal/workitem"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type PromptHandler struct {
+	store *prompt.Store
+}
+
+func NewPromptHandler(store *prompt.Store) *PromptHandler {
+	return &PromptHandler{store: store}
+}
+
+type promptsResponse struct {
+	Versions []string       `json:"versions"`
+	Split    map[string]int `json:"split"`
+}
+
+type setActiveRequest struct {
+	Version string `json:"version"`
+}
+
+type setSplitRequest struct {
+	Weights map[string]int `json:"weights"`
+}
+
+func (h *PromptHandler) GetPrompts(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.response())
+}
+
+// SetActive переключает весь трафик на одну версию промпта
+func (h *PromptHandler) SetActive(c echo.Context) error {
+	var req setActiveRequest
+	if err := c.Bind(&req); err != nil || req.Version == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "version is required")
+	}
+	return h.setSplit(c, map[string]int{req.Version: 1})
+}
+
+// SetSplit распределяет трафик между версиями по весам для A/B сравнения
+func (h *PromptHandler) SetSplit(c echo.Context) error {
+	var req setSplitRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	return h.setSplit(c, req.Weights)
+}
+
+func (h *PromptHandler) setSplit(c echo.Context, weights map[string]int) error {
+	if err := h.store.SetSplit(c.Request().Context(), weights); err != nil {
+		if errors.Is(err, prompt.ErrUnknownVersion) || errors.Is(err, prompt.ErrEmptySplit) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+	return c.JSON(http.StatusOK, h.response())
+}
+
+func (h *PromptHandler) response() promptsResponse {
+	return promptsResponse{Versions: h.store.Versions(), Split: h.store.Split()}
+}
+
+type CacheHandler struct {
+	cache *cache.Cache
+}
+
+func NewCacheHandler(llmCache *cache.Cache) *CacheHandler {
+	return &CacheHandler{cache: llmCache}
+}
+
+func (h *CacheHandler) GetStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.cache.Stats())
+}
+
+type RedactionHandler struct {
+	redactor *redact.Redactor
+}
+
+func NewRedactionHandler(redactor *redact.Redactor) *RedactionHandler {
+	return &RedactionHandler{redactor: redactor}
+}
+
+// GetStats возвращает, сколько сущностей каждого типа было скрыто от модели с запуска сервиса
+func (h *RedactionHandler) GetStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.redactor.Stats())
+}
+
+type RoutingHandler struct {
+	router *routing.Router
+}
+
+func NewRoutingHandler(router *routing.Router) *RoutingHandler {
+	return &RoutingHandler{router: router}
+}
+
+// GetStats возвращает по каждому маршруту число писем, долю эскалаций в большую модель и стоимость вызовов
+func (h *RoutingHandler) GetStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.router.Stats())
+}
+
+type CleanerHandler struct {
+	cleaner *cleaner.Cleaner
+}
+
+func NewCleanerHandler(contentCleaner *cleaner.Cleaner) *CleanerHandler {
+	return &CleanerHandler{cleaner: contentCleaner}
+}
+
+type cleanRequest struct {
+	Body string `json:"body"`
+}
+
+// Clean показывает, каким текст письма уйдёт модели: без цитат, подписей и дисклеймеров, обрезанный по бюджету.
+// Персональные данные в ответе не скрываются — это делается уже после очистки.
+func (h *CleanerHandler) Clean(c echo.Context) error {
+	var req cleanRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if len(req.Body) > maxAnalyzeSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "email is too large")
+	}
+	return c.JSON(http.StatusOK, h.cleaner.Clean(req.Body))
+}
+
+type UsageHandler struct {
+	tracker *budget.Tracker
+}
+
+func NewUsageHandler(tracker *budget.Tracker) *UsageHandler {
+	return &UsageHandler{tracker: tracker}
+}
+
+// GetUsage возвращает расход токенов пользователя за текущие день и месяц вместе с лимитами
+func (h *UsageHandler) GetUsage(c echo.Context) error {
+	userID := c.Param("user_id")
+	if userID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "user_id is required")
+	}
+
+	report, err := h.tracker.Report(c.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+type DeadLetterHandler struct {
+	deadLetters *rmq.DeadLetters
+}
+
+func NewDeadLetterHandler(deadLetters *rmq.DeadLetters) *DeadLetterHandler {
+	return &DeadLetterHandler{deadLetters: deadLetters}
+}
+
+type deadLettersCountResponse struct {
+	Count int `json:"count"`
+}
+
+// List возвращает мёртвые письма очереди; limit по умолчанию 50
+func (h *DeadLetterHandler) List(c echo.Context) error {
+	limit := 50
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive number")
+		}
+		limit = parsed
+	}
+
+	letters, err := h.deadLetters.List(c.Param("queue"), limit)
+	if err != nil {
+		return err
+	}
+	if letters == nil {
+		letters = []rmq.DeadLetter{}
+	}
+	return c.JSON(http.StatusOK, letters)
+}
+
+func (h *DeadLetterHandler) Get(c echo.Context) error {
+	letter, err := h.deadLetters.Get(c.Param("queue"), c.Param("message_id"))
+	if err != nil {
+		return deadLetterError(err)
+	}
+	return c.JSON(http.StatusOK, letter)
+}
+
+// Requeue возвращает одно письмо (или все, если message_id не указан) в исходную очередь
+func (h *DeadLetterHandler) Requeue(c echo.Context) error {
+	count, err := h.deadLetters.Requeue(c.Param("queue"), c.Param("message_id"))
+	if err != nil {
+		return deadLetterError(err)
+	}
+	return c.JSON(http.StatusOK, deadLettersCountResponse{Count: count})
+}
+
+// Purge удаляет одно письмо (или очищает очередь, если message_id не указан)
+func (h *DeadLetterHandler) Purge(c echo.Context) error {
+	count, err := h.deadLetters.Purge(c.Param("queue"), c.Param("message_id"))
+	if err != nil {
+		return deadLetterError(err)
+	}
+	return c.JSON(http.StatusOK, deadLettersCountResponse{Count: count})
+}
+
+func deadLetterError(err error) error {
+	if errors.Is(err, rmq.ErrDeadLetterNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	return err
+}
+
+type WorkItemHandler struct {
+	workItems *workitem.Store
+}
+
+func NewWorkItemHandler(workItems *workitem.Store) *WorkItemHandler {
+	return &WorkItemHandler{workItems: workItems}
+}
+
+// Get показывает, чем закончилась обработка письма: статус, число попыток и последнюю ошибку
+func (h *WorkItemHandler) Get(c echo.Context) error {
+	state, err := h.workItems.Get(c.Request().Context(), c.Param("email_id"))
+	if errors.Is(err, workitem.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, state)
+}
+
+const (
+	// X-User-ID выставляет api-gateway; часовой пояс и язык он не подставляет, а передаёт из запроса клиента как есть
+	HeaderUserID       = "X-User-ID"
+	HeaderUserTimezone = "X-User-Timezone"
+	HeaderUserLanguage = "X-User-Language"
+
+	// Ответ должен уложиться в WriteTimeout сервера вместе с повторами запроса к модели
+	analyzeTimeout = 12 * time.Second
+	maxAnalyzeSize = 256 << 10
+)
+
+type AnalyzeHandler struct {
+	agent *mistral.MistralAgent
+	log   *logger.CurrentLogger
+}
+
+func NewAnalyzeHandler(agent *mistral.MistralAgent, log *logger.CurrentLogger) *AnalyzeHandler {
+	return &AnalyzeHandler{agent: agent, log: log}
+}
+
+type analyzeRequest struct {
+	UserID  string `json:"user_id"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	HTML    string `json:"body_html"`
+	From    string `json:"from_address"`
+	// RFC 3339; относительно него разрешаются "завтра" и "в пятницу". По умолчанию — текущее время.
+	ReferenceTime string `json:"reference_time"`
+	Timezone      string `json:"timezone"`
+	// Язык задач (ISO 639-1) или "auto" — язык письма
+	Language string `json:"language"`
+}
+
+// Analyze извлекает задачи из письма или строки быстрого добавления и сразу возвращает их,
+// не публикуя в очередь и не создавая задач в collector
+func (h *AnalyzeHandler) Analyze(c echo.Context) error {
+	var req analyzeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if strings.TrimSpace(req.Subject) == "" && strings.TrimSpace(req.Body) == "" && strings.TrimSpace(req.HTML) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "subject or body is required")
+	}
+	if len(req.Subject)+len(req.Body)+len(req.HTML) > maxAnalyzeSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "email is too large")
+	}
+
+	reference := time.Now()
+	if req.ReferenceTime != "" {
+		parsed, err := time.Parse(time.RFC3339, req.ReferenceTime)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "reference_time must be in RFC 3339 format")
+		}
+		reference = parsed
+	}
+
+	userID := c.Request().Header.Get(HeaderUserID)
+	if userID == "" {
+		userID = req.UserID
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = c.Request().Header.Get(HeaderUserTimezone)
+	}
+	language := req.Language
+	if language == "" {
+		language = c.Request().Header.Get(HeaderUserLanguage)
+	}
+	if language != "" && !locale.Valid(language) {
+		return echo.NewHTTPError(http.StatusBadRequest, "language must be an ISO 639-1 code or \"auto\"")
+	}
+
+	rawEmail := models.RawEmail{
+		// Письма нет в core, но идентификатор нужен для логов и выбора версии промпта
+		EmailID:  uuid.NewString(),
+		UserID:   userID,
+		From:     req.From,
+		Subject:  req.Subject,
+		Text:     req.Body,
+		HTML:     req.HTML,
+		Date:     reference.Format(time.RFC3339),
+		Timezone: timezone,
+		Language: language,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), analyzeTimeout)
+	defer cancel()
+
+	analysis, err := h.agent.Analyze(ctx, rawEmail, h.log)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return echo.NewHTTPError(http.StatusGatewayTimeout, "analysis timed out")
+	}
+	if err != nil {
+		h.log.Error(ctx, "Synchronous analysis failed", "error", err, "email_id", rawEmail.EmailID)
+		return echo.NewHTTPError(http.StatusBadGateway, "analysis failed")
+	}
+	return c.JSON(http.StatusOK, analysis)
+}
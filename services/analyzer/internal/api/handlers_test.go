@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/logger/zaplogger"
+	"reminder-hub/services/analyzer/internal/ai_agent/mistral"
+	"reminder-hub/services/analyzer/internal/budget"
+	"reminder-hub/services/analyzer/internal/cleaner"
+	"reminder-hub/services/analyzer/internal/config"
+	"reminder-hub/services/analyzer/internal/deadline"
+	"reminder-hub/services/analyzer/internal/prompt"
+	"reminder-hub/services/analyzer/internal/server/echoserver"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/fx/fxtest"
+)
+
+func newTestServer(t *testing.T) *echo.Echo {
+	store, err := prompt.New(context.Background(), &prompt.Config{ActiveVersion: "v1"}, nil)
+	require.NoError(t, err)
+
+	e := echo.New()
+	cfg := &config.Config{InternalToken: "secret", Echo: &echoserver.EchoConfig{BasePath: "/analyzer/v1"}}
+	ConfigRoutes(e, cfg, store, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	return e
+}
+
+func doRequest(e *echo.Echo, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPromptAdmin_RequiresInternalToken(t *testing.T) {
+	e := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/analyzer/v1/admin/prompts", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestPromptAdmin_GetPrompts(t *testing.T) {
+	e := newTestServer(t)
+
+	rec := doRequest(e, http.MethodGet, "/analyzer/v1/admin/prompts", "")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp promptsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"v1"}, resp.Versions)
+	assert.Equal(t, map[string]int{"v1": 1}, resp.Split)
+}
+
+func TestPromptAdmin_SetActiveAndSplit(t *testing.T) {
+	e := newTestServer(t)
+
+	rec := doRequest(e, http.MethodPut, "/analyzer/v1/admin/prompts/active", `{"version":"v1"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/analyzer/v1/admin/prompts/active", `{"version":"v7"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/analyzer/v1/admin/prompts/active", `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/analyzer/v1/admin/prompts/split", `{"weights":{"v1":0}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/analyzer/v1/admin/prompts/split", `{"weights":{"v1":3}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"v1":3`)
+}
+
+func TestCacheAdmin_GetStats(t *testing.T) {
+	e := newTestServer(t)
+
+	rec := doRequest(e, http.MethodGet, "/analyzer/v1/admin/cache/stats", "")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"hits":0,"misses":0,"errors":0}`, rec.Body.String())
+}
+
+func TestUsage_GetUsage(t *testing.T) {
+	store, err := prompt.New(context.Background(), &prompt.Config{ActiveVersion: "v1"}, nil)
+	require.NoError(t, err)
+	log := logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(fxtest.NewLifecycle(t), "test"))
+	tracker := budget.NewTracker(context.Background(), &budget.Config{DailyTokens: 1000}, nil, nil, log)
+	tracker.Record(context.Background(), "user-1", "email-1", "open-mistral-7b", budget.Usage{PromptTokens: 70, CompletionTokens: 30})
+
+	e := echo.New()
+	cfg := &config.Config{InternalToken: "secret", Echo: &echoserver.EchoConfig{BasePath: "/analyzer/v1"}}
+	ConfigRoutes(e, cfg, store, nil, tracker, nil, nil, nil, nil, nil, nil, nil)
+
+	rec := doRequest(e, http.MethodGet, "/analyzer/v1/usage/user-1", "")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp budget.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "user-1", resp.UserID)
+	assert.Equal(t, int64(100), resp.Day.TotalTokens)
+	assert.Equal(t, int64(1000), resp.Day.Limit)
+	assert.Equal(t, int64(70), resp.Month.Models["open-mistral-7b"].PromptTokens)
+}
+
+func TestCleaner_Clean(t *testing.T) {
+	store, err := prompt.New(context.Background(), &prompt.Config{ActiveVersion: "v1"}, nil)
+	require.NoError(t, err)
+
+	e := echo.New()
+	cfg := &config.Config{InternalToken: "secret", Echo: &echoserver.EchoConfig{BasePath: "/analyzer/v1"}}
+	ConfigRoutes(e, cfg, store, nil, nil, nil, nil, cleaner.New(&cleaner.Config{Enabled: true}), nil, nil, nil, nil)
+
+	rec := doRequest(e, http.MethodPost, "/analyzer/v1/admin/clean", `{"body":"Пришлю до среды.\n> Пришлите отчёт до 28 ноября"}`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp cleaner.Result
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Пришлю до среды.", resp.Text)
+	assert.Equal(t, 1, resp.QuotedLines)
+}
+
+// stubModel отвечает заранее заданным текстом и запоминает промпт
+type stubModel struct {
+	response string
+	prompt   string
+}
+
+func (m *stubModel) GenerateContent(_ context.Context, messages []llms.MessageContent, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	if text, ok := messages[0].Parts[0].(llms.TextContent); ok {
+		m.prompt = text.Text
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.response}}}, nil
+}
+
+func (m *stubModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func newAnalyzeServer(t *testing.T, llm llms.Model) *echo.Echo {
+	store, err := prompt.New(context.Background(), &prompt.Config{ActiveVersion: "v1"}, nil)
+	require.NoError(t, err)
+	log := logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(fxtest.NewLifecycle(t), "test"))
+	agent := mistral.NewWithModel(llm, "test-model", &deadline.Config{DefaultTimezone: "UTC"}, store, nil)
+
+	e := echo.New()
+	cfg := &config.Config{InternalToken: "secret", Echo: &echoserver.EchoConfig{BasePath: "/analyzer/v1"}}
+	ConfigRoutes(e, cfg, store, nil, nil, nil, nil, nil, nil, nil, agent, log)
+	return e
+}
+
+func TestAnalyze_ReturnsExtraction(t *testing.T) {
+	llm := &stubModel{response: `{"title":"Отчёт","description":"Сдать отчёт","deadline":{"in_days":1,"time":"10:00"}}`}
+	e := newAnalyzeServer(t, llm)
+
+	req := httptest.NewRequest(http.MethodPost, "/analyzer/v1/analyze",
+		strings.NewReader(`{"subject":"Отчёт","body":"Пришлите отчёт завтра к 10","reference_time":"2025-12-10T20:30:00Z"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	req.Header.Set(HeaderUserID, "user-1")
+	req.Header.Set(HeaderUserTimezone, "Europe/Moscow")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp mistral.Analysis
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, mistral.SourceModel, resp.Source)
+	require.Len(t, resp.Tasks, 1)
+	assert.Equal(t, "user-1", resp.Tasks[0].UserID)
+	assert.Equal(t, "Отчёт", resp.Tasks[0].Title)
+	assert.Equal(t, "2025-12-11T10:00:00+03:00", resp.Tasks[0].Deadline.Format(time.RFC3339))
+	assert.Contains(t, llm.prompt, "Wednesday, 2025-12-10 23:30")
+}
+
+func TestAnalyze_SchemaOrgSkipsModel(t *testing.T) {
+	llm := &stubModel{}
+	e := newAnalyzeServer(t, llm)
+
+	body, _ := json.Marshal(map[string]string{
+		"subject": "Счёт",
+		"body_html": `<script type="application/ld+json">{"@context":"http://schema.org","@type":"Invoice",` +
+			`"provider":{"name":"Водоканал"},"paymentDueDate":"2025-12-20"}</script>`,
+	})
+	rec := doRequest(e, http.MethodPost, "/analyzer/v1/analyze", string(body))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp mistral.Analysis
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, mistral.SourceSchemaOrg, resp.Source)
+	assert.Equal(t, "Оплатить счёт Водоканал", resp.Tasks[0].Title)
+	assert.Empty(t, llm.prompt)
+}
+
+func TestAnalyze_UserLanguage(t *testing.T) {
+	llm := &stubModel{response: `{"title":"Report","description":"Send the report"}`}
+	e := newAnalyzeServer(t, llm)
+
+	rec := doRequest(e, http.MethodPost, "/analyzer/v1/analyze", `{"subject":"Отчёт","body":"Пришлите отчёт","language":"en"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, llm.prompt, `на языке "en"`)
+
+	// Тексты без модели пишутся на языке письма, если пользователь выбрал "auto"
+	body, _ := json.Marshal(map[string]string{
+		"subject":  "Your invoice",
+		"language": "auto",
+		"body_html": `<script type="application/ld+json">{"@context":"http://schema.org","@type":"Invoice",` +
+			`"provider":{"name":"City Water"},"paymentDueDate":"2025-12-20"}</script>`,
+	})
+	rec = doRequest(e, http.MethodPost, "/analyzer/v1/analyze", string(body))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp mistral.Analysis
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Pay invoice City Water", resp.Tasks[0].Title)
+}
+
+func TestAnalyze_RejectsBadRequests(t *testing.T) {
+	e := newAnalyzeServer(t, &stubModel{})
+
+	rec := doRequest(e, http.MethodPost, "/analyzer/v1/analyze", `{"subject":"  "}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/analyzer/v1/analyze", `{"body":"завтра","reference_time":"10.12.2025"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/analyzer/v1/analyze", `{"body":"завтра","language":"english"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	huge, _ := json.Marshal(map[string]string{"body": strings.Repeat("a", maxAnalyzeSize+1)})
+	rec = doRequest(e, http.MethodPost, "/analyzer/v1/analyze", string(huge))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/analyzer/v1/analyze", strings.NewReader(`{"body":"завтра"}`))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
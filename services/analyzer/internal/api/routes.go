@@ -0,0 +1,57 @@
+package api
+
+import (
+	"reminder-hub/pkg/logger"
+	rmq "reminder-hub/pkg/rabbitmq"
+	"reminder-hub/services/analyzer/internal/ai_agent/mistral"
+	"reminder-hub/services/analyzer/internal/budget"
+	"reminder-hub/services/analyzer/internal/cache"
+	"reminder-hub/services/analyzer/internal/cleaner"
+	"reminder-hub/services/analyzer/internal/config"
+	echomiddleware "reminder-hub/services/analyzer/internal/middleware"
+	"reminder-hub/services/analyzer/internal/prompt"
+	"reminder-hub/services/analyzer/internal/redact"
+	"reminder-hub/services/analyzer/internal/routing"
+	"reminder-hub/services/analyzer/internal/workitem"
+
+	"github.com/labstack/echo/v4"
+)
+
+func ConfigRoutes(e *echo.Echo, cfg *config.Config, prompts *prompt.Store, llmCache *cache.Cache, tracker *budget.Tracker, redactor *redact.Redactor, router *routing.Router, contentCleaner *cleaner.Cleaner, deadLetters *rmq.DeadLetters, workItems *workitem.Store, agent *mistral.MistralAgent, log *logger.CurrentLogger) {
+	base := e.Group(cfg.Echo.BasePath, echomiddleware.InternalAuth(cfg.InternalToken))
+
+	promptHandler := NewPromptHandler(prompts)
+	admin := base.Group("/admin")
+	admin.GET("/prompts", promptHandler.GetPrompts)
+	admin.PUT("/prompts/active", promptHandler.SetActive)
+	admin.PUT("/prompts/split", promptHandler.SetSplit)
+
+	cacheHandler := NewCacheHandler(llmCache)
+	admin.GET("/cache/stats", cacheHandler.GetStats)
+
+	redactionHandler := NewRedactionHandler(redactor)
+	admin.GET("/redaction/stats", redactionHandler.GetStats)
+
+	routingHandler := NewRoutingHandler(router)
+	admin.GET("/routing/stats", routingHandler.GetStats)
+
+	cleanerHandler := NewCleanerHandler(contentCleaner)
+	admin.POST("/clean", cleanerHandler.Clean)
+
+	deadLetterHandler := NewDeadLetterHandler(deadLetters)
+	admin.GET("/dead-letters/:queue", deadLetterHandler.List)
+	admin.GET("/dead-letters/:queue/:message_id", deadLetterHandler.Get)
+	admin.POST("/dead-letters/:queue/requeue", deadLetterHandler.Requeue)
+	admin.POST("/dead-letters/:queue/:message_id/requeue", deadLetterHandler.Requeue)
+	admin.DELETE("/dead-letters/:queue", deadLetterHandler.Purge)
+	admin.DELETE("/dead-letters/:queue/:message_id", deadLetterHandler.Purge)
+
+	workItemHandler := NewWorkItemHandler(workItems)
+	admin.GET("/work-items/:email_id", workItemHandler.Get)
+
+	usageHandler := NewUsageHandler(tracker)
+	base.GET("/usage/:user_id", usageHandler.GetUsage)
+
+	analyzeHandler := NewAnalyzeHandler(agent, log)
+	base.POST("/analyze", analyzeHandler.Analyze)
+}
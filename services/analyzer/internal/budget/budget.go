@@ -0,0 +1,232 @@
+package budget
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/models"
+	"reminder-hub/pkg/rabbitmq"
+	"reminder-hub/pkg/redis"
+)
+
+const (
+	keyPrefix = "analyzer:usage:"
+
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+
+	// Ключи живут чуть дольше своего периода, чтобы отчёт за вчера/прошлый месяц был доступен
+	dayTTL   = 48 * time.Hour
+	monthTTL = 62 * 24 * time.Hour
+
+	promptField     = "prompt"
+	completionField = "completion"
+)
+
+type Config struct {
+	// Лимиты в токенах (prompt + completion) на пользователя; 0 — без лимита
+	DailyTokens   int64 `env:"DAILY_TOKENS" env-default:"0"`
+	MonthlyTokens int64 `env:"MONTHLY_TOKENS" env-default:"0"`
+	// Цены в долларах за миллион токенов в формате model:price,model:price
+	PromptPrices     map[string]float64 `env:"PROMPT_PRICES" env-default:"open-mistral-7b:0.25,mistral-large-latest:2"`
+	CompletionPrices map[string]float64 `env:"COMPLETION_PRICES" env-default:"open-mistral-7b:0.25,mistral-large-latest:6"`
+}
+
+// Backend — подмножество pkg/redis.Client, которое нужно учёту
+type Backend interface {
+	HIncrBy(ctx context.Context, key, field string, incr int64) error
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// Usage — токены одного ответа модели
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+type ModelUsage struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+type Period struct {
+	Period           string                `json:"period"`
+	PromptTokens     int64                 `json:"prompt_tokens"`
+	CompletionTokens int64                 `json:"completion_tokens"`
+	TotalTokens      int64                 `json:"total_tokens"`
+	CostUSD          float64               `json:"cost_usd"`
+	Limit            int64                 `json:"limit"`
+	Models           map[string]ModelUsage `json:"models"`
+}
+
+type Report struct {
+	UserID string `json:"user_id"`
+	Day    Period `json:"day"`
+	Month  Period `json:"month"`
+}
+
+// Tracker считает токены по пользователям и моделям и следит за дневным и месячным лимитами
+type Tracker struct {
+	cfg       *Config
+	backend   Backend
+	publisher rabbitmq.IPublisher
+	log       *logger.CurrentLogger
+	now       func() time.Time
+}
+
+func New(cfg *Config, backend Backend, publisher rabbitmq.IPublisher, log *logger.CurrentLogger) *Tracker {
+	return &Tracker{cfg: cfg, backend: backend, publisher: publisher, log: log, now: time.Now}
+}
+
+// NewTracker хранит счётчики в Redis, а без него — в памяти процесса
+func NewTracker(ctx context.Context, cfg *Config, client *redis.Client, publisher rabbitmq.IPublisher, log *logger.CurrentLogger) *Tracker {
+	if client == nil {
+		log.Warn(ctx, "LLM usage is tracked in memory without Redis")
+		return New(cfg, newMemoryBackend(), publisher, log)
+	}
+	return New(cfg, client, publisher, log)
+}
+
+// Allow сообщает, остался ли у пользователя бюджет. Ошибки хранилища не блокируют обработку.
+func (t *Tracker) Allow(ctx context.Context, userID string) bool {
+	if t == nil || (t.cfg.DailyTokens <= 0 && t.cfg.MonthlyTokens <= 0) {
+		return true
+	}
+
+	report, err := t.Report(ctx, userID)
+	if err != nil {
+		t.log.Warn(ctx, "Failed to read LLM usage, allowing request", "error", err, "user_id", userID)
+		return true
+	}
+	return !exceeded(report.Day) && !exceeded(report.Month)
+}
+
+// Record учитывает расход токенов и публикует событие LLMUsage
+func (t *Tracker) Record(ctx context.Context, userID, emailID, model string, usage Usage) {
+	if t == nil {
+		return
+	}
+
+	now := t.now().UTC()
+	for _, period := range []struct {
+		key string
+		ttl time.Duration
+	}{
+		{dayKey(userID, now), dayTTL},
+		{monthKey(userID, now), monthTTL},
+	} {
+		if err := t.add(ctx, period.key, model, usage, period.ttl); err != nil {
+			t.log.Warn(ctx, "Failed to record LLM usage", "error", err, "user_id", userID, "key", period.key)
+		}
+	}
+
+	if t.publisher == nil {
+		return
+	}
+	event := &models.LLMUsage{
+		UserID:           userID,
+		EmailID:          emailID,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          t.cost(model, int64(usage.PromptTokens), int64(usage.CompletionTokens)),
+		Timestamp:        now,
+	}
+	if err := t.publisher.PublishMessage(event); err != nil {
+		t.log.Warn(ctx, "Failed to publish LLM usage", "error", err, "user_id", userID)
+	}
+}
+
+// Report возвращает расход пользователя за текущие день и месяц
+func (t *Tracker) Report(ctx context.Context, userID string) (*Report, error) {
+	now := t.now().UTC()
+
+	day, err := t.period(ctx, dayKey(userID, now), now.Format(dayLayout), t.cfg.DailyTokens)
+	if err != nil {
+		return nil, err
+	}
+	month, err := t.period(ctx, monthKey(userID, now), now.Format(monthLayout), t.cfg.MonthlyTokens)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{UserID: userID, Day: day, Month: month}, nil
+}
+
+func (t *Tracker) add(ctx context.Context, key, model string, usage Usage, ttl time.Duration) error {
+	if err := t.backend.HIncrBy(ctx, key, model+":"+promptField, int64(usage.PromptTokens)); err != nil {
+		return err
+	}
+	if err := t.backend.HIncrBy(ctx, key, model+":"+completionField, int64(usage.CompletionTokens)); err != nil {
+		return err
+	}
+	return t.backend.Expire(ctx, key, ttl)
+}
+
+func (t *Tracker) period(ctx context.Context, key, name string, limit int64) (Period, error) {
+	fields, err := t.backend.HGetAll(ctx, key)
+	if err != nil {
+		return Period{}, err
+	}
+
+	p := Period{Period: name, Limit: limit, Models: make(map[string]ModelUsage)}
+	for field, raw := range fields {
+		// Имя модели может содержать ":", поэтому режем по последнему
+		idx := strings.LastIndex(field, ":")
+		if idx < 0 {
+			continue
+		}
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		model := field[:idx]
+		usage := p.Models[model]
+		switch field[idx+1:] {
+		case promptField:
+			usage.PromptTokens += value
+			p.PromptTokens += value
+		case completionField:
+			usage.CompletionTokens += value
+			p.CompletionTokens += value
+		}
+		p.Models[model] = usage
+	}
+
+	for model, usage := range p.Models {
+		usage.CostUSD = t.cost(model, usage.PromptTokens, usage.CompletionTokens)
+		p.Models[model] = usage
+		p.CostUSD += usage.CostUSD
+	}
+	p.TotalTokens = p.PromptTokens + p.CompletionTokens
+	return p, nil
+}
+
+// Cost — стоимость одного ответа модели в долларах по ценам из конфигурации
+func (t *Tracker) Cost(model string, usage Usage) float64 {
+	if t == nil {
+		return 0
+	}
+	return t.cost(model, int64(usage.PromptTokens), int64(usage.CompletionTokens))
+}
+
+func (t *Tracker) cost(model string, promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*t.cfg.PromptPrices[model] + float64(completionTokens)*t.cfg.CompletionPrices[model]) / 1e6
+}
+
+func exceeded(p Period) bool {
+	return p.Limit > 0 && p.TotalTokens >= p.Limit
+}
+
+func dayKey(userID string, now time.Time) string {
+	return keyPrefix + userID + ":day:" + now.Format(dayLayout)
+}
+
+func monthKey(userID string, now time.Time) string {
+	return keyPrefix + userID + ":month:" + now.Format(monthLayout)
+}
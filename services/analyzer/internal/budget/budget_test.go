@@ -0,0 +1,100 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/logger/zaplogger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+)
+
+type simpleLifecycle struct{}
+
+func (s *simpleLifecycle) Append(hook fx.Hook) {}
+
+func testLogger() *logger.CurrentLogger {
+	return logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(&simpleLifecycle{}, "test"))
+}
+
+func newTestTracker(cfg *Config, now time.Time) *Tracker {
+	backend := newMemoryBackend()
+	backend.now = func() time.Time { return now }
+	t := New(cfg, backend, nil, testLogger())
+	t.now = func() time.Time { return now }
+	return t
+}
+
+func TestReport_AggregatesByModelWithCost(t *testing.T) {
+	now := time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(&Config{
+		PromptPrices:     map[string]float64{"small": 1, "large": 10},
+		CompletionPrices: map[string]float64{"small": 2, "large": 30},
+	}, now)
+	ctx := context.Background()
+
+	tracker.Record(ctx, "user-1", "email-1", "small", Usage{PromptTokens: 1000, CompletionTokens: 500})
+	tracker.Record(ctx, "user-1", "email-2", "small", Usage{PromptTokens: 1000, CompletionTokens: 500})
+	tracker.Record(ctx, "user-1", "email-3", "large", Usage{PromptTokens: 100, CompletionTokens: 100})
+	tracker.Record(ctx, "user-2", "email-4", "large", Usage{PromptTokens: 100, CompletionTokens: 100})
+
+	report, err := tracker.Report(ctx, "user-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-10", report.Day.Period)
+	assert.Equal(t, "2025-12", report.Month.Period)
+	assert.Equal(t, int64(2100), report.Day.PromptTokens)
+	assert.Equal(t, int64(1100), report.Day.CompletionTokens)
+	assert.Equal(t, int64(3200), report.Day.TotalTokens)
+	assert.Equal(t, ModelUsage{PromptTokens: 2000, CompletionTokens: 1000, CostUSD: 0.004}, report.Day.Models["small"])
+	assert.InDelta(t, 0.004+0.001+0.003, report.Day.CostUSD, 1e-9)
+	assert.Equal(t, report.Day.TotalTokens, report.Month.TotalTokens)
+}
+
+func TestAllow_DailyAndMonthlyLimits(t *testing.T) {
+	now := time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	daily := newTestTracker(&Config{DailyTokens: 100}, now)
+	assert.True(t, daily.Allow(ctx, "user-1"))
+	daily.Record(ctx, "user-1", "email-1", "small", Usage{PromptTokens: 60, CompletionTokens: 40})
+	assert.False(t, daily.Allow(ctx, "user-1"))
+	assert.True(t, daily.Allow(ctx, "user-2"))
+
+	monthly := newTestTracker(&Config{MonthlyTokens: 150}, now)
+	monthly.Record(ctx, "user-1", "email-1", "small", Usage{PromptTokens: 100})
+	assert.True(t, monthly.Allow(ctx, "user-1"))
+	// Новый день: дневной счётчик пуст, но месячный продолжает расти
+	monthly.now = func() time.Time { return now.AddDate(0, 0, 1) }
+	monthly.Record(ctx, "user-1", "email-2", "small", Usage{PromptTokens: 50})
+	assert.False(t, monthly.Allow(ctx, "user-1"))
+}
+
+func TestAllow_NilTrackerAndNoLimits(t *testing.T) {
+	var tracker *Tracker
+	assert.True(t, tracker.Allow(context.Background(), "user-1"))
+	tracker.Record(context.Background(), "user-1", "email-1", "small", Usage{PromptTokens: 1})
+
+	unlimited := newTestTracker(&Config{}, time.Now())
+	unlimited.Record(context.Background(), "user-1", "email-1", "small", Usage{PromptTokens: 1 << 30})
+	assert.True(t, unlimited.Allow(context.Background(), "user-1"))
+}
+
+func TestMemoryBackend_Expires(t *testing.T) {
+	now := time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC)
+	backend := newMemoryBackend()
+	backend.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, backend.HIncrBy(ctx, "key", "field", 5))
+	require.NoError(t, backend.Expire(ctx, "key", time.Hour))
+
+	backend.now = func() time.Time { return now.Add(2 * time.Hour) }
+	fields, err := backend.HGetAll(ctx, "key")
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+}
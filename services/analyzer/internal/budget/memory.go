@@ -0,0 +1,67 @@
+package budget
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryBackend хранит счётчики в памяти процесса, когда Redis недоступен
+type memoryBackend struct {
+	mu      sync.Mutex
+	hashes  map[string]map[string]int64
+	expires map[string]time.Time
+	now     func() time.Time
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		hashes:  make(map[string]map[string]int64),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (m *memoryBackend) HIncrBy(_ context.Context, key, field string, incr int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(key)
+	if m.hashes[key] == nil {
+		m.hashes[key] = make(map[string]int64)
+	}
+	m.hashes[key][field] += incr
+	return nil
+}
+
+func (m *memoryBackend) HGetAll(_ context.Context, key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(key)
+	fields := make(map[string]string, len(m.hashes[key]))
+	for field, value := range m.hashes[key] {
+		fields[field] = strconv.FormatInt(value, 10)
+	}
+	return fields, nil
+}
+
+func (m *memoryBackend) Expire(_ context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expires[key] = m.now().Add(ttl)
+	// Заодно выбрасываем счётчики прошедших периодов, чтобы память не росла
+	for k := range m.expires {
+		m.evict(k)
+	}
+	return nil
+}
+
+func (m *memoryBackend) evict(key string) {
+	if deadline, ok := m.expires[key]; ok && m.now().After(deadline) {
+		delete(m.hashes, key)
+		delete(m.expires, key)
+	}
+}
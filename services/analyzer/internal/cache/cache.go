@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/redis"
+)
+
+const keyPrefix = "analyzer:llm:"
+
+type Config struct {
+	Enabled bool `env:"ENABLED" env-default:"true"`
+	// Не читать из кэша, но продолжать записывать — чтобы прогреть кэш новыми ответами
+	BypassRead bool          `env:"BYPASS_READ" env-default:"false"`
+	TTL        time.Duration `env:"TTL" env-default:"168h"`
+}
+
+// Backend — подмножество pkg/redis.Client, которое нужно кэшу
+type Backend interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// Key — всё, от чего зависит ответ модели
+type Key struct {
+	PromptVersion string
+	Model         string
+	Language      string
+	Categories    string
+	Subject       string
+	Body          string
+	// Примеры исправлений пользователя; ключи писем без примеров от них не зависят
+	Examples string
+	// День письма и зона пользователя из промпта: модель может ответить абсолютной датой,
+	// и такой ответ верен только для письма того же дня в той же зоне
+	Day      string
+	Timezone string
+}
+
+type Stats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Errors int64 `json:"errors"`
+}
+
+// Cache хранит сырые ответы модели. Ответ переиспользуется только для писем того же дня в той же зоне:
+// "в пятницу" от письма в понедельник и от письма в четверг — разные даты.
+type Cache struct {
+	cfg     *Config
+	backend Backend
+	log     *logger.CurrentLogger
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+func New(cfg *Config, backend Backend, log *logger.CurrentLogger) *Cache {
+	return &Cache{cfg: cfg, backend: backend, log: log}
+}
+
+// NewRedisCache хранит ответы в Redis. Кэш — это оптимизация, поэтому без Redis сервис работает дальше без него.
+func NewRedisCache(ctx context.Context, cfg *Config, client *redis.Client, log *logger.CurrentLogger) *Cache {
+	if !cfg.Enabled {
+		log.Info(ctx, "LLM cache is disabled")
+		return New(cfg, nil, log)
+	}
+	if client == nil {
+		log.Warn(ctx, "LLM cache is unavailable without Redis")
+		return New(cfg, nil, log)
+	}
+	return New(cfg, client, log)
+}
+
+// Get возвращает сохранённый ответ модели. Любая ошибка Redis считается промахом.
+func (c *Cache) Get(ctx context.Context, key Key) (string, bool) {
+	if c == nil || c.backend == nil || c.cfg.BypassRead {
+		return "", false
+	}
+
+	value, err := c.backend.Get(ctx, key.String())
+	switch {
+	case err == nil:
+		c.hits.Add(1)
+		return value, true
+	case errors.Is(err, redis.Nil):
+		c.misses.Add(1)
+	default:
+		c.errors.Add(1)
+		c.log.Warn(ctx, "LLM cache read failed", "error", err)
+	}
+	return "", false
+}
+
+func (c *Cache) Set(ctx context.Context, key Key, value string) {
+	if c == nil || c.backend == nil {
+		return
+	}
+
+	if err := c.backend.Set(ctx, key.String(), value, c.cfg.TTL); err != nil {
+		c.errors.Add(1)
+		c.log.Warn(ctx, "LLM cache write failed", "error", err)
+	}
+}
+
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load(), Errors: c.errors.Load()}
+}
+
+func (k Key) String() string {
+	h := sha256.New()
+	parts := []string{k.PromptVersion, k.Model, k.Language, k.Categories, normalize(k.Subject), normalize(k.Body), k.Day, k.Timezone}
+	if k.Examples != "" {
+		parts = append(parts, normalize(k.Examples))
+	}
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return keyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// normalize убирает различия, которые не влияют на смысл: регистр и пробелы
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
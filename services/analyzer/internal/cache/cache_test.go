@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/logger/zaplogger"
+	"reminder-hub/pkg/redis"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/fx"
+)
+
+type simpleLifecycle struct{}
+
+func (s *simpleLifecycle) Append(hook fx.Hook) {}
+
+type memoryBackend struct {
+	values map[string]string
+	ttl    time.Duration
+	err    error
+}
+
+func (m *memoryBackend) Get(_ context.Context, key string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	value, ok := m.values[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (m *memoryBackend) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.values[key] = value.(string)
+	m.ttl = ttl
+	return nil
+}
+
+func newTestCache(cfg *Config) (*Cache, *memoryBackend) {
+	backend := &memoryBackend{values: map[string]string{}}
+	log := logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(&simpleLifecycle{}, "test"))
+	return New(cfg, backend, log), backend
+}
+
+func TestKey_NormalizesWhitespaceAndCase(t *testing.T) {
+	a := Key{PromptVersion: "v1", Model: "m", Subject: "CI  failed", Body: "Build #1\n\nfailed "}
+	b := Key{PromptVersion: "v1", Model: "m", Subject: "ci failed", Body: "build #1 failed"}
+
+	assert.Equal(t, a.String(), b.String())
+
+	b.PromptVersion = "v2"
+	assert.NotEqual(t, a.String(), b.String())
+
+	b.PromptVersion, b.Model = "v1", "other"
+	assert.NotEqual(t, a.String(), b.String())
+
+	b.Model = "m"
+	b.Day = "2026-10-19"
+	assert.NotEqual(t, a.String(), b.String())
+
+	b.Day, b.Timezone = "", "Europe/Moscow"
+	assert.NotEqual(t, a.String(), b.String())
+}
+
+func TestCache_HitAndMiss(t *testing.T) {
+	c, backend := newTestCache(&Config{TTL: time.Hour})
+	ctx := context.Background()
+	key := Key{Subject: "Invoice", Body: "Pay by friday"}
+
+	_, ok := c.Get(ctx, key)
+	assert.False(t, ok)
+
+	c.Set(ctx, key, `{"title":"Invoice"}`)
+	value, ok := c.Get(ctx, key)
+
+	assert.True(t, ok)
+	assert.Equal(t, `{"title":"Invoice"}`, value)
+	assert.Equal(t, time.Hour, backend.ttl)
+	assert.Equal(t, Stats{Hits: 1, Misses: 1}, c.Stats())
+}
+
+func TestCache_BypassReadStillWrites(t *testing.T) {
+	c, backend := newTestCache(&Config{BypassRead: true})
+	ctx := context.Background()
+	key := Key{Subject: "Invoice"}
+
+	c.Set(ctx, key, "value")
+	_, ok := c.Get(ctx, key)
+
+	assert.False(t, ok)
+	assert.Len(t, backend.values, 1)
+	assert.Equal(t, Stats{}, c.Stats())
+}
+
+func TestCache_BackendErrorIsMiss(t *testing.T) {
+	c, backend := newTestCache(&Config{})
+	backend.err = errors.New("connection refused")
+	ctx := context.Background()
+
+	_, ok := c.Get(ctx, Key{})
+	c.Set(ctx, Key{}, "value")
+
+	assert.False(t, ok)
+	assert.Equal(t, Stats{Errors: 2}, c.Stats())
+}
+
+func TestCache_NilIsNoop(t *testing.T) {
+	var c *Cache
+	ctx := context.Background()
+
+	c.Set(ctx, Key{}, "value")
+	_, ok := c.Get(ctx, Key{})
+
+	assert.False(t, ok)
+	assert.Equal(t, Stats{}, c.Stats())
+}
+
+func TestNewRedisCache_WithoutBackend(t *testing.T) {
+	log := logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(&simpleLifecycle{}, "test"))
+
+	for _, cfg := range []*Config{{Enabled: false}, {Enabled: true}} {
+		c := NewRedisCache(context.Background(), cfg, nil, log)
+
+		_, ok := c.Get(context.Background(), Key{})
+		assert.False(t, ok)
+	}
+}
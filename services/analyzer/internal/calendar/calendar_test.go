@@ -0,0 +1,163 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const invite = "BEGIN:VCALENDAR\r\n" +
+	"PRODID:-//Google Inc//Google Calendar 70.9054//EN\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Moscow\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19700101T000000\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=Europe/Moscow:20251211T100000\r\n" +
+	"DTEND;TZID=Europe/Moscow:20251211T110000\r\n" +
+	"UID:release-sync@google.com\r\n" +
+	"SEQUENCE:0\r\n" +
+	"ORGANIZER;CN=\"Boss: Team Lead\":mailto:boss@example.com\r\n" +
+	"ATTENDEE;CUTYPE=INDIVIDUAL;ROLE=REQ-PARTICIPANT;CN=Anna:mailto:anna@example.com\r\n" +
+	"ATTENDEE;CN=Boss:mailto:BOSS@example.com\r\n" +
+	"SUMMARY:Созвон по релизу\\, итоги\r\n" +
+	"DESCRIPTION:Повестка:\\nдемо\\nСсылка: https://meet.google.com/abc-defg-h\r\n" +
+	" ij\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-P0DT0H10M0S\r\n" +
+	"DESCRIPTION:Напоминание\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func rawEmail(calendars ...string) models.RawEmail {
+	return models.RawEmail{UserID: "u1", EmailID: "e1", Subject: "Приглашение", Calendars: calendars}
+}
+
+func TestParse_Invite(t *testing.T) {
+	cal, err := Parse(invite, time.UTC)
+	require.NoError(t, err)
+
+	assert.Equal(t, "REQUEST", cal.Method)
+	require.Len(t, cal.Components, 1)
+	c := cal.Components[0]
+	assert.Equal(t, KindEvent, c.Kind)
+	assert.Equal(t, "Созвон по релизу, итоги", c.Summary)
+	assert.Equal(t, "Повестка:\nдемо\nСсылка: https://meet.google.com/abc-defg-hij", c.Description)
+	assert.Equal(t, "2025-12-11T07:00:00Z", c.Start.UTC().Format(time.RFC3339))
+	assert.Equal(t, time.Hour, c.End.Sub(c.Start))
+	assert.Equal(t, "boss@example.com", c.Organizer)
+	assert.Equal(t, []string{"anna@example.com", "BOSS@example.com"}, c.Attendees)
+}
+
+func TestParse_Forms(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*3600)
+	cal, err := Parse("BEGIN:VCALENDAR\n"+
+		"BEGIN:VEVENT\nUID:a\nDTSTART;VALUE=DATE:20251224\nEND:VEVENT\n"+
+		"BEGIN:VEVENT\nUID:b\nDTSTART:20251211T070000Z\nDURATION:PT1H30M\nEND:VEVENT\n"+
+		"BEGIN:VTODO\nUID:c\nDUE:20251212T180000\nSTATUS:needs-action\nEND:VTODO\n"+
+		"END:VCALENDAR\n", loc)
+	require.NoError(t, err)
+	require.Len(t, cal.Components, 3)
+
+	assert.True(t, cal.Components[0].AllDay)
+	assert.Equal(t, "2025-12-24T00:00:00+03:00", cal.Components[0].Start.Format(time.RFC3339))
+	assert.Equal(t, 90*time.Minute, cal.Components[1].End.Sub(cal.Components[1].Start))
+	assert.Equal(t, "2025-12-12T18:00:00+03:00", cal.Components[2].Due.Format(time.RFC3339))
+	assert.Equal(t, "NEEDS-ACTION", cal.Components[2].Status)
+}
+
+func TestParse_NotCalendar(t *testing.T) {
+	_, err := Parse("hello", time.UTC)
+	assert.ErrorIs(t, err, ErrNoCalendar)
+
+	_, err = Parse("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nEND:VCALENDAR", time.UTC)
+	assert.Error(t, err)
+}
+
+func TestParseDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"PT15M":     15 * time.Minute,
+		"P1DT2H":    26 * time.Hour,
+		"P2W":       14 * 24 * time.Hour,
+		"-PT10M":    -10 * time.Minute,
+		"P0DT0H30S": 30 * time.Second,
+	} {
+		got, err := parseDuration(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	_, err := parseDuration("1 hour")
+	assert.Error(t, err)
+}
+
+func TestBuild_InviteBecomesEvent(t *testing.T) {
+	items, errs := Build(rawEmail(invite), time.UTC)
+	require.Empty(t, errs)
+	require.Len(t, items, 1)
+
+	parsed := items[0]
+	assert.Empty(t, parsed.Title)
+	require.Len(t, parsed.Events, 1)
+	e := parsed.Events[0]
+	assert.Equal(t, "release-sync@google.com", e.UID)
+	assert.Equal(t, "https://meet.google.com/abc-defg-hij", e.JoinURL)
+	assert.Equal(t, []string{"boss@example.com", "anna@example.com"}, e.Participants)
+	assert.Empty(t, e.Status)
+}
+
+func TestBuild_KeepsLatestSequence(t *testing.T) {
+	updated := strings.Replace(strings.Replace(invite, "SEQUENCE:0", "SEQUENCE:2", 1), "T100000", "T120000", 1)
+
+	items, _ := Build(rawEmail(updated, invite), time.UTC)
+
+	require.Len(t, items, 1)
+	require.Len(t, items[0].Events, 1)
+	assert.Equal(t, 2, items[0].Events[0].Sequence)
+	assert.Equal(t, 9, items[0].Events[0].Start.UTC().Hour())
+}
+
+func TestBuild_CancelAndTodo(t *testing.T) {
+	cancel := "BEGIN:VCALENDAR\r\nMETHOD:CANCEL\r\n" +
+		"BEGIN:VEVENT\r\nUID:release-sync@google.com\r\nSEQUENCE:1\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	todo := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VTODO\r\nUID:report@example.com\r\nSEQUENCE:3\r\nSUMMARY:Сдать отчёт\r\n" +
+		"DUE:20251212T150000Z\r\nRRULE:FREQ=WEEKLY;BYDAY=FR\r\nSTATUS:COMPLETED\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+
+	items, errs := Build(rawEmail(cancel, todo), time.UTC)
+	require.Empty(t, errs)
+	require.Len(t, items, 1)
+
+	task := items[0]
+	assert.Equal(t, "Сдать отчёт", task.Title)
+	assert.Equal(t, "report@example.com", task.CalendarUID)
+	assert.Equal(t, 3, task.Sequence)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=FR", task.RRule)
+	assert.Equal(t, models.StatusCompleted, task.Status)
+	assert.Equal(t, time.Date(2025, 12, 12, 15, 0, 0, 0, time.UTC), task.Deadline)
+
+	require.Len(t, task.Events, 1)
+	assert.Equal(t, models.StatusCancelled, task.Events[0].Status)
+	assert.True(t, task.Events[0].Start.IsZero())
+}
+
+func TestBuild_IgnoresRepliesAndExceptions(t *testing.T) {
+	reply := strings.Replace(invite, "METHOD:REQUEST", "METHOD:REPLY", 1)
+	exception := strings.Replace(invite, "SEQUENCE:0", "SEQUENCE:0\r\nRECURRENCE-ID:20251218T100000Z", 1)
+
+	items, errs := Build(rawEmail(reply, exception, "garbage"), time.UTC)
+
+	assert.Nil(t, items)
+	assert.Len(t, errs, 1)
+}
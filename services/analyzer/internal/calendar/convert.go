@@ -0,0 +1,177 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"reminder-hub/pkg/models"
+	"reminder-hub/services/analyzer/internal/event"
+)
+
+const maxParticipants = 50
+
+// Методы, в которых нет новых встреч и задач для получателя: ответы участников и встречные предложения
+var ignoredMethods = map[string]bool{
+	"REPLY":          true,
+	"COUNTER":        true,
+	"REFRESH":        true,
+	"DECLINECOUNTER": true,
+}
+
+// Build превращает календарные части письма в задачи (VTODO) и события (VEVENT) без обращения к модели.
+// События попадают в первую задачу, а если задач нет — в сообщение с пустым Title. Пустой результат
+// значит, что применимых компонентов нет и письмо надо разбирать обычным путём.
+func Build(rawEmail models.RawEmail, loc *time.Location) ([]*models.ParsedEmails, []error) {
+	var errs []error
+	var components []Component
+	for i, text := range rawEmail.Calendars {
+		cal, err := Parse(text, loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("calendar part %d: %w", i, err))
+			continue
+		}
+		if ignoredMethods[cal.Method] {
+			continue
+		}
+		for _, c := range cal.Components {
+			if cal.Method == "CANCEL" {
+				c.Status = "CANCELLED"
+			}
+			components = append(components, c)
+		}
+	}
+
+	var tasks []*models.ParsedEmails
+	var events []models.Event
+	for _, c := range latest(components) {
+		switch c.Kind {
+		case KindTodo:
+			tasks = append(tasks, toTask(rawEmail, c, loc))
+		case KindEvent:
+			// Начало нужно только новой встрече; для отмены хватает UID
+			if c.Start.IsZero() && c.Status != "CANCELLED" {
+				errs = append(errs, fmt.Errorf("event %q: no DTSTART", c.UID))
+				continue
+			}
+			events = append(events, toEvent(c))
+		}
+	}
+
+	if len(tasks) == 0 && len(events) == 0 {
+		return nil, errs
+	}
+	if len(tasks) == 0 {
+		tasks = append(tasks, &models.ParsedEmails{
+			UserID:   rawEmail.UserID,
+			EmailID:  rawEmail.EmailID,
+			Timezone: loc.String(),
+			From:     rawEmail.From,
+		})
+	}
+	tasks[0].Events = events
+	return tasks, errs
+}
+
+// latest оставляет по одному компоненту на UID — с наибольшим SEQUENCE. Outlook кладёт одно и то же
+// приглашение и в text/calendar, и во вложение. Исключения из серии (RECURRENCE-ID) пропускаются,
+// чтобы отмена одного повтора не закрыла всю серию.
+func latest(components []Component) []Component {
+	var result []Component
+	index := make(map[string]int)
+	for _, c := range components {
+		if c.RecurrenceID != "" {
+			continue
+		}
+		if c.UID == "" {
+			result = append(result, c)
+			continue
+		}
+		key := c.Kind + "/" + c.UID
+		if i, ok := index[key]; ok {
+			if c.Sequence >= result[i].Sequence {
+				result[i] = c
+			}
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, c)
+	}
+	return result
+}
+
+func toTask(rawEmail models.RawEmail, c Component, loc *time.Location) *models.ParsedEmails {
+	due := c.Due
+	if due.IsZero() {
+		due = c.Start
+	}
+
+	parsed := &models.ParsedEmails{
+		UserID:      rawEmail.UserID,
+		EmailID:     rawEmail.EmailID,
+		Title:       strings.TrimSpace(c.Summary),
+		Description: strings.TrimSpace(c.Description),
+		Deadline:    due,
+		Timezone:    loc.String(),
+		From:        rawEmail.From,
+		CalendarUID: c.UID,
+		Sequence:    c.Sequence,
+		RRule:       c.RRule,
+	}
+	if parsed.Title == "" {
+		parsed.Title = rawEmail.Subject
+	}
+
+	switch c.Status {
+	case "CANCELLED":
+		parsed.Status = models.StatusCancelled
+	case "COMPLETED":
+		parsed.Status = models.StatusCompleted
+	}
+	return parsed
+}
+
+func toEvent(c Component) models.Event {
+	e := models.Event{
+		Title:        strings.TrimSpace(c.Summary),
+		Description:  strings.TrimSpace(c.Description),
+		Start:        c.Start,
+		AllDay:       c.AllDay,
+		Location:     strings.TrimSpace(c.Location),
+		JoinURL:      c.ConferenceURL,
+		Participants: participants(c),
+		UID:          c.UID,
+		Sequence:     c.Sequence,
+	}
+	if e.JoinURL == "" {
+		e.JoinURL = event.JoinURL("", strings.Join([]string{c.Location, c.URL, c.Description}, "\n"))
+	}
+
+	end := c.End
+	if end.IsZero() && c.AllDay && !c.Start.IsZero() {
+		end = c.Start.AddDate(0, 0, 1)
+	}
+	if !end.IsZero() {
+		e.End = &end
+	}
+
+	if c.Status == "CANCELLED" {
+		e.Status = models.StatusCancelled
+	}
+	return e
+}
+
+func participants(c Component) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, p := range append([]string{c.Organizer}, c.Attendees...) {
+		p = strings.TrimSpace(p)
+		key := strings.ToLower(p)
+		if p == "" || seen[key] || len(result) >= maxParticipants {
+			continue
+		}
+		seen[key] = true
+		result = append(result, p)
+	}
+	return result
+}
@@ -0,0 +1,256 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Виды компонентов, которые превращаются в события и задачи
+const (
+	KindEvent = "VEVENT"
+	KindTodo  = "VTODO"
+)
+
+var ErrNoCalendar = errors.New("calendar: no VCALENDAR")
+
+// Calendar — разобранный VCALENDAR: метод (REQUEST, CANCEL, PUBLISH…) и его события и задачи
+type Calendar struct {
+	Method     string
+	Components []Component
+}
+
+// Component — VEVENT или VTODO. Время уже переведено в time.Time; для дат без зоны берётся зона пользователя.
+type Component struct {
+	Kind         string
+	UID          string
+	Sequence     int
+	RecurrenceID string
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Status       string
+	Start        time.Time
+	End          time.Time
+	Due          time.Time
+	Duration     time.Duration
+	AllDay       bool
+	Organizer    string
+	Attendees    []string
+	RRule        string
+	// Ссылки на созвон из расширений Google и Microsoft
+	ConferenceURL string
+}
+
+// property — одна строка содержимого: NAME;PARAM=VALUE:value
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse разбирает текст iCalendar (RFC 5545). Неизвестные свойства и вложенные компоненты
+// (VALARM, VTIMEZONE) пропускаются; зоны из TZID берутся из базы IANA, а если её там нет — loc.
+func Parse(text string, loc *time.Location) (*Calendar, error) {
+	var cal *Calendar
+	var current *Component
+	// Глубина вложенности внутри VEVENT/VTODO: свойства VALARM не должны перетирать свойства события
+	nested := 0
+
+	for _, line := range unfold(text) {
+		prop, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case prop.name == "BEGIN":
+			kind := strings.ToUpper(prop.value)
+			switch {
+			case kind == "VCALENDAR" && cal == nil:
+				cal = &Calendar{}
+			case cal == nil:
+			case current != nil:
+				nested++
+			case kind == KindEvent || kind == KindTodo:
+				current = &Component{Kind: kind}
+			}
+			continue
+		case prop.name == "END":
+			kind := strings.ToUpper(prop.value)
+			switch {
+			case nested > 0:
+				nested--
+			case current != nil && kind == current.Kind:
+				if current.Duration > 0 && current.End.IsZero() && !current.Start.IsZero() {
+					current.End = current.Start.Add(current.Duration)
+				}
+				cal.Components = append(cal.Components, *current)
+				current = nil
+			}
+			continue
+		case cal == nil || nested > 0:
+			continue
+		case current == nil:
+			if prop.name == "METHOD" {
+				cal.Method = strings.ToUpper(prop.value)
+			}
+			continue
+		}
+
+		if err := current.set(prop, loc); err != nil {
+			return nil, fmt.Errorf("calendar: %s %s: %w", current.Kind, prop.name, err)
+		}
+	}
+
+	if cal == nil {
+		return nil, ErrNoCalendar
+	}
+	return cal, nil
+}
+
+func (c *Component) set(prop property, loc *time.Location) error {
+	var err error
+	switch prop.name {
+	case "UID":
+		c.UID = prop.value
+	case "SEQUENCE":
+		c.Sequence, _ = strconv.Atoi(prop.value)
+	case "RECURRENCE-ID":
+		c.RecurrenceID = prop.value
+	case "SUMMARY":
+		c.Summary = unescape(prop.value)
+	case "DESCRIPTION":
+		c.Description = unescape(prop.value)
+	case "LOCATION":
+		c.Location = unescape(prop.value)
+	case "URL":
+		c.URL = prop.value
+	case "STATUS":
+		c.Status = strings.ToUpper(prop.value)
+	case "RRULE":
+		c.RRule = prop.value
+	case "ORGANIZER":
+		c.Organizer = person(prop)
+	case "ATTENDEE":
+		if p := person(prop); p != "" {
+			c.Attendees = append(c.Attendees, p)
+		}
+	case "X-GOOGLE-CONFERENCE", "X-MICROSOFT-SKYPETEAMSMEETINGURL":
+		c.ConferenceURL = prop.value
+	case "DTSTART":
+		c.Start, c.AllDay, err = parseTime(prop, loc)
+	case "DTEND":
+		c.End, _, err = parseTime(prop, loc)
+	case "DUE":
+		c.Due, _, err = parseTime(prop, loc)
+	case "DURATION":
+		c.Duration, err = parseDuration(prop.value)
+	}
+	return err
+}
+
+// unfold склеивает перенесённые строки: продолжение начинается с пробела или табуляции
+func unfold(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines
+}
+
+// parseLine разбирает строку с учётом кавычек: в значениях параметров бывают ':' и ';'
+func parseLine(line string) (property, bool) {
+	prop := property{params: map[string]string{}}
+	quoted := false
+	start, key := 0, ""
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case ch == '"':
+			quoted = !quoted
+		case quoted:
+		case ch == '=' && key == "" && prop.name != "":
+			key = strings.ToUpper(line[start:i])
+			start = i + 1
+		case ch == ';' || ch == ':':
+			if prop.name == "" {
+				prop.name = strings.ToUpper(line[:i])
+			} else if key != "" {
+				prop.params[key] = strings.Trim(line[start:i], `"`)
+				key = ""
+			}
+			start = i + 1
+			if ch == ':' {
+				prop.value = line[i+1:]
+				return prop, prop.name != ""
+			}
+		}
+	}
+	return prop, false
+}
+
+// person возвращает адрес из mailto: или, если его нет, имя из CN
+func person(prop property) string {
+	if addr := strings.TrimSpace(prop.value); strings.HasPrefix(strings.ToLower(addr), "mailto:") {
+		return addr[len("mailto:"):]
+	}
+	return prop.params["CN"]
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// parseTime понимает три формы RFC 5545: дату (весь день), UTC с суффиксом Z и местное время с TZID или без него
+func parseTime(prop property, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.value)
+	if prop.params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	zone := loc
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.Trim(tzid, "/")); err == nil {
+			zone = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, zone)
+	return t, false, err
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration разбирает DURATION вида P1DT2H30M или P2W
+func parseDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if n, err := strconv.Atoi(m[i+2]); err == nil {
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
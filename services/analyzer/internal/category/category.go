@@ -0,0 +1,84 @@
+package category
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Config struct {
+	// Категории, из которых модель выбирает одну. Порядок сохраняется в промпте.
+	Taxonomy []string `env:"TAXONOMY" env-default:"work,finance,travel,personal,health,shopping"`
+	MaxTags  int      `env:"MAX_TAGS" env-default:"5"`
+}
+
+// Длиннее тег — уже описание, а не метка
+const maxTagLength = 32
+
+// Taxonomy проверяет категорию и теги из ответа модели: модель может придумать категорию вне списка
+// или вернуть теги с решёткой, в разном регистре и с повторами
+type Taxonomy struct {
+	categories []string
+	known      map[string]bool
+	maxTags    int
+}
+
+func New(cfg *Config) *Taxonomy {
+	t := &Taxonomy{known: make(map[string]bool), maxTags: cfg.MaxTags}
+	for _, c := range cfg.Taxonomy {
+		c = normalize(c)
+		if c == "" || t.known[c] {
+			continue
+		}
+		t.known[c] = true
+		t.categories = append(t.categories, c)
+	}
+	return t
+}
+
+// Prompt — список категорий для промпта. У nil-таксономии категорий нет, а Category и Tags всё отбрасывают.
+func (t *Taxonomy) Prompt() string {
+	if t == nil {
+		return ""
+	}
+	return strings.Join(t.categories, ", ")
+}
+
+// Category возвращает категорию, если она есть в таксономии, иначе пустую строку
+func (t *Taxonomy) Category(category string) string {
+	category = normalize(category)
+	if t == nil || !t.known[category] {
+		return ""
+	}
+	return category
+}
+
+// Tags приводит теги к нижнему регистру без решётки и повторов и обрезает список до MaxTags.
+// Категория среди тегов не повторяется.
+func (t *Taxonomy) Tags(tags []string, category string) []string {
+	if t == nil {
+		return nil
+	}
+	var out []string
+	seen := map[string]bool{category: true}
+	for _, tag := range tags {
+		tag = normalize(strings.TrimLeft(strings.TrimSpace(tag), "#"))
+		if tag == "" || seen[tag] || utf8.RuneCountInString(tag) > maxTagLength {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+		if len(out) == t.maxTags {
+			break
+		}
+	}
+	return out
+}
+
+// normalize сводит пробелы и регистр: "  Business Trip " и "business  trip" — один тег
+func normalize(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '_'
+	})
+	return strings.Join(fields, " ")
+}
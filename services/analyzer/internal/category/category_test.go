@@ -0,0 +1,26 @@
+package category
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxonomy_Category(t *testing.T) {
+	tax := New(&Config{Taxonomy: []string{"Work", "finance", " travel ", "work", ""}, MaxTags: 3})
+
+	assert.Equal(t, "work, finance, travel", tax.Prompt())
+	assert.Equal(t, "finance", tax.Category("Finance"))
+	assert.Equal(t, "travel", tax.Category(" TRAVEL"))
+	assert.Empty(t, tax.Category("hobby"))
+	assert.Empty(t, tax.Category(""))
+}
+
+func TestTaxonomy_Tags(t *testing.T) {
+	tax := New(&Config{Taxonomy: []string{"work"}, MaxTags: 3})
+
+	assert.Equal(t, []string{"project x", "отчёт", "q4"},
+		tax.Tags([]string{"#Project  X", "work", "project_x", "", "Отчёт", "Q4", "budget"}, "work"))
+	assert.Nil(t, tax.Tags([]string{"this tag is far too long to be a useful label"}, ""))
+	assert.Nil(t, tax.Tags(nil, "work"))
+}
@@ -0,0 +1,116 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"reminder-hub/pkg/models"
+)
+
+// Порог, начиная с которого сигналы одной стороны считаются решающими
+const decisiveScore = 2
+
+type Config struct {
+	Enabled bool `env:"ENABLED" env-default:"true"`
+	// Спрашивать маленькую модель, когда эвристики не дали уверенного ответа
+	UseModel bool   `env:"USE_MODEL" env-default:"false"`
+	Model    string `env:"MODEL" env-default:"open-mistral-7b"`
+	// Домены или адреса, письма от которых всегда считаются задачами или спамом
+	TrustedSenders []string `env:"TRUSTED_SENDERS"`
+	BlockedSenders []string `env:"BLOCKED_SENDERS"`
+}
+
+type Result struct {
+	Label   string   `json:"label"`
+	Reasons []string `json:"reasons,omitempty"`
+	// Эвристики не уверены — можно уточнить у модели
+	Uncertain bool `json:"uncertain"`
+}
+
+func (r Result) Actionable() bool {
+	return r.Label == models.LabelActionable
+}
+
+type Classifier struct {
+	cfg *Config
+}
+
+func New(cfg *Config) *Classifier {
+	return &Classifier{cfg: cfg}
+}
+
+// Classify размечает письмо по заголовкам, отправителю и ключевым словам.
+// При сомнениях письмо считается задачей: лишний вызов модели дешевле потерянного дедлайна.
+func (c *Classifier) Classify(email models.RawEmail) Result {
+	if c == nil || !c.cfg.Enabled {
+		return Result{Label: models.LabelActionable}
+	}
+
+	from := strings.ToLower(email.From)
+	switch {
+	case matchSender(from, c.cfg.BlockedSenders):
+		return Result{Label: models.LabelSpam, Reasons: []string{"blocked sender"}}
+	case matchSender(from, c.cfg.TrustedSenders):
+		return Result{Label: models.LabelActionable, Reasons: []string{"trusted sender"}}
+	}
+
+	var s signals
+	s.headers(email.Headers)
+	s.sender(from)
+	s.text(strings.ToLower(email.Subject + "\n" + email.Text))
+
+	switch {
+	case s.spam >= decisiveScore:
+		return Result{Label: models.LabelSpam, Reasons: s.reasons}
+	case s.informational-s.actionable >= decisiveScore:
+		return Result{Label: models.LabelInformational, Reasons: s.reasons}
+	case s.actionable-s.informational >= decisiveScore:
+		return Result{Label: models.LabelActionable, Reasons: s.reasons}
+	}
+	return Result{Label: models.LabelActionable, Reasons: s.reasons, Uncertain: true}
+}
+
+// ModelPrompt — промпт для маленькой модели, которая уточняет неуверенный результат
+func ModelPrompt(email models.RawEmail) string {
+	return fmt.Sprintf(`Classify the email into exactly one label:
+- "actionable": the recipient is asked to do something or there is a deadline, meeting or payment due
+- "informational": newsletters, notifications, receipts, digests, marketing
+- "spam": unsolicited or fraudulent mail
+Answer with JSON only: {"label": "<label>"}
+
+From: %s
+Subject: %s
+
+%s`, email.From, email.Subject, email.Text)
+}
+
+// ParseModelLabel разбирает ответ модели на ModelPrompt
+func ParseModelLabel(content string) (string, error) {
+	var resp struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal([]byte(content), &resp); err != nil {
+		return "", err
+	}
+
+	label := strings.ToLower(strings.TrimSpace(resp.Label))
+	switch label {
+	case models.LabelActionable, models.LabelInformational, models.LabelSpam:
+		return label, nil
+	}
+	return "", fmt.Errorf("unknown label: %q", resp.Label)
+}
+
+func matchSender(from string, senders []string) bool {
+	for _, sender := range senders {
+		sender = strings.ToLower(strings.TrimSpace(sender))
+		if sender == "" {
+			continue
+		}
+		if from == sender || strings.HasSuffix(from, "@"+sender) || strings.HasSuffix(from, "."+sender) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package classifier
+
+import (
+	"testing"
+
+	"reminder-hub/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	c := New(&Config{
+		Enabled:        true,
+		TrustedSenders: []string{"company.example"},
+		BlockedSenders: []string{"spammer.example"},
+	})
+
+	tests := []struct {
+		name      string
+		email     models.RawEmail
+		label     string
+		uncertain bool
+	}{
+		{
+			name: "bulk newsletter",
+			email: models.RawEmail{
+				From:    "news@shop.example",
+				Subject: "Скидки недели",
+				Headers: map[string]string{"List-Unsubscribe": "<mailto:u@shop.example>", "Precedence": "bulk"},
+			},
+			label: models.LabelInformational,
+		},
+		{
+			name: "automated receipt",
+			email: models.RawEmail{
+				From:    "noreply@store.example",
+				Subject: "Your order receipt",
+				Headers: map[string]string{"Auto-Submitted": "auto-generated"},
+			},
+			label: models.LabelInformational,
+		},
+		{
+			name: "spam keywords and flag",
+			email: models.RawEmail{
+				From:    "lucky@winner.example",
+				Subject: "You have won!",
+				Text:    "Claim your prize in our casino",
+				Headers: map[string]string{"X-Spam-Flag": "YES"},
+			},
+			label: models.LabelSpam,
+		},
+		{
+			name:  "blocked sender",
+			email: models.RawEmail{From: "promo@mail.spammer.example", Subject: "Пожалуйста, ответьте"},
+			label: models.LabelSpam,
+		},
+		{
+			name: "trusted sender despite bulk header",
+			email: models.RawEmail{
+				From:    "hr@company.example",
+				Subject: "Рассылка",
+				Headers: map[string]string{"Precedence": "bulk"},
+			},
+			label: models.LabelActionable,
+		},
+		{
+			name: "request with deadline",
+			email: models.RawEmail{
+				From:    "colleague@partner.example",
+				Subject: "Отчёт",
+				Text:    "Пожалуйста, пришлите отчёт, срок — пятница",
+			},
+			label: models.LabelActionable,
+		},
+		{
+			name:      "no signals",
+			email:     models.RawEmail{From: "friend@mail.example", Subject: "Привет", Text: "Как дела?"},
+			label:     models.LabelActionable,
+			uncertain: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.Classify(tt.email)
+			assert.Equal(t, tt.label, result.Label, result.Reasons)
+			assert.Equal(t, tt.uncertain, result.Uncertain)
+		})
+	}
+}
+
+func TestClassify_Disabled(t *testing.T) {
+	result := New(&Config{}).Classify(models.RawEmail{Headers: map[string]string{"Precedence": "bulk"}})
+	assert.True(t, result.Actionable())
+
+	var c *Classifier
+	assert.True(t, c.Classify(models.RawEmail{}).Actionable())
+}
+
+func TestParseModelLabel(t *testing.T) {
+	label, err := ParseModelLabel(`{"label":" Spam "}`)
+	require.NoError(t, err)
+	assert.Equal(t, models.LabelSpam, label)
+
+	_, err = ParseModelLabel(`{"label":"maybe"}`)
+	assert.Error(t, err)
+
+	_, err = ParseModelLabel("not json")
+	assert.Error(t, err)
+}
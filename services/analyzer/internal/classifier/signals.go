@@ -0,0 +1,99 @@
+package classifier
+
+import "strings"
+
+// Локальные части адресов, с которых пишут роботы
+var automatedSenders = []string{
+	"noreply", "no-reply", "donotreply", "do-not-reply", "notifications", "notification",
+	"newsletter", "marketing", "promo", "mailer-daemon", "digest",
+}
+
+var (
+	actionableKeywords = []string{
+		// ru
+		"прошу", "просьба", "пожалуйста", "нужно", "необходимо", "срок", "дедлайн", "до конца",
+		"не позднее", "подтвердите", "оплатите", "сдать", "отправьте", "пришлите", "встреча", "созвон",
+		// en
+		"please", "deadline", "due", "asap", "by monday", "by friday", "by tomorrow", "action required",
+		"submit", "confirm", "reply by", "meeting", "reminder",
+	}
+	informationalKeywords = []string{
+		// ru
+		"отписаться", "рассылк", "скидк", "распродаж", "акция", "чек", "ваш заказ", "квитанция",
+		"дайджест", "новости", "уведомление",
+		// en
+		"unsubscribe", "newsletter", "sale", "% off", "receipt", "order confirmation", "your order",
+		"digest", "liked your", "new follower", "mentioned you", "view in browser",
+	}
+	spamKeywords = []string{
+		"казино", "выигрыш", "вы выиграли", "ставки на спорт", "кредит без отказа",
+		"casino", "you have won", "lottery", "viagra", "crypto giveaway", "claim your prize",
+	}
+)
+
+type signals struct {
+	actionable    int
+	informational int
+	spam          int
+	reasons       []string
+}
+
+func (s *signals) add(score *int, weight int, reason string) {
+	*score += weight
+	s.reasons = append(s.reasons, reason)
+}
+
+func (s *signals) headers(headers map[string]string) {
+	for name, value := range headers {
+		value = strings.ToLower(value)
+		switch strings.ToLower(name) {
+		case "list-unsubscribe":
+			s.add(&s.informational, 2, "list-unsubscribe header")
+		case "list-id":
+			s.add(&s.informational, 1, "list-id header")
+		case "precedence":
+			if value == "bulk" || value == "list" || value == "junk" {
+				s.add(&s.informational, 2, "precedence: "+value)
+			}
+		case "auto-submitted":
+			if value != "no" {
+				s.add(&s.informational, 2, "auto-submitted: "+value)
+			}
+		case "x-spam-flag":
+			if value == "yes" {
+				s.add(&s.spam, 2, "x-spam-flag")
+			}
+		}
+	}
+}
+
+func (s *signals) sender(from string) {
+	local := from
+	if at := strings.Index(from, "@"); at >= 0 {
+		local = from[:at]
+	}
+	for _, automated := range automatedSenders {
+		if local == automated || strings.HasPrefix(local, automated+"+") || strings.HasPrefix(local, automated+".") {
+			s.add(&s.informational, 1, "automated sender")
+			return
+		}
+	}
+}
+
+func (s *signals) text(text string) {
+	for _, keyword := range spamKeywords {
+		if strings.Contains(text, keyword) {
+			s.add(&s.spam, 1, "spam keyword: "+keyword)
+		}
+	}
+	for _, keyword := range informationalKeywords {
+		if strings.Contains(text, keyword) {
+			s.add(&s.informational, 1, "informational keyword: "+keyword)
+		}
+	}
+	for _, keyword := range actionableKeywords {
+		if strings.Contains(text, keyword) {
+			s.add(&s.actionable, 1, "actionable keyword: "+keyword)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package cleaner
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Config struct {
+	Enabled bool `env:"ENABLED" env-default:"true"`
+	// Бюджет текста письма в токенах; 0 — не обрезать
+	MaxTokens int `env:"MAX_TOKENS" env-default:"1500"`
+	// Доля бюджета для конца письма: срок и просьба часто стоят в последнем абзаце
+	TailShare float64 `env:"TAIL_SHARE" env-default:"0.25"`
+}
+
+const (
+	// Оценка без токенизатора: у Mistral в среднем около трёх символов русского или английского текста на токен
+	charsPerToken = 3
+	// Подпись — несколько коротких строк после прощания
+	maxSignatureLines  = 6
+	maxSignatureLength = 80
+	// Длиннее ссылка после удаления меток отслеживания — остаётся только адрес сайта
+	maxURLLength = 150
+	// Пропущенная середина письма
+	ellipsis = "\n[…]\n"
+)
+
+// Result — очищенный текст и что из него убрано
+type Result struct {
+	Text string `json:"text"`
+	// Строки цитируемой переписки: "> ..." и всё после "On ... wrote:"
+	QuotedLines int `json:"quoted_lines"`
+	// Строки подписи вместе с прощанием
+	SignatureLines int `json:"signature_lines"`
+	// Абзацы с дисклеймерами и ссылками отписки
+	BoilerplateParagraphs int `json:"boilerplate_paragraphs"`
+	// Ссылки, из которых убраны метки отслеживания или которые сокращены до адреса сайта
+	TrackingLinks int  `json:"tracking_links"`
+	Truncated     bool `json:"truncated"`
+	TokensBefore  int  `json:"tokens_before"`
+	TokensAfter   int  `json:"tokens_after"`
+}
+
+// Cleaner убирает из текста письма то, что тратит токены и сбивает извлечение: старые сроки
+// из цитируемой переписки, подписи с телефонами, дисклеймеры и длинные ссылки рассылок
+type Cleaner struct {
+	cfg *Config
+}
+
+func New(cfg *Config) *Cleaner {
+	return &Cleaner{cfg: cfg}
+}
+
+// Text — очищенный текст. nil или выключенный Cleaner возвращает текст без изменений.
+func (c *Cleaner) Text(text string) string {
+	return c.Clean(text).Text
+}
+
+// Clean очищает текст письма. Если после очистки ничего не осталось (письмо целиком из цитаты),
+// возвращается исходный текст, обрезанный по бюджету.
+func (c *Cleaner) Clean(text string) Result {
+	result := Result{Text: text, TokensBefore: tokens(text)}
+	if c == nil || !c.cfg.Enabled {
+		result.TokensAfter = result.TokensBefore
+		return result
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	lines, result.QuotedLines = stripQuoted(lines)
+	// Дисклеймер идёт после подписи и иначе не дал бы её узнать
+	lines, result.BoilerplateParagraphs = stripBoilerplate(lines)
+	lines, result.SignatureLines = stripSignature(lines)
+
+	cleaned := strings.TrimSpace(collapseBlankLines(lines))
+	cleaned, result.TrackingLinks = stripTracking(cleaned)
+	if cleaned == "" {
+		cleaned = strings.TrimSpace(text)
+	}
+
+	result.Text, result.Truncated = c.truncate(cleaned)
+	result.TokensAfter = tokens(result.Text)
+	return result
+}
+
+var (
+	// "On Mon, Dec 1, 2025 at 10:00 AM John <john@example.com> wrote:"
+	replyHeaderEN = regexp.MustCompile(`(?i)^on\s.{4,250}\swrote:\s*$`)
+	// "1 декабря 2025 г., в 10:00, Иван <ivan@example.com> написал:", "Иван пишет:"
+	replyHeaderRU = regexp.MustCompile(`(?i)^.{0,250}\s(писал|писала|написал|написала|написал\(а\)|пишет):\s*$`)
+	// Gmail на русском: "пн, 1 дек. 2025 г. в 10:00, Иван <ivan@example.com>:"
+	replyHeaderGmailRU = regexp.MustCompile(`^.{0,100}\d{4}\s*г\..{0,150}<[^<>\s]+@[^<>\s]+>:\s*$`)
+	originalMessage    = regexp.MustCompile(`(?i)^-{2,}\s*(original message|исходное сообщение)\s*-{2,}\s*$`)
+	// Заголовки цитаты Outlook: "From: ..." и через строку-другую "Sent: ..."
+	outlookFrom = regexp.MustCompile(`(?i)^\*?(from|от):\*?\s`)
+	outlookSent = regexp.MustCompile(`(?i)^\*?(sent|date|отправлено|дата):\*?\s`)
+)
+
+// stripQuoted убирает строки "> ..." и всё, начиная с заголовка цитаты ответа.
+// Пересланные письма не трогаются: в них и есть суть.
+func stripQuoted(lines []string) ([]string, int) {
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if quoteHeader(lines, i) {
+			break
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, len(lines) - len(out)
+}
+
+// quoteHeader — со строки i начинается цитата ответа. Gmail переносит длинный заголовок, поэтому
+// строка проверяется и вместе со следующей.
+func quoteHeader(lines []string, i int) bool {
+	line := strings.TrimSpace(lines[i])
+	if line == "" {
+		return false
+	}
+	candidates := []string{line}
+	if i+1 < len(lines) {
+		candidates = append(candidates, line+" "+strings.TrimSpace(lines[i+1]))
+	}
+	for _, c := range candidates {
+		if replyHeaderEN.MatchString(c) || replyHeaderRU.MatchString(c) || replyHeaderGmailRU.MatchString(c) {
+			return true
+		}
+	}
+	if originalMessage.MatchString(line) {
+		return true
+	}
+	if outlookFrom.MatchString(line) {
+		for j := i + 1; j < len(lines) && j <= i+3; j++ {
+			if outlookSent.MatchString(strings.TrimSpace(lines[j])) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	signatureDelimiter = regexp.MustCompile(`^--\s*$`)
+	mobileSignature    = regexp.MustCompile(`(?i)^(sent from my|get outlook for|отправлено с|отправлено из)\b`)
+	signOff            = regexp.MustCompile(`(?i)^(best regards|kind regards|warm regards|regards|best|cheers|thanks|thank you|many thanks|sincerely|с уважением|всего доброго|всего хорошего|спасибо|заранее спасибо|хорошего дня)[,.!]?\s*$`)
+)
+
+// stripSignature обрезает письмо на разделителе "-- ", подписи мобильного клиента или прощании,
+// после которого идут только несколько коротких строк (имя, должность, телефон)
+func stripSignature(lines []string) ([]string, int) {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if signatureDelimiter.MatchString(line) || mobileSignature.MatchString(trimmed) {
+			return lines[:i], len(lines) - i
+		}
+		if signOff.MatchString(trimmed) && signatureTail(lines[i+1:]) {
+			return lines[:i], len(lines) - i
+		}
+	}
+	return lines, 0
+}
+
+func signatureTail(lines []string) bool {
+	count := 0
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		count++
+		if count > maxSignatureLines || utf8.RuneCountInString(line) > maxSignatureLength {
+			return false
+		}
+	}
+	return true
+}
+
+// Признаки дисклеймеров и служебных подвалов рассылок. Одного слова "конфиденциально" мало:
+// оно бывает и в самой просьбе.
+var boilerplate = regexp.MustCompile(`(?i)(received this (e-?mail|message) in error|intended (solely |only )?for the (use of the )?(named )?(addressee|recipient)|confidentiality notice|disclaimer:|unsubscribe|this (e-?mail|message) was sent to|do not reply to this (e-?mail|message)|получили (это|данное) (письмо|сообщение) по ошибке|предназначен[оа]? (исключительно |только )?для (использования )?(адресат|получател)|отписаться|вы получили (это|данное) письмо, (так как|потому что)|не отвечайте на (это|данное) (письмо|сообщение))`)
+
+// stripBoilerplate убирает абзацы с дисклеймерами и ссылками отписки
+func stripBoilerplate(lines []string) ([]string, int) {
+	var out, paragraph []string
+	removed := 0
+	flush := func() {
+		if len(paragraph) > 0 && boilerplate.MatchString(strings.Join(paragraph, " ")) {
+			removed++
+		} else {
+			out = append(out, paragraph...)
+		}
+		paragraph = nil
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			out = append(out, line)
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	flush()
+	return out, removed
+}
+
+var (
+	link = regexp.MustCompile(`https?://[^\s<>"')\]]+`)
+	// Метки отслеживания рассылок и рекламных систем
+	trackingParams = map[string]bool{
+		"fbclid": true, "gclid": true, "yclid": true, "mc_cid": true, "mc_eid": true,
+		"_hsenc": true, "_hsmi": true, "mkt_tok": true, "_openstat": true,
+	}
+)
+
+// stripTracking убирает из ссылок метки utm_* и подобные, а слишком длинные ссылки сокращает до адреса сайта
+func stripTracking(text string) (string, int) {
+	changed := 0
+	text = link.ReplaceAllStringFunc(text, func(raw string) string {
+		// Пунктуация в конце предложения — не часть ссылки
+		trimmed := strings.TrimRight(raw, ".,;:!?")
+		suffix := raw[len(trimmed):]
+		u, err := url.Parse(trimmed)
+		if err != nil || u.Host == "" {
+			return raw
+		}
+
+		out := trimmed
+		if u.RawQuery != "" {
+			query := u.Query()
+			removed := false
+			for key := range query {
+				if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+					query.Del(key)
+					removed = true
+				}
+			}
+			if removed {
+				u.RawQuery = query.Encode()
+				out = u.String()
+			}
+		}
+		if len(out) > maxURLLength {
+			out = u.Scheme + "://" + u.Host + "/…"
+		}
+		if out != trimmed {
+			changed++
+		}
+		return out + suffix
+	})
+	return text, changed
+}
+
+// truncate оставляет начало и конец текста в пределах бюджета, обрезая по границе слова
+func (c *Cleaner) truncate(text string) (string, bool) {
+	limit := c.cfg.MaxTokens * charsPerToken
+	runes := []rune(text)
+	if c.cfg.MaxTokens <= 0 || len(runes) <= limit {
+		return text, false
+	}
+
+	tailShare := c.cfg.TailShare
+	if tailShare < 0 || tailShare >= 1 {
+		tailShare = 0
+	}
+	tailLen := int(float64(limit) * tailShare)
+	headLen := limit - tailLen
+
+	// Слово на границе обрезается целиком
+	head := runes[:headLen]
+	if i := lastSpace(head); i > headLen/2 && !unicode.IsSpace(runes[headLen]) {
+		head = head[:i]
+	}
+	if tailLen == 0 {
+		return strings.TrimSpace(string(head)) + ellipsis, true
+	}
+	start := len(runes) - tailLen
+	tail := runes[start:]
+	if i := firstSpace(tail); i >= 0 && i < tailLen/2 && !unicode.IsSpace(runes[start-1]) {
+		tail = tail[i:]
+	}
+	return strings.TrimSpace(string(head)) + ellipsis + strings.TrimSpace(string(tail)), true
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func firstSpace(runes []rune) int {
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return -1
+}
+
+// collapseBlankLines схлопывает подряд идущие пустые строки, оставшиеся после вырезанных блоков
+func collapseBlankLines(lines []string) string {
+	var b strings.Builder
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func tokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
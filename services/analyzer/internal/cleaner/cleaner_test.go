@@ -0,0 +1,83 @@
+package cleaner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCleaner() *Cleaner {
+	return New(&Config{Enabled: true, MaxTokens: 1500, TailShare: 0.25})
+}
+
+func TestClean_QuotedReplies(t *testing.T) {
+	cases := map[string]string{
+		"gmail en": "Подтверждаю, пришлю до среды.\n\nOn Mon, Dec 1, 2025 at 10:00 AM John Smith <john@example.com>\nwrote:\n> Please send the report by Friday, Nov 28.\n",
+		"ru":       "Подтверждаю, пришлю до среды.\n\n1 декабря 2025 г., в 10:00, Иван Петров <ivan@example.com> написал:\nПришлите отчёт до 28 ноября.\n",
+		"gmail ru": "Подтверждаю, пришлю до среды.\n\nпн, 1 дек. 2025 г. в 10:00, Иван Петров <ivan@example.com>:\nПришлите отчёт до 28 ноября.\n",
+		"outlook":  "Подтверждаю, пришлю до среды.\n\nFrom: Ivan Petrov <ivan@example.com>\nSent: Monday, December 1, 2025 10:00 AM\nTo: me\nSubject: Report\n\nPlease send the report by Nov 28.\n",
+		"original": "Подтверждаю, пришлю до среды.\n-----Original Message-----\nPlease send the report by Nov 28.\n",
+	}
+	for name, text := range cases {
+		result := newCleaner().Clean(text)
+		assert.Equal(t, "Подтверждаю, пришлю до среды.", result.Text, name)
+		assert.Positive(t, result.QuotedLines, name)
+	}
+
+	// Цитата посреди ответа убирается, а текст после неё остаётся
+	result := newCleaner().Clean("> Когда будет отчёт?\nДо пятницы.\n> А презентация?\nВ понедельник.")
+	assert.Equal(t, "До пятницы.\nВ понедельник.", result.Text)
+	assert.Equal(t, 2, result.QuotedLines)
+}
+
+func TestClean_SignatureAndBoilerplate(t *testing.T) {
+	text := "Привет!\n\nПришлите, пожалуйста, акт до 15 декабря.\n\nС уважением,\nИван Петров\nМенеджер проекта\n+7 999 123-45-67\n\n" +
+		"Это сообщение предназначено исключительно для адресата. Если вы получили это письмо по ошибке, удалите его."
+	result := newCleaner().Clean(text)
+	assert.Equal(t, "Привет!\n\nПришлите, пожалуйста, акт до 15 декабря.", result.Text)
+	assert.Equal(t, 5, result.SignatureLines)
+	assert.Equal(t, 1, result.BoilerplateParagraphs)
+
+	result = newCleaner().Clean("Please review the contract by Friday.\n\nTo unsubscribe from these emails click here.\n\nSent from my iPhone")
+	assert.Equal(t, "Please review the contract by Friday.", result.Text)
+	assert.Equal(t, 1, result.BoilerplateParagraphs)
+	assert.Equal(t, 1, result.SignatureLines)
+
+	// "Спасибо" посреди письма — не подпись
+	text = "Спасибо!\nПришлите, пожалуйста, акт до 15 декабря, а счёт — до 20-го. Без них не сможем закрыть месяц и оплатить работы подрядчиков."
+	assert.Equal(t, text, newCleaner().Clean(text).Text)
+}
+
+func TestClean_TrackingLinks(t *testing.T) {
+	result := newCleaner().Clean("Оплатите счёт: https://pay.example.com/invoice/42?id=7&utm_source=email&utm_campaign=dec.\n" +
+		"Созвон: https://us02web.zoom.us/j/123456789?pwd=abc\n" +
+		"Подробнее: https://click.example.com/track/" + strings.Repeat("a", 200))
+	assert.Equal(t, "Оплатите счёт: https://pay.example.com/invoice/42?id=7.\n"+
+		"Созвон: https://us02web.zoom.us/j/123456789?pwd=abc\n"+
+		"Подробнее: https://click.example.com/…", result.Text)
+	assert.Equal(t, 2, result.TrackingLinks)
+}
+
+func TestClean_TruncatesKeepingHeadAndTail(t *testing.T) {
+	c := New(&Config{Enabled: true, MaxTokens: 20, TailShare: 0.25})
+	text := "Начало письма с просьбой. " + strings.Repeat("середина ", 50) + "Срок — пятница."
+
+	result := c.Clean(text)
+	assert.True(t, result.Truncated)
+	assert.True(t, strings.HasPrefix(result.Text, "Начало письма"))
+	assert.True(t, strings.HasSuffix(result.Text, "Срок — пятница."))
+	assert.Contains(t, result.Text, "[…]")
+	assert.Less(t, result.TokensAfter, result.TokensBefore)
+	assert.LessOrEqual(t, len([]rune(result.Text)), 20*charsPerToken+len([]rune(ellipsis)))
+}
+
+func TestClean_Disabled(t *testing.T) {
+	text := "Текст\n> цитата"
+	var c *Cleaner
+	assert.Equal(t, text, c.Text(text))
+	assert.Equal(t, text, New(&Config{}).Text(text))
+
+	// Письмо только из цитаты не превращается в пустое
+	assert.Equal(t, "> Пришлите отчёт", newCleaner().Text("> Пришлите отчёт"))
+}
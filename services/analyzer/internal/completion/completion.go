@@ -0,0 +1,314 @@
+package completion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/models"
+	"reminder-hub/pkg/redis"
+)
+
+const keyPrefix = "analyzer:completion:user:"
+
+// Вклад признаков в уверенность, что письмо закрывает именно эту задачу. Ответ в той же переписке
+// от того же отправителя набирает 0.8 без совпадения заголовка.
+const (
+	threadWeight = 0.6
+	senderWeight = 0.2
+	titleWeight  = 0.4
+)
+
+// Причины сопоставления в TaskCompletion.Reasons
+const (
+	ReasonThread = "same thread"
+	ReasonSender = "same sender"
+	ReasonTitle  = "title match"
+)
+
+type Config struct {
+	Enabled bool `env:"ENABLED" env-default:"true"`
+	// Сколько последних задач пользователя помнить для сопоставления
+	PerUser int           `env:"PER_USER" env-default:"200"`
+	TTL     time.Duration `env:"TTL" env-default:"720h"`
+	// Доля слов заголовка задачи, которые должны встретиться в письме, если оно не из той же переписки
+	MinTitleMatch float64 `env:"MIN_TITLE_MATCH" env-default:"0.5"`
+}
+
+// Backend — подмножество pkg/redis.Client, которое нужно индексу задач
+type Backend interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// entry — задача, которую analyzer опубликовал, и письмо, из которого она извлечена
+type entry struct {
+	EmailID string `json:"email_id"`
+	// Письмо с приглашениями или разметкой schema.org даёт несколько задач, их различают UID и заголовок
+	CalendarUID string    `json:"calendar_uid,omitempty"`
+	MessageID   string    `json:"message_id,omitempty"`
+	Thread      string    `json:"thread,omitempty"`
+	From        string    `json:"from,omitempty"`
+	Title       string    `json:"title"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Index помнит опубликованные задачи пользователя и находит среди них ту, которую закрывает новое письмо.
+// Статусов задач analyzer не знает: задачу, которую пользователь уже закрыл, collector пропустит сам.
+type Index struct {
+	cfg     *Config
+	backend Backend
+	log     *logger.CurrentLogger
+	// Список задач пользователя читается и записывается целиком
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func New(cfg *Config, backend Backend, log *logger.CurrentLogger) *Index {
+	return &Index{cfg: cfg, backend: backend, log: log, now: time.Now}
+}
+
+// NewIndex хранит задачи в Redis, а без него — в памяти процесса. С выключенной настройкой возвращает nil:
+// письма о завершении не ищутся.
+func NewIndex(ctx context.Context, cfg *Config, client *redis.Client, log *logger.CurrentLogger) *Index {
+	if !cfg.Enabled {
+		log.Info(ctx, "Task completion from follow-up emails is disabled")
+		return nil
+	}
+	if client == nil {
+		log.Warn(ctx, "Tasks for completion matching are kept in memory without Redis")
+		return New(cfg, newMemoryBackend(), log)
+	}
+	return New(cfg, client, log)
+}
+
+// Remember добавляет опубликованную задачу письма в индекс. Повторная обработка письма заменяет
+// запись той же задачи, а не добавляет вторую.
+func (x *Index) Remember(ctx context.Context, email models.RawEmail, task *models.ParsedEmails) {
+	if x == nil || task == nil || task.Title == "" {
+		return
+	}
+
+	added := entry{
+		EmailID:     email.EmailID,
+		CalendarUID: task.CalendarUID,
+		MessageID:   messageID(email.MessageID),
+		Thread:      thread(email),
+		From:        address(email.From),
+		Title:       task.Title,
+		CreatedAt:   x.now(),
+	}
+	err := x.update(ctx, email.UserID, func(entries []entry) []entry {
+		entries = append(without(entries, added.key()), added)
+		if len(entries) > x.cfg.PerUser {
+			entries = entries[len(entries)-x.cfg.PerUser:]
+		}
+		return entries
+	})
+	if err != nil {
+		x.log.Warn(ctx, "Failed to remember task for completion matching", "error", err, "email_id", email.EmailID)
+	}
+}
+
+// Forget убирает из индекса задачу, которую предложено закрыть. Остальные задачи того же письма остаются.
+func (x *Index) Forget(ctx context.Context, suggestion *models.TaskCompletion) {
+	if x == nil || suggestion == nil {
+		return
+	}
+
+	forgotten := entry{EmailID: suggestion.TaskEmailID, CalendarUID: suggestion.TaskCalendarUID, Title: suggestion.TaskTitle}
+	err := x.update(ctx, suggestion.UserID, func(entries []entry) []entry {
+		return without(entries, forgotten.key())
+	})
+	if err != nil {
+		x.log.Warn(ctx, "Failed to forget completed task", "error", err, "email_id", suggestion.TaskEmailID)
+	}
+}
+
+// Match ищет задачу, которую закрывает письмо email с признаком signal. body — текст письма без цитат.
+// Кандидат — задача из той же переписки или с заголовком, слова которого есть в письме; из кандидатов
+// выбирается самый уверенный, при равенстве — более новый. nil — подходящей задачи нет.
+func (x *Index) Match(ctx context.Context, email models.RawEmail, body string, signal Signal) *models.TaskCompletion {
+	if x == nil {
+		return nil
+	}
+
+	x.mu.Lock()
+	entries, err := x.load(ctx, email.UserID)
+	x.mu.Unlock()
+	if err != nil {
+		x.log.Warn(ctx, "Failed to load tasks for completion matching", "error", err, "user_id", email.UserID)
+		return nil
+	}
+
+	refs := references(email)
+	root := thread(email)
+	from := address(email.From)
+	text := words(email.Subject + " " + body)
+
+	var best *models.TaskCompletion
+	var bestAt time.Time
+	for _, e := range entries {
+		if e.EmailID == email.EmailID {
+			continue
+		}
+
+		var reasons []string
+		score := 0.0
+		sameThread := refs[e.MessageID] || (root != "" && e.Thread == root)
+		if sameThread {
+			score += threadWeight
+			reasons = append(reasons, ReasonThread)
+		}
+		if from != "" && e.From == from {
+			score += senderWeight
+			reasons = append(reasons, ReasonSender)
+		}
+		match := titleMatch(e.Title, text)
+		if match > 0 {
+			score += titleWeight * match
+			reasons = append(reasons, fmt.Sprintf("%s %.2f", ReasonTitle, match))
+		}
+		if !sameThread && match < x.cfg.MinTitleMatch {
+			continue
+		}
+
+		confidence := math.Round(math.Min(score, 1)*100) / 100
+		if best != nil && (confidence < best.Confidence || (confidence == best.Confidence && !e.CreatedAt.After(bestAt))) {
+			continue
+		}
+		best = &models.TaskCompletion{
+			UserID:          email.UserID,
+			TaskEmailID:     e.EmailID,
+			TaskCalendarUID: e.CalendarUID,
+			TaskTitle:       e.Title,
+			EmailID:         email.EmailID,
+			Signal:          signal.Kind,
+			Phrase:          signal.Phrase,
+			Confidence:      confidence,
+			Reasons:         reasons,
+		}
+		bestAt = e.CreatedAt
+	}
+	return best
+}
+
+// key — задача внутри письма: по UID, если он есть, иначе по заголовку
+func (e entry) key() string {
+	if e.CalendarUID != "" {
+		return e.EmailID + "|uid:" + e.CalendarUID
+	}
+	return e.EmailID + "|title:" + e.Title
+}
+
+func without(entries []entry, key string) []entry {
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.key() != key {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func (x *Index) update(ctx context.Context, userID string, change func([]entry) []entry) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	entries, err := x.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(change(entries))
+	if err != nil {
+		return err
+	}
+	return x.backend.Set(ctx, keyPrefix+userID, string(data), x.cfg.TTL)
+}
+
+func (x *Index) load(ctx context.Context, userID string) ([]entry, error) {
+	raw, err := x.backend.Get(ctx, keyPrefix+userID)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []entry
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// references — Message-ID писем, на которые отвечает письмо (In-Reply-To и References)
+func references(email models.RawEmail) map[string]bool {
+	refs := make(map[string]bool)
+	for _, header := range []string{"In-Reply-To", "References"} {
+		for _, id := range strings.Fields(email.Headers[header]) {
+			if id = messageID(id); id != "" {
+				refs[id] = true
+			}
+		}
+	}
+	return refs
+}
+
+// thread — первое письмо переписки: начало References, иначе In-Reply-To, иначе само письмо
+func thread(email models.RawEmail) string {
+	for _, header := range []string{"References", "In-Reply-To"} {
+		if ids := strings.Fields(email.Headers[header]); len(ids) > 0 {
+			return messageID(ids[0])
+		}
+	}
+	return messageID(email.MessageID)
+}
+
+func messageID(id string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(id), "<>"))
+}
+
+// address — адрес отправителя без имени: "Anna <anna@example.com>" и "ANNA@example.com" — один отправитель
+func address(from string) string {
+	if parsed, err := mail.ParseAddress(from); err == nil {
+		return strings.ToLower(parsed.Address)
+	}
+	return strings.ToLower(strings.TrimSpace(from))
+}
+
+// titleMatch — доля слов заголовка задачи, которые встречаются в письме
+func titleMatch(title string, text map[string]bool) float64 {
+	titleWords := words(title)
+	if len(titleWords) == 0 {
+		return 0
+	}
+	found := 0
+	for w := range titleWords {
+		if text[w] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(titleWords))
+}
+
+// words — множество слов текста без регистра; короткие слова и предлоги не связывают письмо с задачей
+func words(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(w) >= 3 {
+			set[w] = true
+		}
+	}
+	return set
+}
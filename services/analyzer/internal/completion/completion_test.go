@@ -0,0 +1,160 @@
+package completion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/logger/zaplogger"
+	"reminder-hub/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+)
+
+type simpleLifecycle struct{}
+
+func (s *simpleLifecycle) Append(hook fx.Hook) {}
+
+func testIndex() *Index {
+	cfg := &Config{Enabled: true, PerUser: 3, TTL: time.Hour, MinTitleMatch: 0.5}
+	return New(cfg, newMemoryBackend(), logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(&simpleLifecycle{}, "test")))
+}
+
+func task(title string) *models.ParsedEmails {
+	return &models.ParsedEmails{Title: title}
+}
+
+func email(id, messageID, from, subject string, headers map[string]string) models.RawEmail {
+	return models.RawEmail{UserID: "user-1", EmailID: id, MessageID: messageID, From: from, Subject: subject, Headers: headers}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+		kind    string
+		ok      bool
+	}{
+		{"payment en", "Re: Invoice #42", "Hi! Payment received, thank you.", models.CompletionPayment, true},
+		{"payment ru", "Счёт за хостинг", "Оплата получена, спасибо!", models.CompletionPayment, true},
+		{"delivery en", "Your order", "Your parcel has been delivered to the pickup point.", models.CompletionDelivery, true},
+		{"delivery ru", "Заказ 123", "Посылка вручена адресату", models.CompletionDelivery, true},
+		{"acknowledgement en", "Re: Report", "Thanks, got the report.", models.CompletionAcknowledgement, true},
+		{"acknowledgement ru", "Re: Отчёт", "Спасибо, получила", models.CompletionAcknowledgement, true},
+		{"negation en", "Invoice", "Payment not received yet, please pay by Friday.", "", false},
+		{"negation ru", "Счёт", "Оплата не поступила, оплатите до пятницы", "", false},
+		{"no signal", "Встреча", "Давайте встретимся в пятницу", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signal, ok := Detect(tt.subject, tt.body)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.kind, signal.Kind)
+			if ok {
+				assert.NotEmpty(t, signal.Phrase)
+			}
+		})
+	}
+}
+
+func TestIndex_MatchByThread(t *testing.T) {
+	index := testIndex()
+	ctx := context.Background()
+
+	index.Remember(ctx, email("invoice", "<Invoice-1@example.com>", "Billing <billing@example.com>", "Счёт", nil), task("Оплатить счёт за хостинг"))
+	index.Remember(ctx, email("report", "<report-1@example.com>", "boss@example.com", "Отчёт", nil), task("Отправить квартальный отчёт"))
+
+	reply := email("reply", "<reply@example.com>", "billing@example.com", "Re: Счёт", map[string]string{
+		"In-Reply-To": "<invoice-1@example.com>",
+		"References":  "<invoice-1@example.com>",
+	})
+	signal := Signal{Kind: models.CompletionPayment, Phrase: "Оплата получена"}
+
+	got := index.Match(ctx, reply, "Оплата получена, спасибо", signal)
+	require.NotNil(t, got)
+	assert.Equal(t, "invoice", got.TaskEmailID)
+	assert.Equal(t, "reply", got.EmailID)
+	assert.Equal(t, models.CompletionPayment, got.Signal)
+	// Тема "Re: Счёт" совпадает с одним из трёх слов заголовка
+	assert.Equal(t, 0.93, got.Confidence)
+	assert.Equal(t, []string{ReasonThread, ReasonSender, ReasonTitle + " 0.33"}, got.Reasons)
+
+	index.Forget(ctx, got)
+	assert.Nil(t, index.Match(ctx, reply, "Оплата получена, спасибо", signal))
+}
+
+func TestIndex_MatchByTitle(t *testing.T) {
+	index := testIndex()
+	ctx := context.Background()
+
+	index.Remember(ctx, email("old", "<old@example.com>", "a@example.com", "Отчёт", nil), task("Квартальный отчёт"))
+	index.Remember(ctx, email("other", "<other@example.com>", "b@example.com", "Хостинг", nil), task("Оплатить хостинг"))
+	index.now = func() time.Time { return time.Now().Add(time.Hour) }
+	index.Remember(ctx, email("new", "<new@example.com>", "a@example.com", "Отчёт", nil), task("Квартальный отчёт"))
+
+	// Письмо из другой переписки: задача находится по заголовку, при равной уверенности — более новая
+	ack := email("ack", "<ack@example.com>", "c@example.com", "Квартальный отчёт", nil)
+	got := index.Match(ctx, ack, "Спасибо, получил", Signal{Kind: models.CompletionAcknowledgement})
+	require.NotNil(t, got)
+	assert.Equal(t, "new", got.TaskEmailID)
+	assert.Equal(t, 0.4, got.Confidence)
+
+	// Без совпадения заголовка и переписки кандидатов нет
+	unrelated := email("unrelated", "<x@example.com>", "c@example.com", "Заказ", nil)
+	assert.Nil(t, index.Match(ctx, unrelated, "Посылка доставлена", Signal{Kind: models.CompletionDelivery}))
+}
+
+func TestIndex_RememberLimitsPerUser(t *testing.T) {
+	index := testIndex()
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		index.Remember(ctx, email(id, "<"+id+"@example.com>", "a@example.com", "Тема", nil), task("Задача "+id))
+	}
+	index.Remember(ctx, email("e", "<e@example.com>", "a@example.com", "Тема", nil), task(""))
+
+	entries, err := index.load(ctx, "user-1")
+	require.NoError(t, err)
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.EmailID)
+	}
+	assert.Equal(t, []string{"b", "c", "d"}, ids)
+}
+
+func TestIndex_ForgetOnlyMatchedTask(t *testing.T) {
+	index := testIndex()
+	ctx := context.Background()
+
+	// Письмо с двумя бронированиями даёт две задачи с одним email_id
+	booking := email("booking", "<booking@example.com>", "hotels@example.com", "Бронирования", nil)
+	index.Remember(ctx, booking, &models.ParsedEmails{Title: "Оплатить отель в Казани", CalendarUID: "kazan"})
+	index.Remember(ctx, booking, &models.ParsedEmails{Title: "Оплатить отель в Сочи", CalendarUID: "sochi"})
+	// Повторная обработка письма не дублирует задачу
+	index.Remember(ctx, booking, &models.ParsedEmails{Title: "Оплатить отель в Сочи", CalendarUID: "sochi"})
+
+	paid := email("paid", "<paid@example.com>", "pay@example.com", "Отель в Сочи", nil)
+	got := index.Match(ctx, paid, "Оплата получена: отель в Сочи", Signal{Kind: models.CompletionPayment})
+	require.NotNil(t, got)
+	assert.Equal(t, "sochi", got.TaskCalendarUID)
+	assert.Equal(t, "Оплатить отель в Сочи", got.TaskTitle)
+
+	index.Forget(ctx, got)
+	entries, err := index.load(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "kazan", entries[0].CalendarUID)
+}
+
+func TestIndex_Nil(t *testing.T) {
+	var index *Index
+	ctx := context.Background()
+
+	index.Remember(ctx, email("a", "", "", "", nil), task("Задача"))
+	index.Forget(ctx, &models.TaskCompletion{UserID: "user-1", TaskEmailID: "a"})
+	assert.Nil(t, index.Match(ctx, email("b", "", "", "", nil), "", Signal{}))
+}
@@ -0,0 +1,56 @@
+package completion
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"reminder-hub/pkg/models"
+)
+
+// Signal — признак того, что письмо закрывает дело: оплата прошла, посылка доставлена, адресат получил то, что просил
+type Signal struct {
+	Kind string
+	// Фраза из письма, по которой найден признак
+	Phrase string
+}
+
+type pattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// Границы слов через \b в RE2 работают только для ASCII, поэтому для кириллицы граница — не буква
+const (
+	start = `(?:^|[^\p{L}])`
+	end   = `(?:$|[^\p{L}])`
+)
+
+var patterns = []pattern{
+	{models.CompletionPayment, regexp.MustCompile(`(?i)` + start + `(payment (has been |was )?received|received your payment|thank you for your payment|(invoice|bill) (has been |was |is )?paid|paid in full)` + end)},
+	{models.CompletionPayment, regexp.MustCompile(`(?i)` + start + `(оплата (получена|поступила|прошла|зачислена)|плат[её]ж (получен|поступил|прош[её]л|зачислен)|сч[её]т оплачен|спасибо за оплату|оплату получили)` + end)},
+	{models.CompletionDelivery, regexp.MustCompile(`(?i)` + start + `((has been |was )delivered|delivered to|successfully delivered)` + end)},
+	{models.CompletionDelivery, regexp.MustCompile(`(?i)` + start + `((посылка|заказ|отправление) (доставлен[аоы]?|вручен[аоы]?|получен[аоы]?)|вручено адресату)` + end)},
+	{models.CompletionAcknowledgement, regexp.MustCompile(`(?i)` + start + `(thanks?( you)?,? (i )?(got|received) (it|the [\p{L}]+|your [\p{L}]+)|(got|received) (it|the [\p{L}]+),? thanks?)` + end)},
+	{models.CompletionAcknowledgement, regexp.MustCompile(`(?i)` + start + `(спасибо,? (вс[её] )?получил[аи]?|получил[аи]?,? спасибо|вс[её] получил[аи]?)` + end)},
+}
+
+// Отрицания, рядом с которыми те же слова значат обратное: "оплата не поступила", "payment not received"
+var negations = regexp.MustCompile(`(?i)` + start + `(not (yet )?(been )?(received|delivered|paid)|payment (was |has )?not|haven't (received|got)|didn't (receive|get)|` +
+	`не (был[аои]? )?(получен[аоы]?|поступил[аи]?|прош[её]л|прошла|доставлен[аоы]?|вручен[аоы]?|оплачен|получил[аи]?)|` +
+	`(оплата|плат[её]ж|посылка|заказ) (ещ[её] )?не)` + end)
+
+// Detect ищет признак завершения в теме и тексте письма. Текст должен быть без цитат: в цитате
+// исходного письма те же слова ("пришлите счёт, когда оплата поступит") ничего не закрывают.
+func Detect(subject, body string) (Signal, bool) {
+	text := subject + "\n" + body
+	if negations.MatchString(text) {
+		return Signal{}, false
+	}
+	for _, p := range patterns {
+		if m := p.re.FindString(text); m != "" {
+			return Signal{Kind: p.kind, Phrase: strings.TrimFunc(m, func(r rune) bool { return !unicode.IsLetter(r) })}, true
+		}
+	}
+	return Signal{}, false
+}
@@ -0,0 +1,267 @@
+package confidence
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"reminder-hub/pkg/models"
+	"reminder-hub/services/analyzer/internal/deadline"
+)
+
+const (
+	maxTitleLength = 150
+	// Дедлайн дальше года от письма почти всегда означает неверно понятый год
+	maxDeadlineAhead = 366 * 24 * time.Hour
+	dateLayout       = "2006-01-02"
+)
+
+type Config struct {
+	// Если уверенность хотя бы в одном поле ниже порога, задача уходит на проверку пользователю
+	Threshold float64 `env:"THRESHOLD" env-default:"0.6"`
+}
+
+// Reported — самооценка модели из поля "confidence" ответа; nil — модель её не прислала
+type Reported struct {
+	Title    *float64 `json:"title"`
+	Deadline *float64 `json:"deadline"`
+}
+
+// Input — всё, что нужно для проверки одного извлечения
+type Input struct {
+	Subject string
+	Body    string
+	Title   string
+	// Дедлайн в том виде, как его вернула модель, и результат его разрешения
+	Deadline   *deadline.Spec
+	Resolved   time.Time
+	ResolveErr error
+	Reference  time.Time
+	Reported   *Reported
+}
+
+// Scorer сводит самооценку модели и проверки ответа по тексту письма в уверенность по полям
+type Scorer struct {
+	cfg *Config
+}
+
+func New(cfg *Config) *Scorer {
+	return &Scorer{cfg: cfg}
+}
+
+// Apply проставляет задаче уверенность и, если она ниже порога, статус needs_review с причинами
+func (s *Scorer) Apply(parsed *models.ParsedEmails, in Input) {
+	if s == nil {
+		return
+	}
+
+	text := strings.ToLower(in.Subject + "\n" + in.Body)
+	var reasons []string
+
+	title, titleReasons := checkTitle(in.Title, text)
+	deadlineScore, deadlineReasons := checkDeadline(in, text)
+	reasons = append(reasons, titleReasons...)
+	reasons = append(reasons, deadlineReasons...)
+
+	var reportedTitle, reportedDeadline *float64
+	if in.Reported != nil {
+		reportedTitle, reportedDeadline = in.Reported.Title, in.Reported.Deadline
+	}
+	if v, ok := reported(reportedTitle); ok {
+		title *= v
+		if v < s.cfg.Threshold {
+			reasons = append(reasons, "model is unsure about the title")
+		}
+	}
+	if v, ok := reported(reportedDeadline); ok {
+		deadlineScore *= v
+		if v < s.cfg.Threshold {
+			reasons = append(reasons, "model is unsure about the deadline")
+		}
+	}
+
+	parsed.Confidence = &models.Confidence{Title: round(title), Deadline: round(deadlineScore)}
+	s.review(parsed, reasons)
+}
+
+// Fallback помечает задачу, собранную без модели: заголовок — тема письма, срок неизвестен
+func (s *Scorer) Fallback(parsed *models.ParsedEmails) {
+	if s == nil {
+		return
+	}
+	parsed.Confidence = &models.Confidence{Title: 0.5, Deadline: 0}
+	s.review(parsed, []string{"extracted without the model"})
+}
+
+func (s *Scorer) review(parsed *models.ParsedEmails, reasons []string) {
+	if parsed.Confidence.Title >= s.cfg.Threshold && parsed.Confidence.Deadline >= s.cfg.Threshold {
+		return
+	}
+	parsed.Status = models.StatusNeedsReview
+	parsed.ReviewReasons = reasons
+}
+
+func checkTitle(title, text string) (float64, []string) {
+	if strings.TrimSpace(title) == "" {
+		return 0, []string{"title is empty"}
+	}
+
+	score := 1.0
+	var reasons []string
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		score *= 0.7
+		reasons = append(reasons, "title is too long")
+	}
+	if !grounded(title, text) {
+		score *= 0.5
+		reasons = append(reasons, "title has no words from the email")
+	}
+	return score, reasons
+}
+
+func checkDeadline(in Input, text string) (float64, []string) {
+	spec := in.Deadline
+	if spec.IsEmpty() {
+		// Модель не нашла срок, хотя в письме есть похожие на него слова
+		if deadlineCue.MatchString(text) {
+			return 0.5, []string{"email mentions a date but no deadline was extracted"}
+		}
+		return 1, nil
+	}
+	if in.ResolveErr != nil || in.Resolved.IsZero() {
+		return 0, []string{"deadline could not be resolved"}
+	}
+
+	score := 1.0
+	var reasons []string
+	reference := in.Reference.In(in.Resolved.Location())
+	if in.Resolved.Format(dateLayout) < reference.Format(dateLayout) {
+		score *= 0.4
+		reasons = append(reasons, "deadline is before the email date")
+	}
+	if in.Resolved.Sub(in.Reference) > maxDeadlineAhead {
+		score *= 0.5
+		reasons = append(reasons, "deadline is more than a year away")
+	}
+
+	switch {
+	case spec.Date != "":
+		if !mentionsDay(spec.Date, text) {
+			score *= 0.5
+			reasons = append(reasons, "deadline date is not in the email")
+		}
+	case spec.Weekday != "":
+		if !mentionsWeekday(spec.Weekday, text) {
+			score *= 0.5
+			reasons = append(reasons, "deadline weekday is not in the email")
+		}
+	case spec.InDays != nil:
+		if !relativeCue.MatchString(text) {
+			score *= 0.5
+			reasons = append(reasons, "relative deadline has no cue in the email")
+		}
+	}
+	return score, reasons
+}
+
+// Mentioned проверяет, что срок из ответа модели есть в тексте письма: число дня для даты,
+// название дня для дня недели, подсказка вроде "завтра" для относительного срока
+func Mentioned(spec *deadline.Spec, text string) bool {
+	text = strings.ToLower(text)
+	switch {
+	case spec.IsEmpty():
+		return true
+	case spec.Date != "":
+		return mentionsDay(spec.Date, text)
+	case spec.Weekday != "":
+		return mentionsWeekday(spec.Weekday, text)
+	case spec.InDays != nil:
+		return relativeCue.MatchString(text)
+	}
+	return true
+}
+
+// grounded проверяет, что хотя бы одно значимое слово заголовка есть в письме
+func grounded(title, text string) bool {
+	significant := 0
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), notWordRune) {
+		if utf8.RuneCountInString(word) < 3 {
+			continue
+		}
+		significant++
+		// Сравниваем по началу слова, чтобы "отчёт" находился в "отчёта"
+		stem := []rune(word)
+		if len(stem) > 5 {
+			stem = stem[:5]
+		}
+		if strings.Contains(text, string(stem)) {
+			return true
+		}
+	}
+	// Заголовок из одних коротких слов проверить нечем
+	return significant == 0
+}
+
+// mentionsDay ищет в тексте число дня из даты YYYY-MM-DD или MM-DD
+func mentionsDay(date, text string) bool {
+	parts := strings.Split(date, "-")
+	day, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return false
+	}
+	for _, number := range numberPattern.FindAllString(text, -1) {
+		if n, err := strconv.Atoi(number); err == nil && n == day {
+			return true
+		}
+	}
+	return false
+}
+
+func mentionsWeekday(weekday, text string) bool {
+	for _, name := range weekdayNames[strings.ToLower(weekday)] {
+		if strings.Contains(text, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// reported отбрасывает отсутствующую или бессмысленную самооценку
+func reported(v *float64) (float64, bool) {
+	if v == nil || math.IsNaN(*v) {
+		return 0, false
+	}
+	return math.Min(math.Max(*v, 0), 1), true
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+var (
+	numberPattern = regexp.MustCompile(`\d+`)
+
+	relativeCue = regexp.MustCompile(`сегодня|завтра|послезавтра|через|на этой неделе|на следующей неделе|today|tonight|tomorrow|\bin \d+ (day|week)|next week|this week`)
+
+	// \b в regexp работает только для ASCII, поэтому русские слова ищутся без него
+	deadlineCue = regexp.MustCompile(`(^|[^\p{L}])до \d|срок|дедлайн|не позднее|deadline|\bdue\b|\buntil\b|\bby (monday|tuesday|wednesday|thursday|friday|saturday|sunday|tomorrow|\d)|\d{1,2}[./]\d{1,2}`)
+
+	// Основы названий дней недели, чтобы находить любые падежи: "в пятницу", "до пятницы"
+	weekdayNames = map[string][]string{
+		"monday":    {"понедельник", "monday", "mon "},
+		"tuesday":   {"вторник", "tuesday", "tue "},
+		"wednesday": {"среду", "среды", "среда", "среде", "wednesday", "wed "},
+		"thursday":  {"четверг", "thursday", "thu "},
+		"friday":    {"пятниц", "friday", "fri "},
+		"saturday":  {"суббот", "saturday", "sat "},
+		"sunday":    {"воскресен", "sunday", "sun "},
+	}
+)
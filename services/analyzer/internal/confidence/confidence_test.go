@@ -0,0 +1,142 @@
+package confidence
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/models"
+	"reminder-hub/services/analyzer/internal/deadline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var reference = time.Date(2025, 12, 10, 9, 0, 0, 0, time.UTC)
+
+func float(v float64) *float64 { return &v }
+
+func intPtr(v int) *int { return &v }
+
+func score(in Input) *models.ParsedEmails {
+	parsed := &models.ParsedEmails{}
+	New(&Config{Threshold: 0.6}).Apply(parsed, in)
+	return parsed
+}
+
+func TestApply_ConfidentExtraction(t *testing.T) {
+	parsed := score(Input{
+		Subject:   "Квартальный отчёт",
+		Body:      "Пришлите, пожалуйста, отчёт до пятницы",
+		Title:     "Прислать квартальный отчёт",
+		Deadline:  &deadline.Spec{Weekday: "friday"},
+		Resolved:  time.Date(2025, 12, 12, 23, 59, 0, 0, time.UTC),
+		Reference: reference,
+		Reported:  &Reported{Title: float(0.9), Deadline: float(0.8)},
+	})
+
+	require.NotNil(t, parsed.Confidence)
+	assert.Equal(t, models.Confidence{Title: 0.9, Deadline: 0.8}, *parsed.Confidence)
+	assert.Empty(t, parsed.Status)
+	assert.Empty(t, parsed.ReviewReasons)
+}
+
+func TestApply_ModelUnsure(t *testing.T) {
+	parsed := score(Input{
+		Subject:   "Встреча",
+		Body:      "Давай как-нибудь встретимся",
+		Title:     "Встреча",
+		Reference: reference,
+		Reported:  &Reported{Title: float(0.9), Deadline: float(0.3)},
+	})
+
+	assert.Equal(t, models.StatusNeedsReview, parsed.Status)
+	assert.Equal(t, 0.3, parsed.Confidence.Deadline)
+	assert.Contains(t, parsed.ReviewReasons, "model is unsure about the deadline")
+}
+
+func TestApply_ValidatorChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     Input
+		reason string
+	}{
+		{
+			name:   "title not grounded",
+			in:     Input{Subject: "Счёт", Body: "Оплатите счёт", Title: "Забронировать отель"},
+			reason: "title has no words from the email",
+		},
+		{
+			name:   "deadline missed",
+			in:     Input{Subject: "Отчёт", Body: "Сдать отчёт до 15.12", Title: "Сдать отчёт"},
+			reason: "email mentions a date but no deadline was extracted",
+		},
+		{
+			name: "deadline in the past",
+			in: Input{Subject: "Отчёт", Body: "Сдать отчёт 5 декабря", Title: "Сдать отчёт",
+				Deadline: &deadline.Spec{Date: "12-05"}, Resolved: time.Date(2025, 12, 5, 23, 59, 0, 0, time.UTC)},
+			reason: "deadline is before the email date",
+		},
+		{
+			name: "date not in email",
+			in: Input{Subject: "Отчёт", Body: "Сдать отчёт на следующей неделе", Title: "Сдать отчёт",
+				Deadline: &deadline.Spec{Date: "2025-12-19"}, Resolved: time.Date(2025, 12, 19, 23, 59, 0, 0, time.UTC)},
+			reason: "deadline date is not in the email",
+		},
+		{
+			name: "relative deadline without cue",
+			in: Input{Subject: "Отчёт", Body: "Сдать отчёт", Title: "Сдать отчёт",
+				Deadline: &deadline.Spec{InDays: intPtr(1)}, Resolved: time.Date(2025, 12, 11, 23, 59, 0, 0, time.UTC)},
+			reason: "relative deadline has no cue in the email",
+		},
+		{
+			name: "unresolved deadline",
+			in: Input{Subject: "Отчёт", Body: "Сдать отчёт", Title: "Сдать отчёт",
+				Deadline: &deadline.Spec{Weekday: "someday"}, ResolveErr: errors.New("unknown weekday")},
+			reason: "deadline could not be resolved",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.in.Reference = reference
+			parsed := score(tt.in)
+
+			assert.Equal(t, models.StatusNeedsReview, parsed.Status)
+			assert.Contains(t, parsed.ReviewReasons, tt.reason)
+		})
+	}
+}
+
+func TestApply_NoSelfReportUsesChecksOnly(t *testing.T) {
+	parsed := score(Input{
+		Subject:   "Invoice #42",
+		Body:      "Please pay the invoice by Friday",
+		Title:     "Pay invoice #42",
+		Deadline:  &deadline.Spec{Weekday: "friday"},
+		Resolved:  time.Date(2025, 12, 12, 23, 59, 0, 0, time.UTC),
+		Reference: reference,
+	})
+
+	assert.Equal(t, models.Confidence{Title: 1, Deadline: 1}, *parsed.Confidence)
+	assert.Empty(t, parsed.Status)
+}
+
+func TestFallback_NeedsReview(t *testing.T) {
+	parsed := &models.ParsedEmails{Title: "Счёт"}
+	New(&Config{Threshold: 0.6}).Fallback(parsed)
+
+	assert.Equal(t, models.StatusNeedsReview, parsed.Status)
+	assert.Equal(t, []string{"extracted without the model"}, parsed.ReviewReasons)
+}
+
+func TestNilScorer_Noop(t *testing.T) {
+	var s *Scorer
+	parsed := &models.ParsedEmails{}
+
+	s.Apply(parsed, Input{})
+	s.Fallback(parsed)
+
+	assert.Nil(t, parsed.Confidence)
+	assert.Empty(t, parsed.Status)
+}
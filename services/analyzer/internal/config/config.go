@@ -8,10 +8,28 @@ import (
 	"reminder-hub/pkg/logger"
 	"reminder-hub/pkg/logger/zaplogger"
 	"reminder-hub/pkg/rabbitmq"
+	"reminder-hub/pkg/redis"
 	"reminder-hub/services/analyzer/internal/ai_agent/mistral"
+	"reminder-hub/services/analyzer/internal/budget"
+	"reminder-hub/services/analyzer/internal/cache"
+	"reminder-hub/services/analyzer/internal/category"
+	"reminder-hub/services/analyzer/internal/classifier"
+	"reminder-hub/services/analyzer/internal/cleaner"
+	"reminder-hub/services/analyzer/internal/completion"
+	"reminder-hub/services/analyzer/internal/confidence"
+	"reminder-hub/services/analyzer/internal/correction"
+	"reminder-hub/services/analyzer/internal/deadline"
+	"reminder-hub/services/analyzer/internal/fairqueue"
+	"reminder-hub/services/analyzer/internal/injection"
+	"reminder-hub/services/analyzer/internal/prompt"
+	"reminder-hub/services/analyzer/internal/ratelimit"
+	"reminder-hub/services/analyzer/internal/redact"
+	"reminder-hub/services/analyzer/internal/routing"
 	"reminder-hub/services/analyzer/internal/server/echoserver"
+	"reminder-hub/services/analyzer/internal/workitem"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
@@ -23,26 +41,89 @@ var configPath string
 type Config struct {
 	Environment   string `env:"ENV" env-default:"development"`
 	ServiceName   string `env:"SERVICE_NAME" env-default:"analyzer"`
+	InternalToken string `env:"INTERNAL_API_TOKEN" env-default:"gateway-secret-token"`
 	Logger        *logger.CurrentLogger
 	Rabbitmq      *rabbitmq.RabbitMQConfig `env-prefix:"RABBITMQ_"`
 	Echo          *echoserver.EchoConfig   `env-prefix:"ECHO_"`
 	MistralConfig *mistral.MistralConfig   `env-prefix:"MISTRAL_"`
+	Deadline      *deadline.Config         `env-prefix:"DEADLINE_"`
+	Prompt        *prompt.Config           `env-prefix:"PROMPT_"`
+	Redis         *redis.Config            `env-prefix:"REDIS_"`
+	Cache         *cache.Config            `env-prefix:"LLM_CACHE_"`
+	Budget        *budget.Config           `env-prefix:"LLM_BUDGET_"`
+	Classifier    *classifier.Config       `env-prefix:"CLASSIFIER_"`
+	Redact        *redact.Config           `env-prefix:"PII_REDACT_"`
+	WorkItem      *workitem.Config         `env-prefix:"WORK_ITEM_"`
+	Confidence    *confidence.Config       `env-prefix:"CONFIDENCE_"`
+	Injection     *injection.Config        `env-prefix:"INJECTION_"`
+	RateLimit     *ratelimit.Config        `env-prefix:"LLM_RATE_"`
+	FairQueue     *fairqueue.Config        `env-prefix:"FAIR_QUEUE_"`
+	Category      *category.Config         `env-prefix:"CATEGORY_"`
+	Routing       *routing.Config          `env-prefix:"LLM_ROUTING_"`
+	Cleaner       *cleaner.Config          `env-prefix:"CLEANER_"`
+	Correction    *correction.Config       `env-prefix:"CORRECTIONS_"`
+	Completion    *completion.Config       `env-prefix:"COMPLETION_"`
+}
+
+// Result раздаёт конфигурации отдельных компонентов через fx
+type Result struct {
+	fx.Out
+
+	Config        *Config
+	Logger        *logger.CurrentLogger
+	Echo          *echoserver.EchoConfig
+	Rabbitmq      *rabbitmq.RabbitMQConfig
+	MistralConfig *mistral.MistralConfig
+	Deadline      *deadline.Config
+	Prompt        *prompt.Config
+	Redis         *redis.Config
+	Cache         *cache.Config
+	Budget        *budget.Config
+	Classifier    *classifier.Config
+	Redact        *redact.Config
+	WorkItem      *workitem.Config
+	Confidence    *confidence.Config
+	Injection     *injection.Config
+	RateLimit     *ratelimit.Config
+	FairQueue     *fairqueue.Config
+	Category      *category.Config
+	Routing       *routing.Config
+	Cleaner       *cleaner.Config
+	Correction    *correction.Config
+	Completion    *completion.Config
 }
 
 func init() {
 	flag.StringVar(&configPath, "config", "", "products write microservice config path")
 }
 
-func InitConfig(fx fx.Lifecycle) (*Config, *logger.CurrentLogger, *echoserver.EchoConfig, *rabbitmq.RabbitMQConfig, *mistral.MistralConfig, error) {
+func InitConfig(lc fx.Lifecycle) (Result, error) {
 
 	_ = godotenv.Load(".env")
 	cfg := &Config{
 		Rabbitmq:      &rabbitmq.RabbitMQConfig{},
 		Echo:          &echoserver.EchoConfig{},
 		MistralConfig: &mistral.MistralConfig{},
+		Deadline:      &deadline.Config{},
+		Prompt:        &prompt.Config{},
+		Redis:         &redis.Config{},
+		Cache:         &cache.Config{},
+		Budget:        &budget.Config{},
+		Classifier:    &classifier.Config{},
+		Redact:        &redact.Config{},
+		WorkItem:      &workitem.Config{},
+		Confidence:    &confidence.Config{},
+		Injection:     &injection.Config{},
+		RateLimit:     &ratelimit.Config{},
+		FairQueue:     &fairqueue.Config{},
+		Category:      &category.Config{},
+		Routing:       &routing.Config{},
+		Cleaner:       &cleaner.Config{},
+		Correction:    &correction.Config{},
+		Completion:    &completion.Config{},
 	}
 	if err := cleanenv.ReadEnv(cfg); err != nil {
-		return nil, nil, nil, nil, nil, fmt.Errorf("failed to parse config %w", err)
+		return Result{}, fmt.Errorf("failed to parse config %w", err)
 	}
 
 	cfg.Rabbitmq = parseRabbitMQConfig()
@@ -53,11 +134,34 @@ func InitConfig(fx fx.Lifecycle) (*Config, *logger.CurrentLogger, *echoserver.Ec
 		}
 	}
 
-	adapter := zaplogger.NewLoggerAdapter(fx, cfg.Environment)
+	adapter := zaplogger.NewLoggerAdapter(lc, cfg.Environment)
 
 	log.Printf("Config WAS PARSED. \n\n THERE IS SOME VALUES: Config:%v\n EchoConfig:%v\n RabbitConfig: Host=%s, Port=%d, User=%s\n", cfg, cfg.Echo, cfg.Rabbitmq.Host, cfg.Rabbitmq.Port, cfg.Rabbitmq.User)
 
-	return cfg, logger.NewCurrentLogger(adapter), cfg.Echo, cfg.Rabbitmq, cfg.MistralConfig, nil
+	return Result{
+		Config:        cfg,
+		Logger:        logger.NewCurrentLogger(adapter),
+		Echo:          cfg.Echo,
+		Rabbitmq:      cfg.Rabbitmq,
+		MistralConfig: cfg.MistralConfig,
+		Deadline:      cfg.Deadline,
+		Prompt:        cfg.Prompt,
+		Redis:         cfg.Redis,
+		Cache:         cfg.Cache,
+		Budget:        cfg.Budget,
+		Classifier:    cfg.Classifier,
+		Redact:        cfg.Redact,
+		WorkItem:      cfg.WorkItem,
+		Confidence:    cfg.Confidence,
+		Injection:     cfg.Injection,
+		RateLimit:     cfg.RateLimit,
+		FairQueue:     cfg.FairQueue,
+		Category:      cfg.Category,
+		Routing:       cfg.Routing,
+		Cleaner:       cfg.Cleaner,
+		Correction:    cfg.Correction,
+		Completion:    cfg.Completion,
+	}, nil
 }
 
 func parseRabbitMQConfig() *rabbitmq.RabbitMQConfig {
@@ -68,13 +172,21 @@ func parseRabbitMQConfig() *rabbitmq.RabbitMQConfig {
 		}
 	}
 
+	// Незаданные или некорректные значения повторов заменяются значениями по умолчанию в RetryPolicy
+	maxAttempts, _ := strconv.Atoi(os.Getenv("RABBITMQ_MAX_ATTEMPTS"))
+	retryDelay, _ := time.ParseDuration(os.Getenv("RABBITMQ_RETRY_DELAY"))
+	maxRetryDelay, _ := time.ParseDuration(os.Getenv("RABBITMQ_MAX_RETRY_DELAY"))
+
 	return &rabbitmq.RabbitMQConfig{
-		Host:         getEnvOrDefault("RABBITMQ_HOST", "localhost"),
-		Port:         port,
-		User:         getEnvOrDefault("RABBITMQ_USER", "guest"),
-		Password:     getEnvOrDefault("RABBITMQ_PASSWORD", "guest"),
-		ExchangeName: getEnvOrDefault("RABBITMQ_EXCHANGE", "donotmatter"),
-		Kind:         getEnvOrDefault("RABBITMQ_KIND", "topic"),
+		Host:          getEnvOrDefault("RABBITMQ_HOST", "localhost"),
+		Port:          port,
+		User:          getEnvOrDefault("RABBITMQ_USER", "guest"),
+		Password:      getEnvOrDefault("RABBITMQ_PASSWORD", "guest"),
+		ExchangeName:  getEnvOrDefault("RABBITMQ_EXCHANGE", "donotmatter"),
+		Kind:          getEnvOrDefault("RABBITMQ_KIND", "topic"),
+		MaxAttempts:   maxAttempts,
+		RetryDelay:    retryDelay,
+		MaxRetryDelay: maxRetryDelay,
 	}
 }
 
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -61,6 +62,20 @@ func TestParseRabbitMQConfig_WithDefaults(t *testing.T) {
 	assert.Equal(t, "topic", cfg.Kind)
 }
 
+func TestParseRabbitMQConfig_RetryPolicy(t *testing.T) {
+	os.Setenv("RABBITMQ_MAX_ATTEMPTS", "3")
+	os.Setenv("RABBITMQ_RETRY_DELAY", "2s")
+	defer os.Unsetenv("RABBITMQ_MAX_ATTEMPTS")
+	defer os.Unsetenv("RABBITMQ_RETRY_DELAY")
+
+	policy := parseRabbitMQConfig().RetryPolicy()
+
+	assert.Equal(t, 3, policy.MaxAttempts)
+	assert.Equal(t, 2*time.Second, policy.Delay)
+	// Не заданное значение берётся по умолчанию
+	assert.Equal(t, 5*time.Minute, policy.MaxDelay)
+}
+
 func TestGetEnvOrDefault_WithValue(t *testing.T) {
 	os.Setenv("TEST_KEY", "test_value")
 	defer os.Unsetenv("TEST_KEY")
@@ -0,0 +1,285 @@
+package correction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/models"
+	"reminder-hub/pkg/redis"
+)
+
+const (
+	emailPrefix = "analyzer:corrections:email:"
+	userPrefix  = "analyzer:corrections:user:"
+)
+
+type Config struct {
+	Enabled bool `env:"ENABLED" env-default:"true"`
+	// Сколько исправлений показать модели в одном промпте и сколько символов они могут занять вместе
+	MaxExamples int `env:"MAX_EXAMPLES" env-default:"3"`
+	MaxChars    int `env:"MAX_CHARS" env-default:"1500"`
+	// Сколько последних исправлений помнить для одного пользователя
+	PerUser int `env:"PER_USER" env-default:"50"`
+	// Сколько символов текста письма хранить в примере
+	ExcerptChars int `env:"EXCERPT_CHARS" env-default:"300"`
+	// Доля общих слов, начиная с которой прошлое письмо считается похожим
+	MinSimilarity float64 `env:"MIN_SIMILARITY" env-default:"0.1"`
+	// Сколько ждать исправления после разбора письма и сколько хранить сами исправления
+	TTL time.Duration `env:"TTL" env-default:"720h"`
+}
+
+// Backend — подмножество pkg/redis.Client, которое нужно хранилищу исправлений
+type Backend interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// Example — исправление пользователя вместе с письмом, из которого модель извлекла задачу.
+// Тема и отрывок письма хранятся в том виде, в каком их видела модель, то есть без персональных данных.
+type Example struct {
+	EmailID     string            `json:"email_id"`
+	Subject     string            `json:"subject"`
+	Excerpt     string            `json:"excerpt"`
+	Original    models.TaskFields `json:"original"`
+	Corrected   models.TaskFields `json:"corrected"`
+	Timezone    string            `json:"timezone,omitempty"`
+	CorrectedAt time.Time         `json:"corrected_at"`
+}
+
+// email — письмо, разобранное моделью; ждёт исправления задачи до TTL
+type email struct {
+	Subject string `json:"subject"`
+	Excerpt string `json:"excerpt"`
+}
+
+// Store хранит исправления каждого пользователя и подбирает из них примеры для промпта
+type Store struct {
+	cfg     *Config
+	backend Backend
+	log     *logger.CurrentLogger
+	// Список исправлений пользователя читается и записывается целиком
+	mu sync.Mutex
+}
+
+func New(cfg *Config, backend Backend, log *logger.CurrentLogger) *Store {
+	return &Store{cfg: cfg, backend: backend, log: log}
+}
+
+// NewStore хранит исправления в Redis, а без него — в памяти процесса. С выключенной настройкой возвращает nil:
+// модель работает без примеров.
+func NewStore(ctx context.Context, cfg *Config, client *redis.Client, log *logger.CurrentLogger) *Store {
+	if !cfg.Enabled {
+		log.Info(ctx, "Learning from user corrections is disabled")
+		return nil
+	}
+	if client == nil {
+		log.Warn(ctx, "User corrections are kept in memory without Redis")
+		return New(cfg, newMemoryBackend(), log)
+	}
+	return New(cfg, client, log)
+}
+
+// Remember сохраняет письмо, которое видела модель: исправление из collector приходит только с email_id
+func (s *Store) Remember(ctx context.Context, emailID, subject, body string) {
+	if s == nil {
+		return
+	}
+
+	data, err := json.Marshal(email{Subject: subject, Excerpt: s.excerpt(body)})
+	if err != nil {
+		return
+	}
+	if err := s.backend.Set(ctx, emailPrefix+emailID, string(data), s.cfg.TTL); err != nil {
+		s.log.Warn(ctx, "Failed to remember email for corrections", "error", err, "email_id", emailID)
+	}
+}
+
+// Add сохраняет исправление пользователя. Повторное исправление той же задачи заменяет прежнее.
+// Письмо, которое модель не разбирала или разбирала дольше TTL назад, пропускается без ошибки.
+func (s *Store) Add(ctx context.Context, c models.TaskCorrection) error {
+	if s == nil {
+		return nil
+	}
+
+	raw, err := s.backend.Get(ctx, emailPrefix+c.EmailID)
+	if errors.Is(err, redis.Nil) {
+		s.log.Info(ctx, "Correction for unknown email skipped", "email_id", c.EmailID, "user_id", c.UserID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var source email
+	if err := json.Unmarshal([]byte(raw), &source); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	examples, err := s.load(ctx, c.UserID)
+	if err != nil {
+		return err
+	}
+	kept := examples[:0]
+	for _, ex := range examples {
+		if ex.EmailID != c.EmailID {
+			kept = append(kept, ex)
+		}
+	}
+	kept = append(kept, Example{
+		EmailID:     c.EmailID,
+		Subject:     source.Subject,
+		Excerpt:     source.Excerpt,
+		Original:    c.Original,
+		Corrected:   c.Corrected,
+		Timezone:    c.Timezone,
+		CorrectedAt: c.CorrectedAt,
+	})
+	if len(kept) > s.cfg.PerUser {
+		kept = kept[len(kept)-s.cfg.PerUser:]
+	}
+
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return err
+	}
+	return s.backend.Set(ctx, userPrefix+c.UserID, string(data), s.cfg.TTL)
+}
+
+// Examples подбирает исправления из самых похожих писем пользователя: не больше MaxExamples
+// и не длиннее MaxChars вместе. Ошибка хранилища означает промпт без примеров.
+func (s *Store) Examples(ctx context.Context, userID, subject, body string) []Example {
+	if s == nil || userID == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	examples, err := s.load(ctx, userID)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Warn(ctx, "Failed to load user corrections", "error", err, "user_id", userID)
+		return nil
+	}
+
+	target := words(subject + " " + s.excerpt(body))
+	type scored struct {
+		example Example
+		score   float64
+	}
+	var candidates []scored
+	for _, ex := range examples {
+		score := similarity(target, words(ex.Subject+" "+ex.Excerpt))
+		if score >= s.cfg.MinSimilarity {
+			candidates = append(candidates, scored{ex, score})
+		}
+	}
+	// При равном сходстве свежее исправление важнее
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].example.CorrectedAt.After(candidates[j].example.CorrectedAt)
+	})
+
+	var selected []Example
+	size := 0
+	for _, c := range candidates {
+		if len(selected) == s.cfg.MaxExamples {
+			break
+		}
+		n := utf8.RuneCountInString(render(c.example))
+		if size+n > s.cfg.MaxChars {
+			continue
+		}
+		size += n
+		selected = append(selected, c.example)
+	}
+	return selected
+}
+
+// Prompt — примеры в виде текста для промпта; без примеров — пустая строка
+func Prompt(examples []Example) string {
+	parts := make([]string, 0, len(examples))
+	for _, ex := range examples {
+		parts = append(parts, render(ex))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func render(ex Example) string {
+	loc, err := time.LoadLocation(ex.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return fmt.Sprintf("Тема: %q\n%s\nИзвлечено: %s\nИсправлено пользователем: %s",
+		ex.Subject, ex.Excerpt, fields(ex.Original, loc), fields(ex.Corrected, loc))
+}
+
+func fields(f models.TaskFields, loc *time.Location) string {
+	deadline := "без срока"
+	if f.Deadline != nil {
+		deadline = f.Deadline.In(loc).Format("2006-01-02 15:04")
+	}
+	return fmt.Sprintf("заголовок %q, срок %s", f.Title, deadline)
+}
+
+func (s *Store) load(ctx context.Context, userID string) ([]Example, error) {
+	raw, err := s.backend.Get(ctx, userPrefix+userID)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var examples []Example
+	if err := json.Unmarshal([]byte(raw), &examples); err != nil {
+		return nil, err
+	}
+	return examples, nil
+}
+
+// excerpt — начало письма без лишних пробелов; по нему же считается сходство
+func (s *Store) excerpt(body string) string {
+	body = strings.Join(strings.Fields(body), " ")
+	if utf8.RuneCountInString(body) <= s.cfg.ExcerptChars {
+		return body
+	}
+	return string([]rune(body)[:s.cfg.ExcerptChars]) + "…"
+}
+
+// words — множество слов текста без регистра; короткие слова и предлоги не делают письма похожими
+func words(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(w) >= 3 {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+// similarity — доля общих слов (коэффициент Жаккара)
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
@@ -0,0 +1,119 @@
+package correction
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/logger/zaplogger"
+	"reminder-hub/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+)
+
+type simpleLifecycle struct{}
+
+func (s *simpleLifecycle) Append(hook fx.Hook) {}
+
+func testStore(cfg *Config) *Store {
+	return New(cfg, newMemoryBackend(), logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(&simpleLifecycle{}, "test")))
+}
+
+func testConfig() *Config {
+	return &Config{Enabled: true, MaxExamples: 2, MaxChars: 1500, PerUser: 3, ExcerptChars: 300, MinSimilarity: 0.1, TTL: time.Hour}
+}
+
+func correct(emailID, original, corrected string, at time.Time) models.TaskCorrection {
+	deadline := time.Date(2026, 11, 28, 15, 0, 0, 0, time.UTC)
+	return models.TaskCorrection{
+		UserID:      "user-1",
+		EmailID:     emailID,
+		Original:    models.TaskFields{Title: original, Deadline: &deadline},
+		Corrected:   models.TaskFields{Title: corrected},
+		Timezone:    "Europe/Moscow",
+		CorrectedAt: at,
+	}
+}
+
+func TestStore_ExamplesPickSimilarCorrections(t *testing.T) {
+	store := testStore(testConfig())
+	ctx := context.Background()
+	now := time.Now()
+
+	store.Remember(ctx, "invoice", "Счёт за ноябрь", "Оплатите счёт за хостинг до 28 ноября")
+	store.Remember(ctx, "report", "Квартальный отчёт", "Пришлите квартальный отчёт по продажам")
+	store.Remember(ctx, "party", "Корпоратив", "Приглашаем на корпоратив в пятницу")
+	require.NoError(t, store.Add(ctx, correct("invoice", "Счёт", "Оплатить хостинг", now)))
+	require.NoError(t, store.Add(ctx, correct("report", "Отчёт", "Отчёт по продажам Q4", now)))
+	require.NoError(t, store.Add(ctx, correct("party", "Корпоратив", "Корпоратив — не задача", now)))
+
+	examples := store.Examples(ctx, "user-1", "Счёт за декабрь", "Оплатите счёт за хостинг до 28 декабря")
+	require.Len(t, examples, 1)
+	assert.Equal(t, "invoice", examples[0].EmailID)
+
+	text := Prompt(examples)
+	assert.Contains(t, text, `Извлечено: заголовок "Счёт", срок 2026-11-28 18:00`)
+	assert.Contains(t, text, `Исправлено пользователем: заголовок "Оплатить хостинг", срок без срока`)
+
+	assert.Empty(t, store.Examples(ctx, "user-2", "Счёт за декабрь", "Оплатите счёт за хостинг"))
+}
+
+func TestStore_AddReplacesAndLimits(t *testing.T) {
+	store := testStore(testConfig())
+	ctx := context.Background()
+	now := time.Now()
+
+	// Исправление письма, которое модель не разбирала, пропускается
+	require.NoError(t, store.Add(ctx, correct("unknown", "Счёт", "Оплатить", now)))
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		store.Remember(ctx, id, "Счёт за хостинг "+id, "Оплатите счёт за хостинг")
+		require.NoError(t, store.Add(ctx, correct(id, "Счёт", "Оплатить хостинг", now)))
+	}
+	require.NoError(t, store.Add(ctx, correct("c", "Счёт", "Оплатить хостинг до обеда", now.Add(time.Minute))))
+
+	examples, err := store.load(ctx, "user-1")
+	require.NoError(t, err)
+	ids := make([]string, 0, len(examples))
+	for _, ex := range examples {
+		ids = append(ids, ex.EmailID)
+	}
+	assert.Equal(t, []string{"b", "d", "c"}, ids)
+
+	// Свежее исправление — первым; третий пример не помещается в MaxExamples
+	selected := store.Examples(ctx, "user-1", "Счёт за хостинг", "Оплатите счёт за хостинг")
+	require.Len(t, selected, 2)
+	assert.Equal(t, "Оплатить хостинг до обеда", selected[0].Corrected.Title)
+}
+
+func TestStore_ExamplesRespectMaxChars(t *testing.T) {
+	cfg := testConfig()
+	cfg.ExcerptChars = 40
+	cfg.MaxChars = 200
+	store := testStore(cfg)
+	ctx := context.Background()
+
+	store.Remember(ctx, "long", "Счёт за хостинг", strings.Repeat("Оплатите счёт за хостинг. ", 20))
+	require.NoError(t, store.Add(ctx, correct("long", "Счёт", "Оплатить хостинг", time.Now())))
+
+	examples := store.Examples(ctx, "user-1", "Счёт за хостинг", "Оплатите счёт за хостинг")
+	require.Len(t, examples, 1)
+	assert.LessOrEqual(t, len([]rune(examples[0].Excerpt)), 41)
+
+	cfg.MaxChars = 50
+	assert.Empty(t, store.Examples(ctx, "user-1", "Счёт за хостинг", "Оплатите счёт за хостинг"))
+}
+
+func TestStore_Nil(t *testing.T) {
+	var store *Store
+	ctx := context.Background()
+
+	store.Remember(ctx, "email-1", "Тема", "Текст")
+	assert.NoError(t, store.Add(ctx, correct("email-1", "Счёт", "Оплатить", time.Now())))
+	assert.Nil(t, store.Examples(ctx, "user-1", "Тема", "Текст"))
+	assert.Empty(t, Prompt(nil))
+}
@@ -0,0 +1,57 @@
+package correction
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"reminder-hub/pkg/redis"
+)
+
+// memoryBackend хранит письма и исправления в памяти процесса, когда Redis недоступен
+type memoryBackend struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	now     func() time.Time
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		values:  make(map[string]string),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (m *memoryBackend) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if deadline, ok := m.expires[key]; ok && m.now().After(deadline) {
+		delete(m.values, key)
+		delete(m.expires, key)
+	}
+	value, ok := m.values[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (m *memoryBackend) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for k, deadline := range m.expires {
+		if now.After(deadline) {
+			delete(m.values, k)
+			delete(m.expires, k)
+		}
+	}
+	m.values[key] = fmt.Sprint(value)
+	m.expires[key] = now.Add(ttl)
+	return nil
+}
@@ -0,0 +1,177 @@
+package deadline
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimezone = "UTC"
+
+	dateLayout      = "2006-01-02"
+	shortDateLayout = "01-02"
+	clockLayout     = "15:04"
+
+	// Если в письме указан только день, дедлайном считается конец этого дня
+	defaultHour   = 23
+	defaultMinute = 59
+)
+
+var ErrEmptySpec = errors.New("deadline spec is empty")
+
+type Config struct {
+	// Используется, если в письме не пришёл часовой пояс пользователя
+	DefaultTimezone string `env:"DEFAULT_TIMEZONE" env-default:"UTC"`
+}
+
+// Spec — дедлайн в том виде, в каком его вернула модель.
+// Модель не вычисляет даты сама: относительные выражения ("завтра", "в пятницу")
+// передаются как смещение и разрешаются в коде относительно даты письма.
+type Spec struct {
+	Date    string `json:"date"`
+	Time    string `json:"time"`
+	InDays  *int   `json:"in_days"`
+	Weekday string `json:"weekday"`
+}
+
+func (s *Spec) IsEmpty() bool {
+	return s == nil || (s.Date == "" && s.InDays == nil && s.Weekday == "")
+}
+
+// LoadLocation возвращает зону пользователя или fallback, если имя пустое или неизвестное
+func LoadLocation(name, fallback string) *time.Location {
+	for _, candidate := range []string{name, fallback} {
+		if candidate == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(candidate); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// Reference возвращает момент, относительно которого разрешаются относительные даты:
+// заголовок Date письма в зоне пользователя, либо now, если дату разобрать не удалось.
+func Reference(date string, loc *time.Location, now time.Time) time.Time {
+	for _, layout := range []string{time.RFC3339, time.RFC1123Z, time.RFC1123} {
+		if t, err := time.Parse(layout, strings.TrimSpace(date)); err == nil {
+			return t.In(loc)
+		}
+	}
+	return now.In(loc)
+}
+
+// Resolve переводит Spec в конкретный момент времени в зоне ref
+func Resolve(spec *Spec, ref time.Time) (time.Time, error) {
+	if spec.IsEmpty() {
+		return time.Time{}, ErrEmptySpec
+	}
+
+	day, err := resolveDay(spec, ref)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	hour, minute := defaultHour, defaultMinute
+	if spec.Time != "" {
+		clock, err := time.Parse(clockLayout, strings.TrimSpace(spec.Time))
+		if err != nil {
+			return time.Time{}, err
+		}
+		hour, minute = clock.Hour(), clock.Minute()
+	}
+
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, ref.Location()), nil
+}
+
+// ResolveEvent переводит время события. Конец — время того же дня ("HH:MM") или длительность в минутах;
+// если не задано ни то, ни другое, end нулевой. Событие без времени начала считается на весь день.
+func ResolveEvent(start *Spec, endTime string, durationMinutes int, ref time.Time) (begin, end time.Time, allDay bool, err error) {
+	if start.IsEmpty() {
+		return time.Time{}, time.Time{}, false, ErrEmptySpec
+	}
+
+	day, err := resolveDay(start, ref)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	if strings.TrimSpace(start.Time) == "" {
+		return day, day.AddDate(0, 0, 1), true, nil
+	}
+
+	begin, err = atClock(day, start.Time)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	switch {
+	case strings.TrimSpace(endTime) != "":
+		end, err = atClock(day, endTime)
+		if err != nil {
+			return time.Time{}, time.Time{}, false, err
+		}
+		// "с 23:00 до 01:00" заканчивается на следующий день
+		if !end.After(begin) {
+			end = end.AddDate(0, 0, 1)
+		}
+	case durationMinutes > 0:
+		end = begin.Add(time.Duration(durationMinutes) * time.Minute)
+	}
+	return begin, end, false, nil
+}
+
+func atClock(day time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse(clockLayout, strings.TrimSpace(clock))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location()), nil
+}
+
+func resolveDay(spec *Spec, ref time.Time) (time.Time, error) {
+	today := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+
+	switch {
+	case spec.InDays != nil:
+		return today.AddDate(0, 0, *spec.InDays), nil
+	case spec.Weekday != "":
+		weekday, err := parseWeekday(spec.Weekday)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// "в пятницу", написанное в пятницу, означает следующую неделю
+		offset := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if offset == 0 {
+			offset = 7
+		}
+		return today.AddDate(0, 0, offset), nil
+	}
+
+	date := strings.TrimSpace(spec.Date)
+	if t, err := time.ParseInLocation(dateLayout, date, ref.Location()); err == nil {
+		return t, nil
+	}
+
+	// Дата без года: берём ближайшую будущую относительно письма
+	t, err := time.ParseInLocation(shortDateLayout, date, ref.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	day := time.Date(today.Year(), t.Month(), t.Day(), 0, 0, 0, 0, ref.Location())
+	if day.Before(today) {
+		day = day.AddDate(1, 0, 0)
+	}
+	return day, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.ToLower(d.String()) == name {
+			return d, nil
+		}
+	}
+	return time.Sunday, errors.New("unknown weekday: " + name)
+}
@@ -0,0 +1,165 @@
+package deadline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int { return &v }
+
+func moscowRef() time.Time {
+	loc := LoadLocation("Europe/Moscow", DefaultTimezone)
+	// Среда, 10 декабря 2025, 23:30 по Москве — в UTC это ещё 20:30 того же дня
+	return Reference("2025-12-10T20:30:00Z", loc, time.Now())
+}
+
+func TestResolve_TomorrowUsesUserZone(t *testing.T) {
+	ref := moscowRef()
+
+	got, err := Resolve(&Spec{InDays: intPtr(1), Time: "10:00"}, ref)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-11T10:00:00+03:00", got.Format(time.RFC3339))
+}
+
+func TestResolve_WeekdayIsStrictlyAfterReference(t *testing.T) {
+	ref := moscowRef()
+
+	got, err := Resolve(&Spec{Weekday: "Wednesday"}, ref)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-17T23:59:00+03:00", got.Format(time.RFC3339))
+}
+
+func TestResolve_DateWithoutYearRollsOver(t *testing.T) {
+	ref := moscowRef()
+
+	got, err := Resolve(&Spec{Date: "01-15", Time: "09:30"}, ref)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2026-01-15T09:30:00+03:00", got.Format(time.RFC3339))
+}
+
+func TestResolve_FullDate(t *testing.T) {
+	ref := moscowRef()
+
+	got, err := Resolve(&Spec{Date: "2025-12-06", Time: "10:30"}, ref)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-06T10:30:00+03:00", got.Format(time.RFC3339))
+}
+
+func TestResolve_Errors(t *testing.T) {
+	ref := moscowRef()
+
+	_, err := Resolve(&Spec{}, ref)
+	assert.ErrorIs(t, err, ErrEmptySpec)
+
+	_, err = Resolve(nil, ref)
+	assert.ErrorIs(t, err, ErrEmptySpec)
+
+	_, err = Resolve(&Spec{Date: "soon"}, ref)
+	assert.Error(t, err)
+
+	_, err = Resolve(&Spec{Weekday: "someday"}, ref)
+	assert.Error(t, err)
+
+	_, err = Resolve(&Spec{InDays: intPtr(1), Time: "25:00"}, ref)
+	assert.Error(t, err)
+}
+
+func TestReference_FallsBackToNow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	got := Reference("not a date", time.UTC, now)
+
+	assert.Equal(t, now, got)
+}
+
+func TestLoadLocation_Fallback(t *testing.T) {
+	assert.Equal(t, "UTC", LoadLocation("Mars/Olympus", "").String())
+	assert.Equal(t, "Europe/Berlin", LoadLocation("", "Europe/Berlin").String())
+}
+
+func TestResolveEvent(t *testing.T) {
+	ref := moscowRef()
+
+	begin, end, allDay, err := ResolveEvent(&Spec{Weekday: "friday", Time: "15:00"}, "16:30", 0, ref)
+	require.NoError(t, err)
+	assert.False(t, allDay)
+	assert.Equal(t, "2025-12-12T15:00:00+03:00", begin.Format(time.RFC3339))
+	assert.Equal(t, "2025-12-12T16:30:00+03:00", end.Format(time.RFC3339))
+
+	_, end, _, err = ResolveEvent(&Spec{InDays: intPtr(1), Time: "23:00"}, "01:00", 0, ref)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-12T01:00:00+03:00", end.Format(time.RFC3339))
+
+	begin, end, _, err = ResolveEvent(&Spec{Date: "12-15", Time: "10:00"}, "", 45, ref)
+	require.NoError(t, err)
+	assert.Equal(t, 45*time.Minute, end.Sub(begin))
+
+	_, end, _, err = ResolveEvent(&Spec{Date: "12-15", Time: "10:00"}, "", 0, ref)
+	require.NoError(t, err)
+	assert.True(t, end.IsZero())
+
+	begin, end, allDay, err = ResolveEvent(&Spec{Date: "2025-12-20"}, "", 0, ref)
+	require.NoError(t, err)
+	assert.True(t, allDay)
+	assert.Equal(t, "2025-12-20T00:00:00+03:00", begin.Format(time.RFC3339))
+	assert.Equal(t, "2025-12-21T00:00:00+03:00", end.Format(time.RFC3339))
+
+	_, _, _, err = ResolveEvent(nil, "", 0, ref)
+	assert.ErrorIs(t, err, ErrEmptySpec)
+}
+
+func TestRecurrence_RRule(t *testing.T) {
+	loc := LoadLocation("Europe/Moscow", DefaultTimezone)
+
+	cases := []struct {
+		spec Recurrence
+		want string
+	}{
+		{Recurrence{Freq: "weekly", Weekdays: []string{"Monday"}}, "FREQ=WEEKLY;BYDAY=MO"},
+		{Recurrence{Freq: "monthly", MonthDay: 5, Count: 6}, "FREQ=MONTHLY;BYMONTHDAY=5;COUNT=6"},
+		{Recurrence{Freq: "weekly", Interval: 2, Weekdays: []string{"tuesday", "friday"}}, "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,FR"},
+		{Recurrence{Freq: "yearly", Month: 3, MonthDay: 31, Until: "2027-03-31"}, "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=31;UNTIL=20270331T205959Z"},
+	}
+	for _, tc := range cases {
+		got, err := tc.spec.RRule(loc)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+
+	_, err := (&Recurrence{Freq: "hourly"}).RRule(loc)
+	assert.Error(t, err)
+	_, err = (&Recurrence{Freq: "weekly", Weekdays: []string{"понедельник"}}).RRule(loc)
+	assert.Error(t, err)
+}
+
+func TestRecurrence_First(t *testing.T) {
+	// Среда, 10 декабря 2025, 23:30 по Москве
+	ref := moscowRef()
+
+	monday, err := (&Recurrence{Freq: "weekly", Weekdays: []string{"monday"}}).First(ref, "10:00")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-15T10:00:00+03:00", monday.Format(time.RFC3339))
+
+	rent, err := (&Recurrence{Freq: "monthly", MonthDay: 5}).First(ref, "")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-01-05T23:59:00+03:00", rent.Format(time.RFC3339))
+
+	lastDay, err := (&Recurrence{Freq: "monthly", MonthDay: -1}).First(ref, "")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-31T23:59:00+03:00", lastDay.Format(time.RFC3339))
+
+	// Сегодняшний конец дня ещё впереди
+	daily, err := (&Recurrence{Freq: "daily"}).First(ref, "")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-10T23:59:00+03:00", daily.Format(time.RFC3339))
+
+	_, err = (&Recurrence{Freq: "monthly"}).First(ref, "")
+	assert.ErrorIs(t, err, ErrNoOccurrence)
+}
@@ -0,0 +1,160 @@
+package deadline
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	untilLayout = "20060102T150405Z"
+	// Ближайшее повторение ищется не дальше года вперёд: у ежегодного правила оно всегда в этом окне
+	maxFirstSearchDays = 366
+)
+
+var (
+	ErrNoOccurrence = errors.New("recurrence has no day to start from")
+
+	rruleFreq = map[string]string{"daily": "DAILY", "weekly": "WEEKLY", "monthly": "MONTHLY", "yearly": "YEARLY"}
+	rruleDay  = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+)
+
+// Recurrence — повторение срока в том виде, в каком его вернула модель: "каждый понедельник",
+// "5-го числа каждого месяца". В RRULE (RFC 5545) оно переводится в коде, как и дедлайн.
+type Recurrence struct {
+	Freq     string   `json:"freq"`
+	Interval int      `json:"interval"`
+	Weekdays []string `json:"weekdays"`
+	// Число месяца; -1 — последний день месяца
+	MonthDay int    `json:"month_day"`
+	Month    int    `json:"month"`
+	Until    string `json:"until"`
+	Count    int    `json:"count"`
+}
+
+func (r *Recurrence) IsEmpty() bool {
+	return r == nil || strings.TrimSpace(r.Freq) == ""
+}
+
+// RRule собирает правило без DTSTART: время и первое повторение задаёт дедлайн задачи.
+// UNTIL переводится в UTC на конец дня в зоне loc, как того требует RFC 5545 для времени с зоной.
+func (r *Recurrence) RRule(loc *time.Location) (string, error) {
+	if r.IsEmpty() {
+		return "", ErrEmptySpec
+	}
+	freq, ok := rruleFreq[strings.ToLower(strings.TrimSpace(r.Freq))]
+	if !ok {
+		return "", fmt.Errorf("unknown recurrence frequency %q", r.Freq)
+	}
+
+	parts := []string{"FREQ=" + freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		days := make([]string, 0, len(r.Weekdays))
+		for _, name := range r.Weekdays {
+			weekday, err := parseWeekday(name)
+			if err != nil {
+				return "", err
+			}
+			days = append(days, rruleDay[weekday])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Month != 0 {
+		if r.Month < 1 || r.Month > 12 {
+			return "", fmt.Errorf("invalid recurrence month %d", r.Month)
+		}
+		parts = append(parts, "BYMONTH="+strconv.Itoa(r.Month))
+	}
+	if r.MonthDay != 0 {
+		if r.MonthDay < -31 || r.MonthDay > 31 {
+			return "", fmt.Errorf("invalid recurrence month day %d", r.MonthDay)
+		}
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	}
+
+	switch until := strings.TrimSpace(r.Until); {
+	case until != "":
+		day, err := time.ParseInLocation(dateLayout, until, loc)
+		if err != nil {
+			return "", err
+		}
+		end := time.Date(day.Year(), day.Month(), day.Day(), defaultHour, defaultMinute, 59, 0, loc)
+		parts = append(parts, "UNTIL="+end.UTC().Format(untilLayout))
+	case r.Count > 0:
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// First возвращает ближайшее повторение строго после ref. Используется, когда модель нашла
+// повторение, но не назвала конкретный срок. clock — время "HH:MM" или пусто (конец дня).
+func (r *Recurrence) First(ref time.Time, clock string) (time.Time, error) {
+	if r.IsEmpty() {
+		return time.Time{}, ErrEmptySpec
+	}
+
+	var weekdays []time.Weekday
+	for _, name := range r.Weekdays {
+		weekday, err := parseWeekday(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		weekdays = append(weekdays, weekday)
+	}
+
+	freq := strings.ToLower(strings.TrimSpace(r.Freq))
+	// Без дня недели или числа месяца непонятно, от какого дня считать повторения
+	if freq != "daily" && len(weekdays) == 0 && r.MonthDay == 0 {
+		return time.Time{}, ErrNoOccurrence
+	}
+
+	today := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+	for i := 0; i <= maxFirstSearchDays; i++ {
+		day := today.AddDate(0, 0, i)
+		if !r.matches(day, weekdays) {
+			continue
+		}
+
+		at := time.Date(day.Year(), day.Month(), day.Day(), defaultHour, defaultMinute, 0, 0, ref.Location())
+		if strings.TrimSpace(clock) != "" {
+			var err error
+			if at, err = atClock(day, clock); err != nil {
+				return time.Time{}, err
+			}
+		}
+		if at.After(ref) {
+			return at, nil
+		}
+	}
+	return time.Time{}, ErrNoOccurrence
+}
+
+func (r *Recurrence) matches(day time.Time, weekdays []time.Weekday) bool {
+	if r.Month != 0 && int(day.Month()) != r.Month {
+		return false
+	}
+	if r.MonthDay != 0 {
+		lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		want := r.MonthDay
+		if want < 0 {
+			want = lastDay + want + 1
+		}
+		if day.Day() != want {
+			return false
+		}
+	}
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, weekday := range weekdays {
+		if day.Weekday() == weekday {
+			return true
+		}
+	}
+	return false
+}
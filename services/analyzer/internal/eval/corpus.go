@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"reminder-hub/pkg/models"
+)
+
+// Case — одно размеченное письмо корпуса (строка JSONL)
+type Case struct {
+	ID       string         `json:"id"`
+	Subject  string         `json:"subject"`
+	Body     string         `json:"body"`
+	Date     string         `json:"date"`
+	Timezone string         `json:"timezone,omitempty"`
+	Expected []ExpectedItem `json:"expected"`
+}
+
+// ExpectedItem — задача, которую должна извлечь модель. Пустой Deadline — задача без срока.
+type ExpectedItem struct {
+	Title    string `json:"title"`
+	Deadline string `json:"deadline,omitempty"`
+}
+
+func (c Case) RawEmail() models.RawEmail {
+	return models.RawEmail{
+		EmailID:  c.ID,
+		UserID:   "eval",
+		Subject:  c.Subject,
+		Text:     c.Body,
+		Date:     c.Date,
+		Timezone: c.Timezone,
+	}
+}
+
+func (e ExpectedItem) deadline() (time.Time, error) {
+	if e.Deadline == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, e.Deadline)
+}
+
+// LoadCorpus читает корпус в формате JSONL; пустые строки и строки с # пропускаются
+func LoadCorpus(path string) ([]Case, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open corpus: %w", err)
+	}
+	defer f.Close()
+
+	var cases []Case
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var c Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("corpus line %d: %w", line, err)
+		}
+		if c.ID == "" {
+			return nil, fmt.Errorf("corpus line %d: id is required", line)
+		}
+		for _, item := range c.Expected {
+			if _, err := item.deadline(); err != nil {
+				return nil, fmt.Errorf("corpus line %d: %w", line, err)
+			}
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read corpus: %w", err)
+	}
+	return cases, nil
+}
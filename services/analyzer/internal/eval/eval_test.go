@@ -0,0 +1,115 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/logger/zaplogger"
+	"reminder-hub/pkg/models"
+	"reminder-hub/services/analyzer/internal/ai_agent/mistral"
+	"reminder-hub/services/analyzer/internal/deadline"
+	"reminder-hub/services/analyzer/internal/prompt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+)
+
+type simpleLifecycle struct{}
+
+func (s *simpleLifecycle) Append(hook fx.Hook) {}
+
+func testLogger() *logger.CurrentLogger {
+	return logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(&simpleLifecycle{}, "test"))
+}
+
+func runTestdata(t *testing.T) *Report {
+	cases, err := LoadCorpus("testdata/corpus.jsonl")
+	require.NoError(t, err)
+	require.Len(t, cases, 3)
+
+	replay, err := LoadReplay("testdata/replay.json")
+	require.NoError(t, err)
+	prompts, err := prompt.New(context.Background(), &prompt.Config{ActiveVersion: "v1"}, nil)
+	require.NoError(t, err)
+
+	agent := mistral.NewWithModel(replay, "replay", &deadline.Config{DefaultTimezone: "UTC"}, prompts, nil)
+	return Run(context.Background(), cases, agent, Options{Tolerance: 24 * time.Hour, TitleThreshold: 0.5}, testLogger())
+}
+
+func TestRun_ReplayCorpus(t *testing.T) {
+	report := runTestdata(t)
+
+	assert.Equal(t, 3, report.Summary.Cases)
+	assert.Equal(t, 0, report.Summary.Errors)
+	assert.Equal(t, 1.0, report.Summary.ItemRecall)
+
+	byID := make(map[string]CaseResult)
+	for _, c := range report.Cases {
+		byID[c.ID] = c
+	}
+	assert.True(t, byID["report-tomorrow"].Items[0].DeadlineExact)
+	// Модель ответила "в четверг" вместо пятницы: мимо точного совпадения, но в пределах суток
+	assert.False(t, byID["invoice-friday"].Items[0].DeadlineExact)
+	assert.True(t, byID["invoice-friday"].Items[0].DeadlineWithinTolerance)
+	assert.True(t, byID["ci-failed"].Items[0].DeadlineExact)
+
+	assert.InDelta(t, 2.0/3, report.Summary.DeadlineExact, 1e-9)
+	assert.Equal(t, 1.0, report.Summary.DeadlineWithinTolerance)
+}
+
+type failingExtractor struct{}
+
+func (failingExtractor) Extract(context.Context, models.RawEmail, *logger.CurrentLogger) (*models.ParsedEmails, error) {
+	return nil, errors.New("provider is down")
+}
+
+func TestRun_ErrorsAreReported(t *testing.T) {
+	report := Run(context.Background(), []Case{{ID: "case-1", Expected: []ExpectedItem{{Title: "x"}}}}, failingExtractor{}, Options{TitleThreshold: 0.5}, testLogger())
+
+	assert.Equal(t, 1, report.Summary.Errors)
+	assert.Equal(t, "provider is down", report.Cases[0].Error)
+	assert.Equal(t, 0.0, report.Summary.ItemRecall)
+}
+
+func TestReplay_MissingRecording(t *testing.T) {
+	_, err := NewReplay(nil).GenerateContent(WithCaseID(context.Background(), "unknown"), nil)
+
+	assert.ErrorIs(t, err, ErrNoRecording)
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, TitleSimilarity("Pay invoice #42", "pay INVOICE 42"))
+	assert.Equal(t, 0.0, TitleSimilarity("Отчёт", "Счёт"))
+	assert.InDelta(t, 0.5, TitleSimilarity("Fix CI pipeline on main", "Fix failed CI pipeline"), 0.2)
+	assert.Equal(t, 1.0, TitleSimilarity("", ""))
+}
+
+func TestReport_SaveLoadAndCompare(t *testing.T) {
+	current := runTestdata(t)
+	path := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, current.Save(path))
+
+	baseline, err := LoadReport(path)
+	require.NoError(t, err)
+	baseline.Summary.DeadlineExact = 1
+	baseline.Cases[1].Items[0].DeadlineExact = true
+
+	var out bytes.Buffer
+	Compare(&out, current, baseline)
+
+	assert.Contains(t, out.String(), "deadline_exact             0.667 (baseline 1.000, -0.333)")
+	assert.Contains(t, out.String(), "changed invoice-friday: matched 1 -> 1, exact deadlines 1 -> 0")
+}
+
+func TestLoadCorpus_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadCorpus(filepath.Join(dir, "missing.jsonl"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/models"
+)
+
+// Extractor — то, что оценивается; реализуется mistral.MistralAgent
+type Extractor interface {
+	Extract(ctx context.Context, rawEmail models.RawEmail, log *logger.CurrentLogger) (*models.ParsedEmails, error)
+}
+
+type Options struct {
+	// Допуск для дедлайна, который не совпал до минуты
+	Tolerance time.Duration
+	// Минимальное сходство заголовков, при котором извлечённая задача засчитывается за ожидаемую
+	TitleThreshold float64
+}
+
+type ExtractedItem struct {
+	Title    string `json:"title"`
+	Deadline string `json:"deadline,omitempty"`
+}
+
+type ItemMatch struct {
+	ExpectedTitle           string  `json:"expected_title"`
+	ExpectedDeadline        string  `json:"expected_deadline,omitempty"`
+	Found                   bool    `json:"found"`
+	TitleSimilarity         float64 `json:"title_similarity"`
+	DeadlineExact           bool    `json:"deadline_exact"`
+	DeadlineWithinTolerance bool    `json:"deadline_within_tolerance"`
+}
+
+type CaseResult struct {
+	ID        string          `json:"id"`
+	Error     string          `json:"error,omitempty"`
+	Expected  int             `json:"expected"`
+	Matched   int             `json:"matched"`
+	Extracted []ExtractedItem `json:"extracted"`
+	Items     []ItemMatch     `json:"items"`
+}
+
+type Summary struct {
+	Cases  int `json:"cases"`
+	Errors int `json:"errors"`
+	// Доли от ожидаемых задач, 0..1
+	ItemRecall              float64 `json:"item_recall"`
+	DeadlineExact           float64 `json:"deadline_exact"`
+	DeadlineWithinTolerance float64 `json:"deadline_within_tolerance"`
+	TitleSimilarity         float64 `json:"title_similarity"`
+}
+
+type RunInfo struct {
+	Provider      string    `json:"provider"`
+	Model         string    `json:"model"`
+	PromptVersion string    `json:"prompt_version"`
+	Corpus        string    `json:"corpus"`
+	Tolerance     string    `json:"tolerance"`
+	StartedAt     time.Time `json:"started_at"`
+}
+
+type Report struct {
+	Run     RunInfo      `json:"run"`
+	Summary Summary      `json:"summary"`
+	Cases   []CaseResult `json:"cases"`
+}
+
+// Run прогоняет корпус через extractor и считает метрики
+func Run(ctx context.Context, cases []Case, extractor Extractor, opts Options, log *logger.CurrentLogger) *Report {
+	report := &Report{Run: RunInfo{Tolerance: opts.Tolerance.String(), StartedAt: time.Now().UTC()}}
+
+	for _, c := range cases {
+		parsed, err := extractor.Extract(WithCaseID(ctx, c.ID), c.RawEmail(), log)
+
+		var extracted []*models.ParsedEmails
+		if err == nil && parsed != nil && parsed.Title != "" {
+			extracted = append(extracted, parsed)
+		}
+
+		result := scoreCase(c, extracted, opts)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		report.Cases = append(report.Cases, result)
+	}
+
+	report.Summary = summarize(report.Cases)
+	return report
+}
+
+func summarize(cases []CaseResult) Summary {
+	s := Summary{Cases: len(cases)}
+	var expected, matched, exact, within int
+	var similarity float64
+	for _, c := range cases {
+		if c.Error != "" {
+			s.Errors++
+		}
+		for _, item := range c.Items {
+			expected++
+			similarity += item.TitleSimilarity
+			if item.Found {
+				matched++
+			}
+			if item.DeadlineExact {
+				exact++
+			}
+			if item.DeadlineWithinTolerance {
+				within++
+			}
+		}
+	}
+	if expected > 0 {
+		s.ItemRecall = float64(matched) / float64(expected)
+		s.DeadlineExact = float64(exact) / float64(expected)
+		s.DeadlineWithinTolerance = float64(within) / float64(expected)
+		s.TitleSimilarity = similarity / float64(expected)
+	}
+	return s
+}
+
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read report: %w", err)
+	}
+
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse report: %w", err)
+	}
+	return &r, nil
+}
+
+// Compare печатает сводку прогона и, если передан baseline, разницу с ним по метрикам и по письмам
+func Compare(w io.Writer, current, baseline *Report) {
+	metrics := []struct {
+		name     string
+		current  float64
+		baseline func(Summary) float64
+	}{
+		{"item_recall", current.Summary.ItemRecall, func(s Summary) float64 { return s.ItemRecall }},
+		{"deadline_exact", current.Summary.DeadlineExact, func(s Summary) float64 { return s.DeadlineExact }},
+		{"deadline_within_tolerance", current.Summary.DeadlineWithinTolerance, func(s Summary) float64 { return s.DeadlineWithinTolerance }},
+		{"title_similarity", current.Summary.TitleSimilarity, func(s Summary) float64 { return s.TitleSimilarity }},
+	}
+
+	fmt.Fprintf(w, "cases: %d, errors: %d\n", current.Summary.Cases, current.Summary.Errors)
+	for _, m := range metrics {
+		if baseline == nil {
+			fmt.Fprintf(w, "%-26s %.3f\n", m.name, m.current)
+			continue
+		}
+		old := m.baseline(baseline.Summary)
+		fmt.Fprintf(w, "%-26s %.3f (baseline %.3f, %+.3f)\n", m.name, m.current, old, m.current-old)
+	}
+	if baseline == nil {
+		return
+	}
+
+	// Письма, где результат изменился — с них стоит начинать разбор
+	before := make(map[string]CaseResult, len(baseline.Cases))
+	for _, c := range baseline.Cases {
+		before[c.ID] = c
+	}
+	var changed []string
+	for _, c := range current.Cases {
+		if old, ok := before[c.ID]; ok && (old.Matched != c.Matched || exactCount(old) != exactCount(c)) {
+			changed = append(changed, fmt.Sprintf("%s: matched %d -> %d, exact deadlines %d -> %d",
+				c.ID, old.Matched, c.Matched, exactCount(old), exactCount(c)))
+		}
+	}
+	sort.Strings(changed)
+	for _, line := range changed {
+		fmt.Fprintln(w, "changed", line)
+	}
+}
+
+func exactCount(c CaseResult) int {
+	n := 0
+	for _, item := range c.Items {
+		if item.DeadlineExact {
+			n++
+		}
+	}
+	return n
+}
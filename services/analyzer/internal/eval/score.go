@@ -0,0 +1,87 @@
+package eval
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"reminder-hub/pkg/models"
+)
+
+// TitleSimilarity — коэффициент Дайса по словам заголовков без учёта регистра и пунктуации, от 0 до 1
+func TitleSimilarity(a, b string) float64 {
+	wordsA, wordsB := words(a), words(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(wordsA))
+	for _, w := range wordsA {
+		counts[w]++
+	}
+	common := 0
+	for _, w := range wordsB {
+		if counts[w] > 0 {
+			counts[w]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(wordsA)+len(wordsB))
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// scoreCase сопоставляет извлечённые задачи с ожидаемыми: каждой ожидаемой — самую похожую по заголовку
+func scoreCase(c Case, extracted []*models.ParsedEmails, opts Options) CaseResult {
+	result := CaseResult{ID: c.ID, Expected: len(c.Expected)}
+	for _, p := range extracted {
+		item := ExtractedItem{Title: p.Title}
+		if !p.Deadline.IsZero() {
+			item.Deadline = p.Deadline.Format(time.RFC3339)
+		}
+		result.Extracted = append(result.Extracted, item)
+	}
+
+	used := make(map[int]bool)
+	for _, exp := range c.Expected {
+		best, bestSim := -1, 0.0
+		for i, p := range extracted {
+			if used[i] {
+				continue
+			}
+			if sim := TitleSimilarity(exp.Title, p.Title); best < 0 || sim > bestSim {
+				best, bestSim = i, sim
+			}
+		}
+
+		m := ItemMatch{ExpectedTitle: exp.Title, ExpectedDeadline: exp.Deadline, TitleSimilarity: bestSim}
+		if best >= 0 && bestSim >= opts.TitleThreshold {
+			used[best] = true
+			m.Found = true
+			m.DeadlineExact, m.DeadlineWithinTolerance = compareDeadlines(exp, extracted[best].Deadline, opts.Tolerance)
+			result.Matched++
+		}
+		result.Items = append(result.Items, m)
+	}
+	return result
+}
+
+func compareDeadlines(exp ExpectedItem, got time.Time, tolerance time.Duration) (exact, within bool) {
+	want, _ := exp.deadline()
+	if want.IsZero() || got.IsZero() {
+		return want.IsZero() && got.IsZero(), want.IsZero() && got.IsZero()
+	}
+
+	diff := got.Sub(want)
+	if diff < 0 {
+		diff = -diff
+	}
+	return got.Equal(want), diff <= tolerance
+}
@@ -0,0 +1,85 @@
+package event
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"reminder-hub/pkg/models"
+	"reminder-hub/services/analyzer/internal/deadline"
+)
+
+const maxParticipants = 50
+
+// Spec — событие в том виде, в каком его вернула модель. Начало задаётся так же, как дедлайн.
+type Spec struct {
+	Title           string         `json:"title"`
+	Description     string         `json:"description"`
+	Start           *deadline.Spec `json:"start"`
+	EndTime         string         `json:"end_time"`
+	DurationMinutes int            `json:"duration_minutes"`
+	Location        string         `json:"location"`
+	JoinURL         string         `json:"join_url"`
+	Participants    []string       `json:"participants"`
+}
+
+// Build разрешает время событий относительно даты письма. События без понятного начала отбрасываются
+// и возвращаются ошибками, чтобы их можно было залогировать. restore возвращает в текст персональные данные.
+func Build(specs []Spec, reference time.Time, body string, restore func(string) string) ([]models.Event, []error) {
+	var events []models.Event
+	var errs []error
+	for i, spec := range specs {
+		begin, end, allDay, err := deadline.ResolveEvent(spec.Start, spec.EndTime, spec.DurationMinutes, reference)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("event %d %q: %w", i, spec.Title, err))
+			continue
+		}
+
+		e := models.Event{
+			Title:        restore(strings.TrimSpace(spec.Title)),
+			Description:  restore(strings.TrimSpace(spec.Description)),
+			Start:        begin,
+			AllDay:       allDay,
+			Location:     restore(strings.TrimSpace(spec.Location)),
+			JoinURL:      JoinURL(restore(spec.JoinURL), body),
+			Participants: participants(spec.Participants, restore),
+		}
+		if !end.IsZero() {
+			e.End = &end
+		}
+		events = append(events, e)
+	}
+	return events, errs
+}
+
+// JoinURL оставляет ссылку модели, только если она есть в письме: выдуманная или подменённая ссылка
+// на созвон опаснее отсутствующей. Иначе берётся первая ссылка известного сервиса видеовстреч из письма.
+func JoinURL(candidate, body string) string {
+	candidate = strings.TrimSpace(candidate)
+	if candidate != "" && strings.Contains(body, candidate) {
+		return candidate
+	}
+	// Точка или запятая после ссылки в тексте — это пунктуация предложения
+	return strings.TrimRight(meetingLink.FindString(body), ".,;:!?")
+}
+
+func participants(names []string, restore func(string) string) []string {
+	seen := make(map[string]bool, len(names))
+	var result []string
+	for _, name := range names {
+		name = restore(strings.TrimSpace(name))
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+		if len(result) == maxParticipants {
+			break
+		}
+	}
+	return result
+}
+
+var meetingLink = regexp.MustCompile(`https://(?:[\w-]+\.)*(?:zoom\.us|meet\.google\.com|teams\.microsoft\.com|teams\.live\.com|telemost\.yandex\.ru|meet\.jit\.si|webex\.com)/[^\s<>"')\]]*`)
@@ -0,0 +1,48 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"reminder-hub/services/analyzer/internal/deadline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func identity(s string) string { return s }
+
+func TestBuild(t *testing.T) {
+	ref := time.Date(2025, 12, 10, 9, 0, 0, 0, time.UTC)
+	body := "Созвон в пятницу 15:00–16:00, ссылка https://meet.google.com/abc-defg-hij"
+
+	events, errs := Build([]Spec{
+		{
+			Title:        "Созвон по релизу",
+			Start:        &deadline.Spec{Weekday: "friday", Time: "15:00"},
+			EndTime:      "16:00",
+			JoinURL:      "https://meet.google.com/abc-defg-hij",
+			Participants: []string{"Анна", "анна", "Иван", " "},
+		},
+		{Title: "Без даты"},
+	}, ref, body, identity)
+
+	require.Len(t, events, 1)
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], deadline.ErrEmptySpec)
+
+	e := events[0]
+	assert.Equal(t, "2025-12-12T15:00:00Z", e.Start.Format(time.RFC3339))
+	require.NotNil(t, e.End)
+	assert.Equal(t, "2025-12-12T16:00:00Z", e.End.Format(time.RFC3339))
+	assert.Equal(t, "https://meet.google.com/abc-defg-hij", e.JoinURL)
+	assert.Equal(t, []string{"Анна", "Иван"}, e.Participants)
+}
+
+func TestJoinURL_RejectsLinksNotInEmail(t *testing.T) {
+	body := "Подключайтесь: https://zoom.us/j/123456?pwd=abc."
+
+	assert.Equal(t, "https://zoom.us/j/123456?pwd=abc", JoinURL("https://zoom.us/j/123456?pwd=abc", body))
+	assert.Equal(t, "https://zoom.us/j/123456?pwd=abc", JoinURL("https://evil.example/login", body))
+	assert.Equal(t, "", JoinURL("https://evil.example/login", "Встреча в переговорной"))
+}
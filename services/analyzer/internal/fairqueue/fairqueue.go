@@ -0,0 +1,183 @@
+package fairqueue
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"reminder-hub/pkg/models"
+	rmq "reminder-hub/pkg/rabbitmq"
+)
+
+type Config struct {
+	// Сколько неподтверждённых писем каждая очередь (живая и фоновая) держит в памяти analyzer.
+	// Из них и выбирается следующее письмо: пользователи чередуются только внутри этого окна, а дальше
+	// брокер отдаёт письма в порядке очереди.
+	Prefetch int `env:"PREFETCH" env-default:"32"`
+	// Доли классов: из 11 писем подряд 8 живых, 2 повторно обработанных и 1 из первичной синхронизации,
+	// если все классы ждут. Пустой класс свою долю не держит.
+	LiveWeight      int `env:"LIVE_WEIGHT" env-default:"8"`
+	ReprocessWeight int `env:"REPROCESS_WEIGHT" env-default:"2"`
+	BackfillWeight  int `env:"BACKFILL_WEIGHT" env-default:"1"`
+}
+
+// Stats — сколько писем и пользователей ждут в каждом классе
+type Stats struct {
+	Class   string `json:"class"`
+	Pending int    `json:"pending"`
+	Users   int    `json:"users"`
+}
+
+// Queue — взвешенная справедливая очередь перед воркерами модели. Классы (живая почта, повторная обработка,
+// первичная синхронизация) чередуются smooth weighted round-robin, а внутри класса пользователи
+// обслуживаются по кругу. Очередь видит только то, что брокер уже выдал, то есть до Prefetch писем из каждой
+// очереди RabbitMQ. Поэтому живая почта не ждёт первичную синхронизацию, но если один ящик выложил в
+// bulk-очередь десять тысяч писем, письма другого пользователя, вставшие за ними, попадут в круг лишь
+// после того, как от этой пачки останется меньше Prefetch писем.
+type Queue struct {
+	mu      sync.Mutex
+	classes []*class
+	pending int
+	// Закрывается и заменяется при каждом Push, чтобы разбудить ждущие Pop
+	changed chan struct{}
+}
+
+type class struct {
+	name    string
+	weight  int
+	current int
+	pending int
+	users   map[string][]*rmq.Job
+	// Пользователи с письмами в порядке обслуживания
+	ring []string
+}
+
+func New(cfg *Config) *Queue {
+	return &Queue{
+		classes: []*class{
+			newClass(models.SourceLive, cfg.LiveWeight),
+			newClass(models.SourceReprocess, cfg.ReprocessWeight),
+			newClass(models.SourceBackfill, cfg.BackfillWeight),
+		},
+		changed: make(chan struct{}),
+	}
+}
+
+func newClass(name string, weight int) *class {
+	return &class{name: name, weight: max(1, weight), users: make(map[string][]*rmq.Job)}
+}
+
+// Push ставит письмо в очередь его пользователя
+func (q *Queue) Push(job *rmq.Job) {
+	source, userID := Classify(job.Delivery.Body, job.Delivery.Headers)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c := q.class(source)
+	if len(c.users[userID]) == 0 {
+		c.ring = append(c.ring, userID)
+	}
+	c.users[userID] = append(c.users[userID], job)
+	c.pending++
+	q.pending++
+
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Pop ждёт и возвращает следующее письмо. Ошибка — только отмена контекста.
+func (q *Queue) Pop(ctx context.Context) (*rmq.Job, error) {
+	for {
+		q.mu.Lock()
+		if q.pending > 0 {
+			job := q.next()
+			q.mu.Unlock()
+			return job, nil
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (q *Queue) Stats() []Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := make([]Stats, 0, len(q.classes))
+	for _, c := range q.classes {
+		stats = append(stats, Stats{Class: c.name, Pending: c.pending, Users: len(c.ring)})
+	}
+	return stats
+}
+
+// next выбирает класс по smooth weighted round-robin и берёт письмо следующего по кругу пользователя.
+// Вызывается под мьютексом, когда в очереди есть хотя бы одно письмо.
+func (q *Queue) next() *rmq.Job {
+	var chosen *class
+	total := 0
+	for _, c := range q.classes {
+		if c.pending == 0 {
+			continue
+		}
+		c.current += c.weight
+		total += c.weight
+		if chosen == nil || c.current > chosen.current {
+			chosen = c
+		}
+	}
+	chosen.current -= total
+
+	userID := chosen.ring[0]
+	jobs := chosen.users[userID]
+	job := jobs[0]
+	chosen.ring = chosen.ring[1:]
+	if len(jobs) > 1 {
+		chosen.users[userID] = jobs[1:]
+		chosen.ring = append(chosen.ring, userID)
+	} else {
+		delete(chosen.users, userID)
+	}
+	chosen.pending--
+	q.pending--
+
+	// Опустевший класс начинает заново, иначе накопленный долг исказит доли после паузы
+	if chosen.pending == 0 {
+		chosen.current = 0
+	}
+	return job
+}
+
+func (q *Queue) class(source string) *class {
+	for _, c := range q.classes {
+		if c.name == source {
+			return c
+		}
+	}
+	return q.classes[0]
+}
+
+// Classify определяет класс и пользователя письма. Письмо, возвращённое из мёртвых, считается повторной
+// обработкой, даже если пришло из живой синхронизации. Непонятное тело обрабатывается как живое:
+// обработчик всё равно отклонит его сразу, без вызова модели.
+func Classify(body []byte, headers map[string]interface{}) (source, userID string) {
+	var item models.EmailWorkItem
+	_ = json.Unmarshal(body, &item)
+
+	source = item.Source
+	if requeued, _ := headers[rmq.HeaderRequeued].(bool); requeued {
+		source = models.SourceReprocess
+	}
+	switch source {
+	case models.SourceBackfill, models.SourceReprocess:
+	default:
+		source = models.SourceLive
+	}
+	return source, item.Email.UserID
+}
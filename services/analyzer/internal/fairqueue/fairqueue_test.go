@@ -0,0 +1,147 @@
+package fairqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/models"
+	rmq "reminder-hub/pkg/rabbitmq"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func job(t *testing.T, userID, source, emailID string) *rmq.Job {
+	body, err := json.Marshal(models.EmailWorkItem{Email: models.RawEmail{EmailID: emailID, UserID: userID}, Source: source})
+	require.NoError(t, err)
+	return &rmq.Job{Delivery: amqp.Delivery{Body: body}}
+}
+
+func drain(t *testing.T, q *Queue, n int) []string {
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		j, err := q.Pop(context.Background())
+		require.NoError(t, err)
+		var item models.EmailWorkItem
+		require.NoError(t, json.Unmarshal(j.Delivery.Body, &item))
+		ids = append(ids, item.Email.EmailID)
+	}
+	return ids
+}
+
+func TestQueue_RoundRobinAcrossUsers(t *testing.T) {
+	q := New(&Config{LiveWeight: 1, ReprocessWeight: 1, BackfillWeight: 1})
+
+	// Пользователь с большой пачкой успел поставить письма первым
+	for _, id := range []string{"a1", "a2", "a3", "a4"} {
+		q.Push(job(t, "alice", models.SourceLive, id))
+	}
+	q.Push(job(t, "bob", models.SourceLive, "b1"))
+	q.Push(job(t, "carol", models.SourceLive, "c1"))
+	q.Push(job(t, "bob", models.SourceLive, "b2"))
+
+	assert.Equal(t, []string{"a1", "b1", "c1", "a2", "b2", "a3", "a4"}, drain(t, q, 7))
+	assert.Equal(t, []Stats{
+		{Class: models.SourceLive}, {Class: models.SourceReprocess}, {Class: models.SourceBackfill},
+	}, q.Stats())
+}
+
+func TestQueue_LivePrecedesBulk(t *testing.T) {
+	q := New(&Config{LiveWeight: 3, ReprocessWeight: 2, BackfillWeight: 1})
+
+	for _, id := range []string{"b1", "b2", "b3", "b4"} {
+		q.Push(job(t, "alice", models.SourceBackfill, id))
+	}
+	for _, id := range []string{"r1", "r2", "r3", "r4"} {
+		q.Push(job(t, "alice", models.SourceReprocess, id))
+	}
+	for _, id := range []string{"l1", "l2", "l3", "l4"} {
+		q.Push(job(t, "bob", models.SourceLive, id))
+	}
+
+	// Из первых шести писем три живых, два повторных и одно фоновое
+	first := drain(t, q, 6)
+	counts := map[byte]int{}
+	for _, id := range first {
+		counts[id[0]]++
+	}
+	assert.Equal(t, map[byte]int{'l': 3, 'r': 2, 'b': 1}, counts)
+	assert.Equal(t, "l1", first[0])
+
+	// Когда живые и повторные кончились, фоновые идут подряд
+	rest := drain(t, q, 6)
+	assert.Equal(t, []string{"b2", "b3", "b4"}, rest[len(rest)-3:])
+}
+
+func TestQueue_FairnessLimitedToPrefetch(t *testing.T) {
+	const prefetch = 4
+	q := New(&Config{Prefetch: prefetch})
+
+	// Брокер выдаёт письма по порядку и не больше prefetch неподтверждённых
+	var broker []*rmq.Job
+	for i := 1; i <= 20; i++ {
+		broker = append(broker, job(t, "alice", models.SourceBackfill, fmt.Sprintf("a%d", i)))
+	}
+	broker = append(broker, job(t, "bob", models.SourceBackfill, "b1"))
+
+	var order []string
+	unacked := 0
+	for len(order) < 21 {
+		for ; unacked < prefetch && len(broker) > 0; unacked++ {
+			q.Push(broker[0])
+			broker = broker[1:]
+		}
+		order = append(order, drain(t, q, 1)...)
+		unacked--
+	}
+
+	// Письмо bob попадает в окно, только когда у alice остаётся prefetch-1 писем, и выходит сразу после следующего из них
+	assert.Equal(t, "b1", order[20-prefetch+2])
+	assert.Equal(t, "a20", order[20])
+}
+
+func TestQueue_PopWaitsForPush(t *testing.T) {
+	q := New(&Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := q.Pop(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Push(job(t, "alice", "", "a1"))
+	}()
+	assert.Equal(t, []string{"a1"}, drain(t, q, 1))
+}
+
+func TestClassify(t *testing.T) {
+	body := func(source string) []byte {
+		b, _ := json.Marshal(models.EmailWorkItem{Email: models.RawEmail{UserID: "u1"}, Source: source})
+		return b
+	}
+
+	cases := []struct {
+		body    []byte
+		headers map[string]interface{}
+		source  string
+		userID  string
+	}{
+		{body(""), nil, models.SourceLive, "u1"},
+		{body(models.SourceLive), nil, models.SourceLive, "u1"},
+		{body(models.SourceBackfill), nil, models.SourceBackfill, "u1"},
+		{body(models.SourceReprocess), nil, models.SourceReprocess, "u1"},
+		{body("unknown"), nil, models.SourceLive, "u1"},
+		{body(models.SourceLive), amqp.Table{rmq.HeaderRequeued: true}, models.SourceReprocess, "u1"},
+		{[]byte("not json"), nil, models.SourceLive, ""},
+	}
+	for _, tc := range cases {
+		source, userID := Classify(tc.body, tc.headers)
+		assert.Equal(t, tc.source, source, "%s", tc.body)
+		assert.Equal(t, tc.userID, userID, "%s", tc.body)
+	}
+}
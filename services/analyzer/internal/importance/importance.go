@@ -0,0 +1,46 @@
+package importance
+
+import (
+	"strings"
+
+	"reminder-hub/pkg/models"
+)
+
+// Reported — поле "importance" ответа модели; nil — модель его не прислала
+type Reported struct {
+	Urgent     bool   `json:"urgent"`
+	SenderRole string `json:"sender_role"`
+	Priority   string `json:"priority"`
+}
+
+var (
+	senderRoles = map[string]bool{
+		models.SenderManager:   true,
+		models.SenderClient:    true,
+		models.SenderColleague: true,
+		models.SenderAutomated: true,
+	}
+	priorities = map[string]bool{
+		models.PriorityHigh: true,
+		models.PriorityLow:  true,
+	}
+)
+
+// Signals оставляет из ответа модели только известные значения: придуманная роль или приоритет
+// не должны сдвигать приоритет задачи. Если не осталось ничего, возвращает nil.
+func Signals(reported *Reported) *models.Importance {
+	if reported == nil {
+		return nil
+	}
+	signals := models.Importance{Urgent: reported.Urgent}
+	if role := strings.ToLower(strings.TrimSpace(reported.SenderRole)); senderRoles[role] {
+		signals.SenderRole = role
+	}
+	if priority := strings.ToLower(strings.TrimSpace(reported.Priority)); priorities[priority] {
+		signals.Priority = priority
+	}
+	if signals == (models.Importance{}) {
+		return nil
+	}
+	return &signals
+}
@@ -0,0 +1,19 @@
+package importance
+
+import (
+	"testing"
+
+	"reminder-hub/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignals(t *testing.T) {
+	assert.Nil(t, Signals(nil))
+	assert.Nil(t, Signals(&Reported{SenderRole: "unknown", Priority: "normal"}))
+
+	assert.Equal(t, &models.Importance{Urgent: true, SenderRole: models.SenderManager, Priority: models.PriorityHigh},
+		Signals(&Reported{Urgent: true, SenderRole: " Manager", Priority: "HIGH"}))
+	assert.Equal(t, &models.Importance{SenderRole: models.SenderAutomated},
+		Signals(&Reported{SenderRole: "automated", Priority: "urgent"}))
+}
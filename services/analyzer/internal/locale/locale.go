@@ -0,0 +1,153 @@
+package locale
+
+import (
+	"strings"
+	"unicode"
+
+	"reminder-hub/pkg/models"
+)
+
+// Key — текст, который analyzer пишет в задачу сам, без модели
+type Key int
+
+const (
+	FallbackDescription Key = iota
+	Flight
+	Terminal
+	Train
+	Bus
+	Ferry
+	Taxi
+	CarRental
+	CheckIn
+	TableReservation
+	ReservationNumber
+	PickUpParcel
+	Carrier
+	TrackingNumber
+	Tracking
+	PayInvoice
+	Amount
+	Account
+	PaymentLink
+)
+
+const (
+	// Язык текстов, если он не задан ни пользователем, ни в конфигурации
+	defaultLanguage = "ru"
+	// Язык текстов для языков без своего каталога
+	fallbackLanguage = "en"
+)
+
+var catalog = map[string]map[Key]string{
+	"ru": {
+		FallbackDescription: "Не удалось обработать письмо автоматически",
+		Flight:              "Рейс",
+		Terminal:            "терминал ",
+		Train:               "Поезд",
+		Bus:                 "Автобус",
+		Ferry:               "Паром",
+		Taxi:                "Такси",
+		CarRental:           "Аренда автомобиля",
+		CheckIn:             "Заселение",
+		TableReservation:    "Бронь столика",
+		ReservationNumber:   "Номер брони: ",
+		PickUpParcel:        "Получить посылку",
+		Carrier:             "Перевозчик: ",
+		TrackingNumber:      "Трек-номер: ",
+		Tracking:            "Отслеживание: ",
+		PayInvoice:          "Оплатить счёт",
+		Amount:              "Сумма: ",
+		Account:             "Лицевой счёт: ",
+		PaymentLink:         "Ссылка на оплату: ",
+	},
+	"en": {
+		FallbackDescription: "The email could not be processed automatically",
+		Flight:              "Flight",
+		Terminal:            "terminal ",
+		Train:               "Train",
+		Bus:                 "Bus",
+		Ferry:               "Ferry",
+		Taxi:                "Taxi",
+		CarRental:           "Car rental",
+		CheckIn:             "Check-in",
+		TableReservation:    "Table reservation",
+		ReservationNumber:   "Reservation number: ",
+		PickUpParcel:        "Pick up parcel",
+		Carrier:             "Carrier: ",
+		TrackingNumber:      "Tracking number: ",
+		Tracking:            "Tracking: ",
+		PayInvoice:          "Pay invoice",
+		Amount:              "Amount: ",
+		Account:             "Account: ",
+		PaymentLink:         "Payment link: ",
+	},
+}
+
+// Text возвращает текст на языке language. Для языков без каталога — по-английски:
+// модель пишет на любом языке, а шаблонный текст переведён только на несколько.
+func Text(language string, key Key) string {
+	if language == "" {
+		language = defaultLanguage
+	}
+	if texts, ok := catalog[base(language)]; ok {
+		return texts[key]
+	}
+	return catalog[fallbackLanguage][key]
+}
+
+// Resolve превращает LanguageAuto в язык письма; остальные значения возвращаются как есть
+func Resolve(language string, texts ...string) string {
+	if language != models.LanguageAuto {
+		return language
+	}
+	return Detect(strings.Join(texts, "\n"))
+}
+
+// Detect угадывает язык письма по алфавиту: кириллица — русский, иначе английский.
+// Этого хватает для шаблонных текстов; модели в режиме LanguageAuto язык не подсказывается.
+func Detect(text string) string {
+	var cyrillic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	if cyrillic > 0 && cyrillic >= latin/2 {
+		return "ru"
+	}
+	return "en"
+}
+
+// Valid — значение, которое можно сохранить как язык пользователя: LanguageAuto или код ISO 639-1,
+// возможно с регионом ("en", "pt-BR")
+func Valid(language string) bool {
+	if language == models.LanguageAuto {
+		return true
+	}
+	code, region, hasRegion := strings.Cut(language, "-")
+	if !letters(code, 2) {
+		return false
+	}
+	return !hasRegion || letters(region, 2)
+}
+
+func base(language string) string {
+	code, _, _ := strings.Cut(strings.ToLower(language), "-")
+	return code
+}
+
+func letters(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package locale
+
+import (
+	"testing"
+
+	"reminder-hub/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestText(t *testing.T) {
+	assert.Equal(t, "Оплатить счёт", Text("ru", PayInvoice))
+	assert.Equal(t, "Pay invoice", Text("en", PayInvoice))
+	assert.Equal(t, "Pay invoice", Text("en-GB", PayInvoice))
+	// Без языка — как было до настройки, по-русски; без каталога — по-английски
+	assert.Equal(t, "Оплатить счёт", Text("", PayInvoice))
+	assert.Equal(t, "Pay invoice", Text("de", PayInvoice))
+}
+
+func TestResolve(t *testing.T) {
+	assert.Equal(t, "de", Resolve("de", "Пришлите отчёт"))
+	assert.Equal(t, "", Resolve("", "Please send the report"))
+	assert.Equal(t, "ru", Resolve(models.LanguageAuto, "Отчёт", "Пришлите отчёт до пятницы, ссылка https://example.com/report"))
+	assert.Equal(t, "en", Resolve(models.LanguageAuto, "Report", "Please send the report by Friday"))
+	assert.Equal(t, "en", Resolve(models.LanguageAuto, "", "12:00"))
+}
+
+func TestValid(t *testing.T) {
+	for _, language := range []string{models.LanguageAuto, "en", "ru", "pt-BR"} {
+		assert.True(t, Valid(language), language)
+	}
+	for _, language := range []string{"", "english", "e", "en-", "en_US", "12", "ру"} {
+		assert.False(t, Valid(language), language)
+	}
+}
@@ -0,0 +1,25 @@
+package echomiddleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// InternalAuth пропускает только запросы с внутренним токеном сервисов (Authorization: Bearer <token>)
+func InternalAuth(internalToken string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get(echo.HeaderAuthorization)
+			token, ok := strings.CutPrefix(authHeader, "Bearer ")
+			if !ok || token == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Authorization header required")
+			}
+			if token != internalToken {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+			}
+			return next(c)
+		}
+	}
+}
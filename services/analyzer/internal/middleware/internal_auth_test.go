@@ -0,0 +1,34 @@
+package echomiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestInternalAuth(t *testing.T) {
+	e := echo.New()
+	h := InternalAuth("secret")(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for header, wantErr := range map[string]bool{
+		"":              true,
+		"Basic secret":  true,
+		"Bearer wrong":  true,
+		"Bearer secret": false,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(echo.HeaderAuthorization, header)
+		}
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		err := h(c)
+		if (err != nil) != wantErr {
+			t.Fatalf("header %q: err = %v, wantErr %v", header, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package prompt
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/redis"
+
+	"github.com/tmc/langchaingo/prompts"
+)
+
+const templateExt = ".tmpl"
+
+// Распределение трафика общее для всех реплик analyzer и переживает перезапуск
+const splitKey = "analyzer:prompts:split"
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+var (
+	ErrUnknownVersion = errors.New("unknown prompt version")
+	ErrEmptySplit     = errors.New("prompt traffic split is empty")
+)
+
+// Переменные, доступные в шаблонах
+var inputVariables = []string{"subject", "body", "reference", "timezone", "language", "today", "categories", "examples"}
+
+type Config struct {
+	// Каталог с файлами <version>.tmpl; файлы из него дополняют и перекрывают встроенные шаблоны
+	Dir             string `env:"DIR"`
+	ActiveVersion   string `env:"ACTIVE_VERSION" env-default:"v1"`
+	DefaultLanguage string `env:"DEFAULT_LANGUAGE" env-default:"ru"`
+	// Как часто перечитывать распределение трафика, которое могла поменять другая реплика
+	SplitRefresh time.Duration `env:"SPLIT_REFRESH" env-default:"30s"`
+}
+
+// Backend — подмножество pkg/redis.Client, в котором хранится распределение трафика
+type Backend interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+type Vars struct {
+	Subject   string
+	Body      string
+	Reference string
+	Timezone  string
+	Language  string
+	Today     string
+	// Категории таксономии через запятую
+	Categories string
+	// Исправления пользователя в задачах из похожих писем; пусто — примеров нет
+	Examples string
+}
+
+// Store хранит разобранные шаблоны промптов и распределение трафика между версиями
+type Store struct {
+	templates map[string]prompts.PromptTemplate
+	language  string
+	// nil — распределение живёт только в памяти процесса
+	backend Backend
+	refresh time.Duration
+	log     *logger.CurrentLogger
+
+	mu       sync.RWMutex
+	weights  map[string]int
+	loadedAt time.Time
+}
+
+// NewStore хранит распределение трафика в Redis, а без него — в памяти процесса
+func NewStore(ctx context.Context, cfg *Config, client *redis.Client, log *logger.CurrentLogger) (*Store, error) {
+	var backend Backend
+	if client != nil {
+		backend = client
+	} else {
+		log.Warn(ctx, "Prompt traffic split is kept in memory without Redis")
+	}
+	s, err := New(ctx, cfg, backend)
+	if err != nil {
+		return nil, err
+	}
+	s.log = log
+	return s, nil
+}
+
+// New загружает шаблоны и сохранённое распределение трафика; без сохранённого активна ActiveVersion
+func New(ctx context.Context, cfg *Config, backend Backend) (*Store, error) {
+	templates, err := load(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Dir != "" {
+		overrides, err := load(os.DirFS(cfg.Dir), ".")
+		if err != nil {
+			return nil, err
+		}
+		for version, tmpl := range overrides {
+			templates[version] = tmpl
+		}
+	}
+
+	s := &Store{templates: templates, language: cfg.DefaultLanguage, refresh: cfg.SplitRefresh}
+	split, err := s.validSplit(map[string]int{cfg.ActiveVersion: 1})
+	if err != nil {
+		return nil, err
+	}
+	s.weights = split
+	s.backend = backend
+	if err := s.reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func load(fsys fs.FS, dir string) (map[string]prompts.PromptTemplate, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read prompt templates: %w", err)
+	}
+
+	templates := make(map[string]prompts.PromptTemplate)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), templateExt) {
+			continue
+		}
+		raw, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read prompt template %s: %w", entry.Name(), err)
+		}
+		if err := prompts.CheckValidTemplate(string(raw), prompts.TemplateFormatGoTemplate, inputVariables); err != nil {
+			return nil, fmt.Errorf("invalid prompt template %s: %w", entry.Name(), err)
+		}
+		version := strings.TrimSuffix(entry.Name(), templateExt)
+		templates[version] = prompts.NewPromptTemplate(string(raw), inputVariables)
+	}
+	return templates, nil
+}
+
+// Versions возвращает отсортированный список загруженных версий
+func (s *Store) Versions() []string {
+	versions := make([]string, 0, len(s.templates))
+	for version := range s.templates {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Split возвращает копию текущего распределения трафика
+func (s *Store) Split() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	split := make(map[string]int, len(s.weights))
+	for version, weight := range s.weights {
+		split[version] = weight
+	}
+	return split
+}
+
+// SetSplit задаёт веса версий и сохраняет их для всех реплик. Одна версия с любым положительным весом —
+// это просто смена активной версии.
+func (s *Store) SetSplit(ctx context.Context, weights map[string]int) error {
+	split, err := s.validSplit(weights)
+	if err != nil {
+		return err
+	}
+
+	if s.backend != nil {
+		data, err := json.Marshal(split)
+		if err != nil {
+			return err
+		}
+		if err := s.backend.Set(ctx, splitKey, string(data), 0); err != nil {
+			return fmt.Errorf("save prompt traffic split: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.weights = split
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Store) validSplit(weights map[string]int) (map[string]int, error) {
+	split := make(map[string]int, len(weights))
+	for version, weight := range weights {
+		if _, ok := s.templates[version]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+		}
+		if weight > 0 {
+			split[version] = weight
+		}
+	}
+	if len(split) == 0 {
+		return nil, ErrEmptySplit
+	}
+	return split, nil
+}
+
+// reload читает сохранённое распределение. Ничего не сохранено — остаётся текущее; сохранённое
+// со снятой версией шаблона — ошибка конфигурации, о которой нужно узнать при старте.
+func (s *Store) reload(ctx context.Context) error {
+	if s.backend == nil {
+		return nil
+	}
+
+	raw, err := s.backend.Get(ctx, splitKey)
+	if errors.Is(err, redis.Nil) {
+		s.mu.Lock()
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load prompt traffic split: %w", err)
+	}
+	var weights map[string]int
+	if err := json.Unmarshal([]byte(raw), &weights); err != nil {
+		return fmt.Errorf("load prompt traffic split: %w", err)
+	}
+	split, err := s.validSplit(weights)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.weights = split
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Select выбирает версию для письма. Выбор детерминирован по ключу,
+// поэтому повторная доставка того же письма получает ту же версию.
+// Раз в SplitRefresh распределение перечитывается; при ошибке остаётся прежнее.
+func (s *Store) Select(ctx context.Context, key string) string {
+	s.mu.RLock()
+	stale := s.backend != nil && time.Since(s.loadedAt) >= s.refresh
+	s.mu.RUnlock()
+	if stale {
+		if err := s.reload(ctx); err != nil {
+			if s.log != nil {
+				s.log.Warn(ctx, "Failed to refresh prompt traffic split, keeping the current one", "error", err)
+			}
+			s.mu.Lock()
+			s.loadedAt = time.Now()
+			s.mu.Unlock()
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := make([]string, 0, len(s.weights))
+	total := 0
+	for version, weight := range s.weights {
+		versions = append(versions, version)
+		total += weight
+	}
+	sort.Strings(versions)
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	point := int(h.Sum32() % uint32(total))
+
+	for _, version := range versions {
+		point -= s.weights[version]
+		if point < 0 {
+			return version
+		}
+	}
+	return versions[len(versions)-1]
+}
+
+// Language возвращает язык ответа: переданный или язык по умолчанию
+func (s *Store) Language(language string) string {
+	if language == "" {
+		return s.language
+	}
+	return language
+}
+
+// Render подставляет переменные в шаблон указанной версии
+func (s *Store) Render(version string, vars Vars) (string, error) {
+	tmpl, ok := s.templates[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+	}
+
+	return tmpl.Format(map[string]any{
+		"subject":    vars.Subject,
+		"body":       vars.Body,
+		"reference":  vars.Reference,
+		"timezone":   vars.Timezone,
+		"language":   s.Language(vars.Language),
+		"today":      vars.Today,
+		"categories": vars.Categories,
+		"examples":   vars.Examples,
+	})
+}
@@ -0,0 +1,147 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/redis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *Store {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "v2.tmpl"), []byte("v2 {{.language}}: {{.subject}}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o600))
+
+	store, err := New(context.Background(), &Config{Dir: dir, ActiveVersion: "v1", DefaultLanguage: "ru"}, nil)
+	require.NoError(t, err)
+	return store
+}
+
+func TestNewStore_LoadsEmbeddedAndDirTemplates(t *testing.T) {
+	store := newTestStore(t)
+
+	assert.Equal(t, []string{"v1", "v2"}, store.Versions())
+	assert.Equal(t, map[string]int{"v1": 1}, store.Split())
+}
+
+func TestNewStore_UnknownActiveVersion(t *testing.T) {
+	_, err := New(context.Background(), &Config{ActiveVersion: "v42"}, nil)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestNewStore_InvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte("{{.subject"), 0o600))
+
+	_, err := New(context.Background(), &Config{Dir: dir, ActiveVersion: "v1"}, nil)
+	assert.Error(t, err)
+}
+
+func TestRender_DefaultLanguage(t *testing.T) {
+	store := newTestStore(t)
+
+	out, err := store.Render("v2", Vars{Subject: "Отчёт"})
+	require.NoError(t, err)
+	assert.Equal(t, "v2 ru: Отчёт", out)
+
+	out, err = store.Render("v2", Vars{Subject: "Report", Language: "en"})
+	require.NoError(t, err)
+	assert.Equal(t, "v2 en: Report", out)
+
+	_, err = store.Render("v9", Vars{})
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestRender_EmbeddedTemplateHasAllVariables(t *testing.T) {
+	store := newTestStore(t)
+
+	out, err := store.Render("v1", Vars{
+		Subject:   "subject-marker",
+		Body:      "body-marker",
+		Reference: "reference-marker",
+		Timezone:  "Europe/Moscow",
+		Today:     "today-marker",
+		Examples:  "examples-marker",
+	})
+	require.NoError(t, err)
+	for _, marker := range []string{"subject-marker", "body-marker", "reference-marker", "Europe/Moscow", "today-marker", "examples-marker"} {
+		assert.Contains(t, out, marker)
+	}
+
+	// Без примеров блок об исправлениях не попадает в промпт
+	out, err = store.Render("v1", Vars{Subject: "subject-marker"})
+	require.NoError(t, err)
+	assert.NotContains(t, out, "исправлял")
+}
+
+func TestSelect_SplitIsDeterministicAndWeighted(t *testing.T) {
+	store := newTestStore(t)
+	require.NoError(t, store.SetSplit(context.Background(), map[string]int{"v1": 50, "v2": 50}))
+
+	counts := map[string]int{}
+	for i := range 1000 {
+		key := fmt.Sprintf("email-%d", i)
+		version := store.Select(context.Background(), key)
+		assert.Equal(t, version, store.Select(context.Background(), key))
+		counts[version]++
+	}
+	assert.Greater(t, counts["v1"], 300)
+	assert.Greater(t, counts["v2"], 300)
+
+	require.NoError(t, store.SetSplit(context.Background(), map[string]int{"v2": 1, "v1": 0}))
+	assert.Equal(t, "v2", store.Select(context.Background(), "any"))
+}
+
+func TestSetSplit_Errors(t *testing.T) {
+	store := newTestStore(t)
+
+	assert.ErrorIs(t, store.SetSplit(context.Background(), map[string]int{"v3": 1}), ErrUnknownVersion)
+	assert.ErrorIs(t, store.SetSplit(context.Background(), map[string]int{"v1": 0}), ErrEmptySplit)
+	assert.Equal(t, map[string]int{"v1": 1}, store.Split())
+}
+
+// memoryBackend — общий Redis двух реплик
+type memoryBackend map[string]string
+
+func (m memoryBackend) Get(_ context.Context, key string) (string, error) {
+	if value, ok := m[key]; ok {
+		return value, nil
+	}
+	return "", redis.Nil
+}
+
+func (m memoryBackend) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	m[key] = value.(string)
+	return nil
+}
+
+func TestSetSplit_SharedBetweenReplicas(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "v2.tmpl"), []byte("v2 {{.subject}}"), 0o600))
+	cfg := &Config{Dir: dir, ActiveVersion: "v1", SplitRefresh: time.Hour}
+	backend := memoryBackend{}
+
+	first, err := New(ctx, cfg, backend)
+	require.NoError(t, err)
+	second, err := New(ctx, cfg, backend)
+	require.NoError(t, err)
+
+	require.NoError(t, first.SetSplit(ctx, map[string]int{"v2": 1}))
+	// Вторая реплика перечитывает распределение раз в SplitRefresh
+	assert.Equal(t, "v1", second.Select(ctx, "email-1"))
+	second.loadedAt = time.Time{}
+	assert.Equal(t, "v2", second.Select(ctx, "email-1"))
+
+	// После перезапуска распределение берётся из хранилища, а не из ActiveVersion
+	restarted, err := New(ctx, cfg, backend)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"v2": 1}, restarted.Split())
+}
@@ -6,14 +6,14 @@ import (
 	"reminder-hub/pkg/models"
 	rmq "reminder-hub/pkg/rabbitmq"
 	aiagent "reminder-hub/services/analyzer/internal/ai_agent"
+	"reminder-hub/services/analyzer/internal/fairqueue"
+	"reminder-hub/services/analyzer/internal/ratelimit"
 	"reminder-hub/services/analyzer/internal/shared/delivery"
 
 	"github.com/streadway/amqp"
 	"go.uber.org/fx"
 )
 
-const numberOfConsumers = 4
-
 func ConfigConsumers(
 	lc fx.Lifecycle,
 	ctx context.Context,
@@ -22,6 +22,9 @@ func ConfigConsumers(
 	rabbitmqPublisher rmq.IPublisher,
 	aiagent *aiagent.Agent,
 	rabbitmq *rmq.RabbitMQConfig,
+	limiterCfg *ratelimit.Config,
+	queueCfg *fairqueue.Config,
+	queue *fairqueue.Queue,
 ) error {
 
 	inventoryDeliveryBase := delivery.AnalyzerDeliveryBase{
@@ -31,14 +34,53 @@ func ConfigConsumers(
 		Ctx:               ctx,
 	}
 
+	// Пачки писем только раскладываются на отдельные письма, поэтому им хватает одного консьюмера
 	createProductConsumer := rmq.NewConsumer[*delivery.AnalyzerDeliveryBase](ctx, rabbitmq, connRabbitmq, log, aiagent.ConvertEmail)
+	// Живые и фоновые письма приходят из разных очередей, чтобы первичная синхронизация не стояла
+	// перед живой почтой у брокера. Порядок обработки выбирает общая справедливая очередь.
+	workItemConsumer := rmq.NewConsumer[*delivery.AnalyzerDeliveryBase](ctx, rabbitmq, connRabbitmq, log, aiagent.ProcessWorkItem, rmq.WithScheduler(queue, queueCfg.Prefetch))
+	bulkWorkItemConsumer := rmq.NewConsumer[*delivery.AnalyzerDeliveryBase](ctx, rabbitmq, connRabbitmq, log, aiagent.ProcessWorkItem, rmq.WithScheduler(queue, queueCfg.Prefetch))
+	// Исправления пользователей из collector
+	correctionConsumer := rmq.NewConsumer[*delivery.AnalyzerDeliveryBase](ctx, rabbitmq, connRabbitmq, log, aiagent.LearnCorrection)
 	lc.Append(fx.Hook{
 		OnStart: func(startCtx context.Context) error {
-			for i := 0; i < numberOfConsumers; i++ {
+			go func() {
+				err := createProductConsumer.ConsumeMessage(models.RawEmails{}, &inventoryDeliveryBase)
+				if err != nil {
+					log.Error(startCtx, "ConfigConsumers error in func ConsumeMessage: ", err)
+				}
+			}()
+			go func() {
+				err := workItemConsumer.ConsumeMessage(models.EmailWorkItem{}, &inventoryDeliveryBase)
+				if err != nil {
+					log.Error(startCtx, "ConfigConsumers error in func ConsumeMessage: ", err)
+				}
+			}()
+			go func() {
+				err := bulkWorkItemConsumer.ConsumeMessage(models.BulkEmailWorkItem{}, &inventoryDeliveryBase)
+				if err != nil {
+					log.Error(startCtx, "ConfigConsumers error in func ConsumeMessage: ", err)
+				}
+			}()
+			go func() {
+				err := correctionConsumer.ConsumeMessage(models.TaskCorrection{}, &inventoryDeliveryBase)
+				if err != nil {
+					log.Error(startCtx, "ConfigConsumers error in func ConsumeMessage: ", err)
+				}
+			}()
+			// Воркеры берут следующее письмо, только когда лимитер основной модели не стоит на паузе после 429
+			for i := 0; i < max(1, limiterCfg.Consumers); i++ {
 				go func() {
-					err := createProductConsumer.ConsumeMessage(models.RawEmails{}, &inventoryDeliveryBase)
-					if err != nil {
-						log.Error(startCtx, "ConfigConsumers error in func ConsumeMessage: ", err)
+					for {
+						job, err := queue.Pop(ctx)
+						if err != nil {
+							return
+						}
+						if err := aiagent.WaitReady(ctx); err != nil {
+							// Неподтверждённое письмо брокер вернёт в очередь при закрытии канала
+							return
+						}
+						job.Run()
 					}
 				}()
 			}
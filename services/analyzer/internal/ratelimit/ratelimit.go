@@ -0,0 +1,347 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+type Config struct {
+	Enabled bool `env:"ENABLED" env-default:"true"`
+	// Ограничения по умолчанию для любой модели: запросов в секунду, запас и параллельных вызовов
+	RPS         float64 `env:"RPS" env-default:"1"`
+	Burst       int     `env:"BURST" env-default:"2"`
+	Concurrency int     `env:"CONCURRENCY" env-default:"4"`
+	// Ограничения отдельных моделей: "mistral/mistral-large-latest=0.5:1:2" — rps:burst:concurrency.
+	// Провайдер можно опустить, тогда правило действует для модели у любого провайдера.
+	Models []string `env:"MODELS"`
+	// Пауза после 429 без Retry-After; удваивается с каждым следующим 429 подряд
+	Backoff    time.Duration `env:"BACKOFF" env-default:"2s"`
+	MaxBackoff time.Duration `env:"MAX_BACKOFF" env-default:"1m"`
+	// Сколько писем обрабатывается одновременно; больше Concurrency ставить бессмысленно
+	Consumers int `env:"CONSUMERS" env-default:"4"`
+}
+
+// Limits — ограничения одной модели. RPS <= 0 — без ограничения частоты, только параллельности.
+type Limits struct {
+	RPS         float64
+	Burst       int
+	Concurrency int
+}
+
+// Registry хранит по лимитеру на пару провайдер/модель: у провайдера лимиты считаются по модели и ключу,
+// поэтому основная модель и модель классификатора не должны делить один бюджет
+type Registry struct {
+	cfg     *Config
+	models  map[string]Limits
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+func New(cfg *Config) (*Registry, error) {
+	models := make(map[string]Limits, len(cfg.Models))
+	for _, rule := range cfg.Models {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, limits, err := parseRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		models[name] = limits
+	}
+	return &Registry{cfg: cfg, models: models, buckets: make(map[string]*Bucket)}, nil
+}
+
+// For возвращает лимитер модели. nil — ограничения выключены, Acquire на нём ничего не ждёт.
+func (r *Registry) For(provider, model string) *Bucket {
+	if r == nil || !r.cfg.Enabled {
+		return nil
+	}
+
+	key := provider + "/" + model
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.buckets[key]; ok {
+		return b
+	}
+
+	limits, ok := r.models[key]
+	if !ok {
+		limits, ok = r.models[model]
+	}
+	if !ok {
+		limits = Limits{RPS: r.cfg.RPS, Burst: r.cfg.Burst, Concurrency: r.cfg.Concurrency}
+	}
+	b := newBucket(key, limits, r.cfg.Backoff, r.cfg.MaxBackoff)
+	r.buckets[key] = b
+	return b
+}
+
+// Wait держит воркер, пока модель стоит на паузе после 429 или занята полностью.
+// Письма остаются неподтверждёнными, и сверх prefetch брокер новых не присылает — это и есть backpressure.
+// Остальные модели письма (классификатор, проверка инъекций, сильная модель) ограничиваются уже в Acquire,
+// иначе 429 у одной из них останавливал бы всю обработку.
+func (r *Registry) Wait(ctx context.Context, provider, model string) error {
+	return r.For(provider, model).waitReady(ctx)
+}
+
+// Stats — состояние лимитеров для логов и отладки
+func (r *Registry) Stats() []Stats {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]Stats, 0, len(r.buckets))
+	for _, b := range r.buckets {
+		stats = append(stats, b.Stats())
+	}
+	return stats
+}
+
+type Stats struct {
+	Name        string    `json:"name"`
+	Limit       int       `json:"limit"`
+	MaxLimit    int       `json:"max_limit"`
+	InFlight    int       `json:"in_flight"`
+	PausedUntil time.Time `json:"paused_until,omitempty"`
+}
+
+// Bucket — token bucket с адаптивным числом параллельных вызовов: 429 вдвое уменьшает его и ставит паузу
+// до Retry-After, а серия успешных вызовов возвращает по одному слоту
+type Bucket struct {
+	name       string
+	limits     Limits
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	mu          sync.Mutex
+	tokens      float64
+	refilled    time.Time
+	limit       int
+	inFlight    int
+	successes   int
+	strikes     int
+	pausedUntil time.Time
+	// Закрывается и заменяется при каждом изменении, чтобы разбудить всех ждущих
+	changed chan struct{}
+}
+
+func newBucket(name string, limits Limits, backoff, maxBackoff time.Duration) *Bucket {
+	if limits.Concurrency < 1 {
+		limits.Concurrency = 1
+	}
+	if limits.Burst < 1 {
+		limits.Burst = 1
+	}
+	return &Bucket{
+		name:       name,
+		limits:     limits,
+		backoff:    backoff,
+		maxBackoff: maxBackoff,
+		tokens:     float64(limits.Burst),
+		refilled:   time.Now(),
+		limit:      limits.Concurrency,
+		changed:    make(chan struct{}),
+	}
+}
+
+// Acquire ждёт слот и токен на один вызов модели. release обязательно вызывается с результатом вызова:
+// по нему лимитер понимает, что провайдер ответил 429.
+func (b *Bucket) Acquire(ctx context.Context) (release func(error), err error) {
+	if b == nil {
+		return func(error) {}, nil
+	}
+
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		var wait time.Duration
+		switch {
+		case now.Before(b.pausedUntil):
+			wait = b.pausedUntil.Sub(now)
+		case b.inFlight >= b.limit:
+			wait = -1
+		default:
+			wait = b.take(now)
+		}
+		if wait == 0 {
+			b.inFlight++
+			b.mu.Unlock()
+			return b.release, nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		if err := sleep(ctx, changed, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// take забирает токен или возвращает, сколько ждать следующего
+func (b *Bucket) take(now time.Time) time.Duration {
+	if b.limits.RPS <= 0 {
+		return 0
+	}
+	b.tokens += now.Sub(b.refilled).Seconds() * b.limits.RPS
+	if max := float64(b.limits.Burst); b.tokens > max {
+		b.tokens = max
+	}
+	b.refilled = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limits.RPS * float64(time.Second))
+}
+
+func (b *Bucket) release(callErr error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+	if limited, retryAfter := IsRateLimited(callErr); limited {
+		b.strikes++
+		b.successes = 0
+		b.limit = max(1, b.limit/2)
+		if retryAfter <= 0 {
+			retryAfter = min(b.backoff<<(b.strikes-1), b.maxBackoff)
+		}
+		if until := time.Now().Add(retryAfter); until.After(b.pausedUntil) {
+			b.pausedUntil = until
+		}
+	} else if callErr == nil {
+		b.strikes = 0
+		b.successes++
+		// Слот возвращается после стольких успехов подряд, сколько слотов сейчас открыто
+		if b.limit < b.limits.Concurrency && b.successes >= b.limit {
+			b.limit++
+			b.successes = 0
+		}
+	}
+
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *Bucket) waitReady(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		var wait time.Duration
+		if now := time.Now(); now.Before(b.pausedUntil) {
+			wait = b.pausedUntil.Sub(now)
+		} else if b.inFlight >= b.limit {
+			wait = -1
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		if wait == 0 {
+			return nil
+		}
+		if err := sleep(ctx, changed, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func (b *Bucket) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := Stats{Name: b.name, Limit: b.limit, MaxLimit: b.limits.Concurrency, InFlight: b.inFlight}
+	if time.Now().Before(b.pausedUntil) {
+		s.PausedUntil = b.pausedUntil
+	}
+	return s
+}
+
+// sleep ждёт изменения лимитера или истечения wait; wait < 0 — только изменения
+func sleep(ctx context.Context, changed <-chan struct{}, wait time.Duration) error {
+	var timer <-chan time.Time
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		timer = t.C
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-changed:
+	case <-timer:
+	}
+	return nil
+}
+
+// RetryAfterError — ошибка провайдера, который сообщает, когда повторить запрос
+type RetryAfterError interface {
+	RetryAfter() time.Duration
+}
+
+var (
+	statusPattern     = regexp.MustCompile(`(?i)http (error|status):? ?429\b|\b429 too many requests`)
+	retryAfterPattern = regexp.MustCompile(`(?i)retry[- _]after"?\s*[:=]?\s*"?(\d+(\.\d+)?)`)
+)
+
+// IsRateLimited распознаёт 429 у провайдера и достаёт Retry-After, если он есть: из самой ошибки
+// или из текста ответа. Клиент Mistral отдаёт 429 строкой "(HTTP Error 429) {...}" без заголовков.
+func IsRateLimited(err error) (bool, time.Duration) {
+	if err == nil {
+		return false, 0
+	}
+
+	var retryAfter time.Duration
+	var withRetry RetryAfterError
+	if errors.As(err, &withRetry) {
+		retryAfter = withRetry.RetryAfter()
+	} else if m := retryAfterPattern.FindStringSubmatch(err.Error()); m != nil {
+		if seconds, parseErr := strconv.ParseFloat(m[1], 64); parseErr == nil {
+			retryAfter = time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	var llmErr *llms.Error
+	limited := (errors.As(err, &llmErr) && llmErr.Code == llms.ErrCodeRateLimit) ||
+		statusPattern.MatchString(err.Error()) || retryAfter > 0
+	if !limited {
+		return false, 0
+	}
+	return true, retryAfter
+}
+
+// parseRule разбирает "provider/model=rps:burst:concurrency"
+func parseRule(rule string) (string, Limits, error) {
+	name, value, ok := strings.Cut(rule, "=")
+	parts := strings.Split(value, ":")
+	if !ok || strings.TrimSpace(name) == "" || len(parts) != 3 {
+		return "", Limits{}, fmt.Errorf("ratelimit: invalid rule %q, want provider/model=rps:burst:concurrency", rule)
+	}
+
+	rps, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return "", Limits{}, fmt.Errorf("ratelimit: rule %q: rps: %w", rule, err)
+	}
+	burst, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", Limits{}, fmt.Errorf("ratelimit: rule %q: burst: %w", rule, err)
+	}
+	concurrency, err := strconv.Atoi(parts[2])
+	if err != nil || concurrency < 1 {
+		return "", Limits{}, fmt.Errorf("ratelimit: rule %q: invalid concurrency", rule)
+	}
+	return strings.TrimSpace(name), Limits{RPS: rps, Burst: burst, Concurrency: concurrency}, nil
+}
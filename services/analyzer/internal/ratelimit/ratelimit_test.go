@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+var errRateLimited = errors.New(`(HTTP Error 429) {"message":"Requests rate limit exceeded"}`)
+
+func registry(t *testing.T, cfg Config) *Registry {
+	cfg.Enabled = true
+	if cfg.Backoff == 0 {
+		cfg.Backoff, cfg.MaxBackoff = 50*time.Millisecond, time.Second
+	}
+	r, err := New(&cfg)
+	require.NoError(t, err)
+	return r
+}
+
+func TestNew_ModelRules(t *testing.T) {
+	r := registry(t, Config{RPS: 1, Burst: 2, Concurrency: 4, Models: []string{
+		"mistral/mistral-large-latest=0.5:1:2",
+		"open-mistral-7b=0:1:8",
+	}})
+
+	assert.Equal(t, Limits{RPS: 0.5, Burst: 1, Concurrency: 2}, r.For("mistral", "mistral-large-latest").limits)
+	assert.Equal(t, Limits{RPS: 0, Burst: 1, Concurrency: 8}, r.For("openai", "open-mistral-7b").limits)
+	assert.Equal(t, Limits{RPS: 1, Burst: 2, Concurrency: 4}, r.For("mistral", "ministral-3b-latest").limits)
+	assert.Same(t, r.For("mistral", "open-mistral-7b"), r.For("mistral", "open-mistral-7b"))
+
+	for _, rule := range []string{"open-mistral-7b", "=1:1:1", "m=1:1", "m=x:1:1", "m=1:1:0"} {
+		_, err := New(&Config{Models: []string{rule}})
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestRegistry_Disabled(t *testing.T) {
+	r, err := New(&Config{Enabled: false})
+	require.NoError(t, err)
+
+	b := r.For("mistral", "m")
+	assert.Nil(t, b)
+	release, err := b.Acquire(context.Background())
+	require.NoError(t, err)
+	release(errRateLimited)
+	assert.NoError(t, r.Wait(context.Background(), "mistral", "m"))
+}
+
+func TestBucket_ConcurrencyLimit(t *testing.T) {
+	b := registry(t, Config{Concurrency: 2}).For("mistral", "m")
+	ctx := context.Background()
+
+	first, err := b.Acquire(ctx)
+	require.NoError(t, err)
+	_, err = b.Acquire(ctx)
+	require.NoError(t, err)
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = b.Acquire(short)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	acquired := make(chan struct{})
+	go func() {
+		if _, err := b.Acquire(ctx); err == nil {
+			close(acquired)
+		}
+	}()
+	first(nil)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("slot was not handed over after release")
+	}
+}
+
+func TestBucket_TokenRate(t *testing.T) {
+	b := registry(t, Config{RPS: 20, Burst: 1, Concurrency: 10}).For("mistral", "m")
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := b.Acquire(context.Background())
+		require.NoError(t, err)
+		release(nil)
+	}
+	// Первый вызов из запаса, ещё два — по 50 мс
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestBucket_AdaptsToRateLimit(t *testing.T) {
+	r := registry(t, Config{Concurrency: 4})
+	b := r.For("mistral", "m")
+	ctx := context.Background()
+
+	release, err := b.Acquire(ctx)
+	require.NoError(t, err)
+	release(fmt.Errorf("call: %w", errRateLimited))
+
+	stats := b.Stats()
+	assert.Equal(t, 2, stats.Limit)
+	assert.False(t, stats.PausedUntil.IsZero())
+
+	// Консьюмер и следующий вызов ждут конца паузы
+	start := time.Now()
+	require.NoError(t, r.Wait(ctx, "mistral", "m"))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	// Вторая ошибка подряд снова делит слоты и удваивает паузу
+	release, err = b.Acquire(ctx)
+	require.NoError(t, err)
+	release(errRateLimited)
+	assert.Equal(t, 1, b.Stats().Limit)
+	assert.GreaterOrEqual(t, time.Until(b.Stats().PausedUntil), 80*time.Millisecond)
+
+	// Успехи возвращают слоты по одному
+	for i := 0; i < 1+2+3; i++ {
+		release, err = b.Acquire(ctx)
+		require.NoError(t, err)
+		release(nil)
+	}
+	assert.Equal(t, 4, b.Stats().Limit)
+
+	// Прочие ошибки на лимиты не влияют
+	release, err = b.Acquire(ctx)
+	require.NoError(t, err)
+	release(errors.New("(HTTP Error 500) internal error"))
+	assert.Equal(t, 4, b.Stats().Limit)
+	assert.True(t, b.Stats().PausedUntil.IsZero())
+}
+
+func TestRegistry_WaitOnlyForGivenModel(t *testing.T) {
+	r := registry(t, Config{Concurrency: 1, Backoff: time.Second, MaxBackoff: time.Second})
+	ctx := context.Background()
+
+	// Сильная модель занята, классификатор стоит на паузе после 429
+	_, err := r.For("mistral", "strong").Acquire(ctx)
+	require.NoError(t, err)
+	release, err := r.For("mistral", "classifier").Acquire(ctx)
+	require.NoError(t, err)
+	release(errRateLimited)
+
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, r.Wait(short, "mistral", "cheap"))
+	assert.ErrorIs(t, r.Wait(short, "mistral", "classifier"), context.DeadlineExceeded)
+}
+
+type retryAfterErr struct{ after time.Duration }
+
+func (e retryAfterErr) Error() string             { return "slow down" }
+func (e retryAfterErr) RetryAfter() time.Duration { return e.after }
+
+func TestIsRateLimited(t *testing.T) {
+	cases := []struct {
+		err     error
+		limited bool
+		after   time.Duration
+	}{
+		{nil, false, 0},
+		{errors.New("(HTTP Error 500) boom"), false, 0},
+		{errRateLimited, true, 0},
+		{errors.New(`(HTTP Error 429) {"message":"rate limit","retry_after": 7}`), true, 7 * time.Second},
+		{errors.New("429 Too Many Requests, Retry-After: 1.5"), true, 1500 * time.Millisecond},
+		{llms.NewError(llms.ErrCodeRateLimit, "mistral", "Rate limit exceeded"), true, 0},
+		{fmt.Errorf("wrapped: %w", retryAfterErr{3 * time.Second}), true, 3 * time.Second},
+	}
+	for _, tc := range cases {
+		limited, after := IsRateLimited(tc.err)
+		assert.Equal(t, tc.limited, limited, "%v", tc.err)
+		assert.Equal(t, tc.after, after, "%v", tc.err)
+	}
+}
@@ -0,0 +1,226 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Типы сущностей; они же — префиксы плейсхолдеров
+const (
+	EntityIBAN     = "IBAN"
+	EntityCard     = "CARD"
+	EntityPassport = "PASSPORT"
+	EntityOTP      = "OTP"
+	EntityPhone    = "PHONE"
+)
+
+type Config struct {
+	Enabled   bool `env:"ENABLED" env-default:"true"`
+	Phones    bool `env:"PHONES" env-default:"true"`
+	Cards     bool `env:"CARDS" env-default:"true"`
+	IBANs     bool `env:"IBANS" env-default:"true"`
+	Passports bool `env:"PASSPORTS" env-default:"true"`
+	OTP       bool `env:"OTP" env-default:"true"`
+}
+
+type detector struct {
+	entity string
+	re     *regexp.Regexp
+	// Номер подгруппы со значением; 0 — всё совпадение. Ключевые слова вокруг номера остаются в тексте.
+	group int
+	valid func(string) bool
+	// Продолжение текста сразу за значением, при котором совпадение отбрасывается
+	rejectAfter *regexp.Regexp
+}
+
+// dateOrTime — число продолжается датой или временем: "2026-01-15", "15.01", "10:30"
+var dateOrTime = regexp.MustCompile(`^[-.:]\d`)
+
+// Порядок важен: IBAN и карты длиннее телефонов и должны заменяться первыми
+var detectors = []detector{
+	{entity: EntityIBAN, re: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`), valid: validIBAN},
+	{entity: EntityCard, re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: validLuhn},
+	{entity: EntityPassport, re: regexp.MustCompile(`(?i)(?:паспорт\S*|passport)[^\d\n]{0,20}(\d{2} ?\d{2} ?\d{6}|[A-Z]{1,2}\d{6,8})\b`), group: 1},
+	// Ключевое слово — отдельное слово, а не часть "barcode" или "кодекс": \b в Go не знает кириллицы
+	{entity: EntityOTP, re: regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_])(?:код|коды|кодом|code|otp|пароль|password)` +
+		`(?:[^\p{L}\d\n][^\d\n]{0,19})?(\d{4,8})\b`), group: 1, rejectAfter: dateOrTime},
+	{entity: EntityPhone, re: regexp.MustCompile(`(?:\+\d{1,3}|\b8)[ -]?\(?\d{3}\)?[ -]?\d{3}[ -]?\d{2}[ -]?\d{2}\b`)},
+}
+
+// Redactor заменяет персональные данные на плейсхолдеры перед отправкой текста модели
+type Redactor struct {
+	enabled map[string]bool
+	counts  map[string]*atomic.Int64
+}
+
+func New(cfg *Config) *Redactor {
+	enabled := map[string]bool{
+		EntityIBAN:     cfg.Enabled && cfg.IBANs,
+		EntityCard:     cfg.Enabled && cfg.Cards,
+		EntityPassport: cfg.Enabled && cfg.Passports,
+		EntityOTP:      cfg.Enabled && cfg.OTP,
+		EntityPhone:    cfg.Enabled && cfg.Phones,
+	}
+	counts := make(map[string]*atomic.Int64, len(detectors))
+	for _, d := range detectors {
+		counts[d.entity] = &atomic.Int64{}
+	}
+	return &Redactor{enabled: enabled, counts: counts}
+}
+
+// Mapping — плейсхолдеры одного письма. Одинаковые значения получают один плейсхолдер,
+// поэтому модель видит, что в теме и теле речь об одном и том же номере.
+type Mapping struct {
+	byValue  map[string]string
+	original map[string]string
+	counts   map[string]int
+}
+
+func newMapping() *Mapping {
+	return &Mapping{byValue: make(map[string]string), original: make(map[string]string), counts: make(map[string]int)}
+}
+
+// Redact заменяет найденные сущности во всех текстах одного письма общим набором плейсхолдеров
+func (r *Redactor) Redact(texts ...string) ([]string, *Mapping) {
+	m := newMapping()
+	if r == nil {
+		return texts, m
+	}
+
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		for _, d := range detectors {
+			if r.enabled[d.entity] {
+				text = m.replace(text, d)
+			}
+		}
+		out[i] = text
+	}
+
+	for entity, count := range m.counts {
+		r.counts[entity].Add(int64(count))
+	}
+	return out, m
+}
+
+// Stats — сколько сущностей каждого типа заменено с запуска сервиса
+func (r *Redactor) Stats() map[string]int64 {
+	stats := make(map[string]int64)
+	if r == nil {
+		return stats
+	}
+	for entity, count := range r.counts {
+		stats[entity] = count.Load()
+	}
+	return stats
+}
+
+// Restore возвращает исходные значения на место плейсхолдеров
+func (m *Mapping) Restore(text string) string {
+	if m == nil || len(m.original) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(m.original)*2)
+	for placeholder, value := range m.original {
+		pairs = append(pairs, placeholder, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Counts — сколько разных значений каждого типа заменено в письме
+func (m *Mapping) Counts() map[string]int {
+	return m.counts
+}
+
+func (m *Mapping) replace(text string, d detector) string {
+	matches := d.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[2*d.group], match[2*d.group+1]
+		if start < 0 {
+			continue
+		}
+		value := text[start:end]
+		if d.valid != nil && !d.valid(value) {
+			continue
+		}
+		if d.rejectAfter != nil && d.rejectAfter.MatchString(text[end:]) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(m.placeholder(d.entity, value))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func (m *Mapping) placeholder(entity, value string) string {
+	if placeholder, ok := m.byValue[value]; ok {
+		return placeholder
+	}
+	m.counts[entity]++
+	// Скобки в плейсхолдере не встречаются в значениях, поэтому детекторы не находят их повторно
+	placeholder := fmt.Sprintf("[%s_%d]", entity, m.counts[entity])
+	m.byValue[value] = placeholder
+	m.original[placeholder] = value
+	return placeholder
+}
+
+func digits(s string) []int {
+	var ds []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			ds = append(ds, int(r-'0'))
+		}
+	}
+	return ds
+}
+
+// validLuhn отсекает длинные числа, которые не являются номерами карт
+func validLuhn(s string) bool {
+	ds := digits(s)
+	if len(ds) < 13 || len(ds) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range ds {
+		d := ds[len(ds)-1-i]
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validIBAN проверяет контрольную сумму по ISO 13616 (mod 97)
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	rearranged := s[4:] + s[:4]
+	remainder := 0
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A'+10)) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
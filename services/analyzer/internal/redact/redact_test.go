@@ -0,0 +1,100 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allEnabled() *Config {
+	return &Config{Enabled: true, Phones: true, Cards: true, IBANs: true, Passports: true, OTP: true}
+}
+
+func TestRedact_ReplacesEntities(t *testing.T) {
+	r := New(allEnabled())
+
+	out, m := r.Redact(
+		"Перезвоните на +7 (916) 123-45-67",
+		"Оплатите картой 4111 1111 1111 1111 или на счёт DE89 3704 0044 0532 0130 00. "+
+			"Паспорт: 4510 123456. Ваш код подтверждения: 482913. Телефон +7 (916) 123-45-67, срок 2025-12-10 15:00.",
+	)
+
+	require.Len(t, out, 2)
+	assert.Equal(t, "Перезвоните на [PHONE_1]", out[0])
+	assert.Equal(t,
+		"Оплатите картой [CARD_1] или на счёт [IBAN_1]. "+
+			"Паспорт: [PASSPORT_1]. Ваш код подтверждения: [OTP_1]. Телефон [PHONE_1], срок 2025-12-10 15:00.",
+		out[1])
+	assert.Equal(t, map[string]int{EntityPhone: 1, EntityCard: 1, EntityIBAN: 1, EntityPassport: 1, EntityOTP: 1}, m.Counts())
+}
+
+func TestRedact_OTPKeepsDatesAndOtherWords(t *testing.T) {
+	r := New(allEnabled())
+
+	tests := []string{
+		"Code freeze until 2026-01-15",
+		"Код ревью до 15.01.2026",
+		"Пароль сменить до 2026.01.15",
+		"Password expires 2026-01-15T10:00",
+		"Barcode 12345678 on the parcel",
+		"Postcode 123456",
+		"Налоговый кодекс 2024 года",
+		"Без кода 4821 не войти",
+	}
+	for _, text := range tests {
+		t.Run(text, func(t *testing.T) {
+			out, m := r.Redact(text)
+			assert.Equal(t, text, out[0])
+			assert.Empty(t, m.Counts())
+		})
+	}
+
+	out, _ := r.Redact("Код 4821, code: 5930. OTP 7714 действует до 2026-01-15")
+	assert.Equal(t, "Код [OTP_1], code: [OTP_2]. OTP [OTP_3] действует до 2026-01-15", out[0])
+}
+
+func TestRedact_RestoresPlaceholders(t *testing.T) {
+	r := New(allEnabled())
+	_, m := r.Redact("Позвонить +7 916 123 45 67 и 8 800 555 35 35")
+
+	restored := m.Restore("Позвонить по [PHONE_2], если [PHONE_1] не отвечает")
+
+	assert.Equal(t, "Позвонить по 8 800 555 35 35, если +7 916 123 45 67 не отвечает", restored)
+}
+
+func TestRedact_SkipsInvalidNumbers(t *testing.T) {
+	r := New(allEnabled())
+
+	// Не проходит проверку Луна и mod 97
+	out, m := r.Redact("Номер заказа 1234 5678 9012 3456, счёт DE00 3704 0044 0532 0130 00")
+
+	assert.Equal(t, "Номер заказа 1234 5678 9012 3456, счёт DE00 3704 0044 0532 0130 00", out[0])
+	assert.Empty(t, m.Counts())
+}
+
+func TestRedact_EntityTypesAreConfigurable(t *testing.T) {
+	cfg := allEnabled()
+	cfg.Phones = false
+	r := New(cfg)
+
+	out, _ := r.Redact("Телефон +7 916 123 45 67, код 1234")
+
+	assert.Equal(t, "Телефон +7 916 123 45 67, код [OTP_1]", out[0])
+
+	disabled := New(&Config{Enabled: false, OTP: true})
+	out, _ = disabled.Redact("код 1234")
+	assert.Equal(t, "код 1234", out[0])
+}
+
+func TestRedactor_Stats(t *testing.T) {
+	r := New(allEnabled())
+
+	r.Redact("код 1234")
+	r.Redact("code: 5678, +7 916 123 45 67")
+
+	stats := r.Stats()
+	assert.Equal(t, int64(2), stats[EntityOTP])
+	assert.Equal(t, int64(1), stats[EntityPhone])
+	assert.Equal(t, int64(0), stats[EntityCard])
+}
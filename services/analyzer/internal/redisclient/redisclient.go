@@ -0,0 +1,28 @@
+package redisclient
+
+import (
+	"context"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/redis"
+
+	"go.uber.org/fx"
+)
+
+// New подключается к Redis. Redis для analyzer необязателен: кэш и учёт токенов
+// без него работают в памяти процесса, поэтому при ошибке возвращается nil.
+func New(ctx context.Context, lc fx.Lifecycle, cfg *redis.Config, log *logger.CurrentLogger) *redis.Client {
+	client, err := redis.New(ctx, cfg)
+	if err != nil {
+		log.Warn(ctx, "Redis is unavailable, continuing without it", "error", err)
+		return nil
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(_ context.Context) error {
+			return client.Close()
+		},
+	})
+	log.Info(ctx, "Connected to Redis", "host", cfg.Host, "port", cfg.Port)
+	return client
+}
@@ -0,0 +1,254 @@
+package routing
+
+import (
+	"sort"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"reminder-hub/pkg/models"
+	"reminder-hub/services/analyzer/internal/budget"
+)
+
+type Config struct {
+	// Выключено — все письма идут в основную модель (MISTRAL_MODEL), как раньше
+	Enabled bool `env:"ENABLED" env-default:"true"`
+	// Большая модель: в неё эскалируются неудачные ответы основной и сразу уходят сложные письма
+	Model string `env:"MODEL" env-default:"mistral-large-latest"`
+	// Письмо длиннее (тема и текст, в символах) маленькая модель разбирает плохо
+	MaxChars int `env:"MAX_CHARS" env-default:"6000"`
+	// Письмо многоязычное, если хотя бы два алфавита занимают не меньше этой доли букв
+	MultilingualShare float64 `env:"MULTILINGUAL_SHARE" env-default:"0.25"`
+	// Ответ основной модели с уверенностью ниже порога по любому полю эскалируется
+	MinConfidence float64 `env:"MIN_CONFIDENCE" env-default:"0.8"`
+}
+
+// Маршруты письма
+const (
+	// Маршрутизация выключена: только основная модель
+	RouteDirect = "direct"
+	// Сначала основная модель, при неудаче — большая
+	RouteCheap = "cheap"
+	// Сразу большая модель
+	RouteLong         = "long"
+	RouteMultilingual = "multilingual"
+)
+
+// Причины эскалации
+const (
+	ReasonError         = "error"
+	ReasonInvalid       = "invalid_response"
+	ReasonLowConfidence = "low_confidence"
+)
+
+// Plan — маршрут письма. Strong — сразу в большую модель, Escalate — большая модель после основной при неудаче.
+type Plan struct {
+	Route    string
+	Strong   bool
+	Escalate bool
+}
+
+// ModelStats — вызовы одной модели на маршруте и их стоимость
+type ModelStats struct {
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Stats — сколько писем прошло маршрутом, сколько из них эскалировано и во что это обошлось
+type Stats struct {
+	Route          string                `json:"route"`
+	Emails         int64                 `json:"emails"`
+	Escalated      int64                 `json:"escalated"`
+	EscalationRate float64               `json:"escalation_rate"`
+	Reasons        map[string]int64      `json:"escalation_reasons,omitempty"`
+	Models         map[string]ModelStats `json:"models,omitempty"`
+	CostUSD        float64               `json:"cost_usd"`
+}
+
+// Router выбирает модель для письма и считает метрики маршрутов с запуска сервиса
+type Router struct {
+	cfg *Config
+
+	mu     sync.Mutex
+	routes map[string]*Stats
+}
+
+func New(cfg *Config) *Router {
+	return &Router{cfg: cfg, routes: make(map[string]*Stats)}
+}
+
+// Enabled — маршрутизация включена и большая модель задана. nil-роутер выключен.
+func (r *Router) Enabled() bool {
+	return r != nil && r.cfg.Enabled && r.cfg.Model != ""
+}
+
+// Model — большая модель
+func (r *Router) Model() string {
+	if r == nil {
+		return ""
+	}
+	return r.cfg.Model
+}
+
+// Route выбирает маршрут по тексту, который увидит модель, и учитывает письмо в метриках
+func (r *Router) Route(subject, body string) Plan {
+	plan := Plan{Route: RouteDirect}
+	switch {
+	case !r.Enabled():
+	case r.cfg.MaxChars > 0 && utf8.RuneCountInString(subject)+utf8.RuneCountInString(body) > r.cfg.MaxChars:
+		plan = Plan{Route: RouteLong, Strong: true}
+	case r.cfg.MultilingualShare > 0 && multilingual(subject+"\n"+body, r.cfg.MultilingualShare):
+		plan = Plan{Route: RouteMultilingual, Strong: true}
+	default:
+		plan = Plan{Route: RouteCheap, Escalate: true}
+	}
+
+	if r != nil {
+		r.mu.Lock()
+		r.route(plan.Route).Emails++
+		r.mu.Unlock()
+	}
+	return plan
+}
+
+// LowConfidence — ответ основной модели недостаточно уверенный, чтобы не перепроверить его большой.
+// Порог выше CONFIDENCE_THRESHOLD: большая модель получает и ответы, которые ещё не ушли бы на проверку.
+func (r *Router) LowConfidence(parsed *models.ParsedEmails) bool {
+	if r == nil || parsed == nil {
+		return false
+	}
+	c := parsed.Confidence
+	return c != nil && (c.Title < r.cfg.MinConfidence || c.Deadline < r.cfg.MinConfidence)
+}
+
+// Escalated учитывает эскалацию письма с маршрута route
+func (r *Router) Escalated(route, reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.route(route)
+	s.Escalated++
+	if s.Reasons == nil {
+		s.Reasons = make(map[string]int64)
+	}
+	s.Reasons[reason]++
+}
+
+// Record учитывает вызов модели на маршруте. Ответы из кэша не учитываются: они ничего не стоят.
+func (r *Router) Record(route, model string, usage budget.Usage, costUSD float64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.route(route)
+	if s.Models == nil {
+		s.Models = make(map[string]ModelStats)
+	}
+	m := s.Models[model]
+	m.Calls++
+	m.PromptTokens += int64(usage.PromptTokens)
+	m.CompletionTokens += int64(usage.CompletionTokens)
+	m.CostUSD += costUSD
+	s.Models[model] = m
+	s.CostUSD += costUSD
+}
+
+// Stats — метрики маршрутов по имени
+func (r *Router) Stats() []Stats {
+	if r == nil {
+		return []Stats{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]Stats, 0, len(r.routes))
+	for _, s := range r.routes {
+		out := *s
+		out.Reasons = copyMap(s.Reasons)
+		out.Models = make(map[string]ModelStats, len(s.Models))
+		for model, m := range s.Models {
+			out.Models[model] = m
+		}
+		if out.Emails > 0 {
+			out.EscalationRate = float64(out.Escalated) / float64(out.Emails)
+		}
+		stats = append(stats, out)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Route < stats[j].Route })
+	return stats
+}
+
+func (r *Router) route(name string) *Stats {
+	s, ok := r.routes[name]
+	if !ok {
+		s = &Stats{Route: name}
+		r.routes[name] = s
+	}
+	return s
+}
+
+// multilingual — в тексте хотя бы два алфавита, каждый не меньше доли share от всех букв.
+// Латиница в отдельных словах (названия компаний, ссылки) в русском письме до доли не дотягивает.
+func multilingual(text string, share float64) bool {
+	counts := make(map[string]int)
+	total := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		total++
+		counts[script(r)]++
+	}
+	if total == 0 {
+		return false
+	}
+
+	scripts := 0
+	for _, n := range counts {
+		if float64(n)/float64(total) >= share {
+			scripts++
+		}
+	}
+	return scripts >= 2
+}
+
+var scripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"latin", unicode.Latin},
+	{"cyrillic", unicode.Cyrillic},
+	{"greek", unicode.Greek},
+	{"arabic", unicode.Arabic},
+	{"hebrew", unicode.Hebrew},
+	// Японский текст смешивает иероглифы и каны, но это один язык
+	{"cjk", unicode.Han},
+	{"cjk", unicode.Hiragana},
+	{"cjk", unicode.Katakana},
+	{"cjk", unicode.Hangul},
+}
+
+func script(r rune) string {
+	for _, s := range scripts {
+		if unicode.Is(s.table, r) {
+			return s.name
+		}
+	}
+	return "other"
+}
+
+func copyMap(m map[string]int64) map[string]int64 {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]int64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
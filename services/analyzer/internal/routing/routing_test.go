@@ -0,0 +1,69 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+
+	"reminder-hub/pkg/models"
+	"reminder-hub/services/analyzer/internal/budget"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() *Config {
+	return &Config{Enabled: true, Model: "large", MaxChars: 100, MultilingualShare: 0.25, MinConfidence: 0.8}
+}
+
+func TestRouter_Route(t *testing.T) {
+	r := New(testConfig())
+
+	assert.Equal(t, Plan{Route: RouteCheap, Escalate: true}, r.Route("Отчёт", "Пришлите отчёт по проекту Acme до пятницы"))
+	assert.Equal(t, Plan{Route: RouteLong, Strong: true}, r.Route("Отчёт", strings.Repeat("текст ", 20)))
+	assert.Equal(t, Plan{Route: RouteMultilingual, Strong: true},
+		r.Route("Report / Отчёт", "Please send the report by Friday. Пришлите отчёт до пятницы."))
+	// Японский — один язык, хотя в нём и иероглифы, и каны
+	assert.Equal(t, RouteCheap, r.Route("報告", "金曜日までにレポートを送ってください").Route)
+
+	var disabled *Router
+	assert.Equal(t, Plan{Route: RouteDirect}, disabled.Route("Отчёт", "текст"))
+	assert.Equal(t, Plan{Route: RouteDirect}, New(&Config{Model: "large"}).Route("Отчёт", "текст"))
+}
+
+func TestRouter_LowConfidence(t *testing.T) {
+	r := New(testConfig())
+
+	assert.False(t, r.LowConfidence(&models.ParsedEmails{}))
+	assert.False(t, r.LowConfidence(&models.ParsedEmails{Confidence: &models.Confidence{Title: 0.9, Deadline: 1}}))
+	assert.True(t, r.LowConfidence(&models.ParsedEmails{Confidence: &models.Confidence{Title: 0.9, Deadline: 0.7}}))
+
+	var disabled *Router
+	assert.False(t, disabled.LowConfidence(&models.ParsedEmails{Confidence: &models.Confidence{}}))
+}
+
+func TestRouter_Stats(t *testing.T) {
+	r := New(testConfig())
+
+	for i := 0; i < 4; i++ {
+		r.Route("Отчёт", "Пришлите отчёт")
+	}
+	r.Route("Отчёт", strings.Repeat("текст ", 20))
+	r.Record(RouteCheap, "small", budget.Usage{PromptTokens: 100, CompletionTokens: 20}, 0.01)
+	r.Record(RouteCheap, "small", budget.Usage{PromptTokens: 100, CompletionTokens: 20}, 0.01)
+	r.Escalated(RouteCheap, ReasonLowConfidence)
+	r.Record(RouteCheap, "large", budget.Usage{PromptTokens: 100, CompletionTokens: 20}, 0.1)
+
+	stats := r.Stats()
+	assert.Len(t, stats, 2)
+	cheap := stats[0]
+	assert.Equal(t, RouteCheap, cheap.Route)
+	assert.Equal(t, int64(4), cheap.Emails)
+	assert.Equal(t, int64(1), cheap.Escalated)
+	assert.Equal(t, 0.25, cheap.EscalationRate)
+	assert.Equal(t, map[string]int64{ReasonLowConfidence: 1}, cheap.Reasons)
+	assert.Equal(t, ModelStats{Calls: 2, PromptTokens: 200, CompletionTokens: 40, CostUSD: 0.02}, cheap.Models["small"])
+	assert.InDelta(t, 0.12, cheap.CostUSD, 1e-9)
+	assert.Equal(t, Stats{Route: RouteLong, Emails: 1, Models: map[string]ModelStats{}}, stats[1])
+
+	var disabled *Router
+	assert.Empty(t, disabled.Stats())
+}
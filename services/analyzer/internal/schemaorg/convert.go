@@ -0,0 +1,358 @@
+package schemaorg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"reminder-hub/pkg/models"
+	"reminder-hub/services/analyzer/internal/locale"
+)
+
+// Бронирования, которые становятся событиями: у них есть точное время начала
+var reservationTypes = []string{
+	"FlightReservation",
+	"TrainReservation",
+	"BusReservation",
+	"BoatReservation",
+	"TaxiReservation",
+	"RentalCarReservation",
+	"LodgingReservation",
+	"EventReservation",
+	"FoodEstablishmentReservation",
+}
+
+// Оплаченный счёт и доставленная посылка закрывают задачу, созданную по прошлому письму
+var (
+	paidStatuses      = map[string]bool{"PaymentComplete": true, "PaymentAutomaticallyApplied": true}
+	deliveredStatuses = map[string]bool{"OrderDelivered": true}
+)
+
+// Build превращает разметку schema.org из HTML письма в события (бронирования) и задачи (доставки, счета)
+// без обращения к модели. События попадают в первую задачу, а если задач нет — в сообщение с пустым Title.
+// Пустой результат значит, что применимой разметки нет и письмо надо разбирать обычным путём.
+// Свои тексты ("Рейс", "Оплатить счёт") пишутся на языке language.
+func Build(rawEmail models.RawEmail, loc *time.Location, language string) ([]*models.ParsedEmails, []error) {
+	items, errs := Extract(rawEmail.HTML)
+
+	var tasks []*models.ParsedEmails
+	var events []models.Event
+	seen := make(map[string]bool)
+	for _, it := range items {
+		switch {
+		case it.Is(reservationTypes...):
+			e, err := toEvent(it, loc, language)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if e.Title == "" {
+				e.Title = rawEmail.Subject
+			}
+			if key := e.UID + "|" + e.Title + "|" + e.Start.String(); !seen[key] {
+				seen[key] = true
+				events = append(events, e)
+			}
+		case it.Is("ParcelDelivery", "Order", "Invoice"):
+			task, err := toTask(rawEmail, it, loc, language)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if task == nil || seen[task.CalendarUID+"|"+task.Title] {
+				continue
+			}
+			seen[task.CalendarUID+"|"+task.Title] = true
+			tasks = append(tasks, task)
+		}
+	}
+
+	if len(tasks) == 0 && len(events) == 0 {
+		return nil, errs
+	}
+	if len(tasks) == 0 {
+		tasks = append(tasks, &models.ParsedEmails{
+			UserID:   rawEmail.UserID,
+			EmailID:  rawEmail.EmailID,
+			Timezone: loc.String(),
+			From:     rawEmail.From,
+		})
+	}
+	tasks[0].Events = events
+	return tasks, errs
+}
+
+// toEvent собирает событие из бронирования. UID строится из номера брони и участка поездки,
+// чтобы письмо об изменении рейса обновило событие, а не создало второе.
+func toEvent(it Item, loc *time.Location, language string) (models.Event, error) {
+	text := func(key locale.Key) string { return locale.Text(language, key) }
+	number := it.String("reservationNumber")
+	target := it.Item("reservationFor")
+	if target == nil {
+		target = Item{}
+	}
+
+	var e models.Event
+	var start, end string
+	var key []string
+	switch {
+	case it.Is("FlightReservation"):
+		flight := flightNumber(target)
+		from, to := target.Item("departureAirport"), target.Item("arrivalAirport")
+		e.Title = joinNonEmpty(" ", text(locale.Flight), flight, route(code(from), code(to)))
+		e.Location = joinNonEmpty(", ", from.String("name"), prefixed(text(locale.Terminal), target.String("departureTerminal")))
+		start, end = target.String("departureTime"), target.String("arrivalTime")
+		key = []string{flight, code(from)}
+	case it.Is("TrainReservation", "BusReservation", "BoatReservation"):
+		kind, from, to := text(locale.Train), target.Item("departureStation"), target.Item("arrivalStation")
+		trip := target.String("trainNumber")
+		if it.Is("BusReservation") {
+			kind, from, to = text(locale.Bus), target.Item("departureBusStop"), target.Item("arrivalBusStop")
+			trip = target.String("busNumber")
+		} else if it.Is("BoatReservation") {
+			kind, from, to = text(locale.Ferry), target.Item("departureBoatTerminal"), target.Item("arrivalBoatTerminal")
+			trip = ""
+		}
+		e.Title = joinNonEmpty(" ", kind, trip, route(from.String("name"), to.String("name")))
+		e.Location = place(from)
+		start, end = target.String("departureTime"), target.String("arrivalTime")
+		key = []string{trip, from.String("name")}
+	case it.Is("TaxiReservation", "RentalCarReservation"):
+		e.Title = text(locale.Taxi)
+		if it.Is("RentalCarReservation") {
+			e.Title = joinNonEmpty(": ", text(locale.CarRental), it.Item("provider").String("name"))
+		}
+		e.Location = place(it.Item("pickupLocation"))
+		start, end = it.String("pickupTime"), it.String("dropoffTime")
+	case it.Is("LodgingReservation"):
+		e.Title = joinNonEmpty(": ", text(locale.CheckIn), target.String("name"))
+		e.Location = place(target)
+		start, end = it.String("checkinTime"), it.String("checkoutTime")
+	case it.Is("FoodEstablishmentReservation"):
+		e.Title = joinNonEmpty(": ", text(locale.TableReservation), target.String("name"))
+		e.Location = place(target)
+		start, end = it.String("startTime"), it.String("endTime")
+	default:
+		e.Title = target.String("name")
+		e.Location = place(target.Item("location"))
+		start, end = target.String("startDate"), target.String("endDate")
+		e.JoinURL = target.String("url")
+		if e.JoinURL == "" {
+			e.JoinURL = target.Item("location").String("url")
+		}
+	}
+	if number != "" {
+		e.Description = text(locale.ReservationNumber) + number
+		e.UID = uid(it, append([]string{number}, key...)...)
+	}
+
+	if typeName(it.String("reservationStatus")) == "ReservationCancelled" {
+		if e.UID == "" {
+			return e, fmt.Errorf("%s: cancelled reservation without number", it.Types()[0])
+		}
+		e.Status = models.StatusCancelled
+	}
+
+	var err error
+	if e.Start, e.AllDay, err = parseTime(start, loc); err != nil && e.Status != models.StatusCancelled {
+		return e, fmt.Errorf("%s %q: start: %w", it.Types()[0], e.Title, err)
+	}
+	if t, _, err := parseTime(end, loc); err == nil && t.After(e.Start) {
+		e.End = &t
+	}
+	return e, nil
+}
+
+// toTask собирает задачу из доставки или счёта. nil без ошибки — в объекте нечего делать.
+func toTask(rawEmail models.RawEmail, it Item, loc *time.Location, language string) (*models.ParsedEmails, error) {
+	text := func(key locale.Key) string { return locale.Text(language, key) }
+	parsed := &models.ParsedEmails{
+		UserID:   rawEmail.UserID,
+		EmailID:  rawEmail.EmailID,
+		Timezone: loc.String(),
+		From:     rawEmail.From,
+	}
+
+	var due string
+	var lines []string
+	switch {
+	case it.Is("Order"):
+		// Заказ интересен только доставкой: у него самого срока нет
+		delivery := it.Item("orderDelivery")
+		if delivery == nil {
+			return nil, nil
+		}
+		if !delivery.Is("ParcelDelivery") {
+			delivery["@type"] = "ParcelDelivery"
+		}
+		if delivery["deliveryStatus"] == nil {
+			delivery["deliveryStatus"] = it["orderStatus"]
+		}
+		// Без трек-номера доставку различает номер заказа
+		if delivery["partOfOrder"] == nil && it.String("orderNumber") != "" {
+			delivery["partOfOrder"] = Item{"orderNumber": it.String("orderNumber")}
+		}
+		return toTask(rawEmail, delivery, loc, language)
+	case it.Is("ParcelDelivery"):
+		tracking := it.String("trackingNumber")
+		carrier := it.Item("carrier").String("name")
+		if carrier == "" {
+			carrier = it.Item("provider").String("name")
+		}
+		parsed.Title = joinNonEmpty(": ", text(locale.PickUpParcel), it.Item("itemShipped").String("name"))
+		lines = append(lines, prefixed(text(locale.Carrier), carrier), prefixed(text(locale.TrackingNumber), tracking),
+			prefixed(text(locale.Tracking), it.String("trackingUrl")))
+		due = it.String("expectedArrivalUntil")
+		if due == "" {
+			due = it.String("expectedArrivalFrom")
+		}
+		if tracking != "" {
+			parsed.CalendarUID = uid(it, tracking)
+		} else if order := it.Item("partOfOrder").String("orderNumber"); order != "" {
+			parsed.CalendarUID = uid(it, "order", order)
+		}
+		if deliveredStatuses[typeName(it.String("deliveryStatus"))] {
+			parsed.Status = models.StatusCompleted
+		}
+	case it.Is("Invoice"):
+		provider := it.Item("provider").String("name")
+		if provider == "" {
+			provider = it.Item("broker").String("name")
+		}
+		parsed.Title = joinNonEmpty(" ", text(locale.PayInvoice), provider)
+		lines = append(lines, prefixed(text(locale.Amount), amount(it.Item("totalPaymentDue"))),
+			prefixed(text(locale.Account), it.String("accountId")), prefixed(text(locale.PaymentLink), it.String("url")))
+		due = it.String("paymentDueDate")
+		if due == "" {
+			due = it.String("paymentDue")
+		}
+		if id := firstNonEmpty(it.String("confirmationNumber"), it.String("identifier")); id != "" {
+			parsed.CalendarUID = uid(it, provider, id)
+		} else if order := it.Item("referencesOrder").String("orderNumber"); order != "" {
+			parsed.CalendarUID = uid(it, provider, "order", order)
+		}
+		if paidStatuses[typeName(it.String("paymentStatus"))] {
+			parsed.Status = models.StatusCompleted
+		}
+	default:
+		return nil, nil
+	}
+	parsed.Description = joinNonEmpty("\n", lines...)
+
+	// Закрыть можно только задачу, которую найдём по UID
+	if parsed.Status == models.StatusCompleted && parsed.CalendarUID == "" {
+		return nil, nil
+	}
+	if due != "" {
+		deadline, _, err := parseTime(due, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: due date: %w", it.Types()[0], err)
+		}
+		parsed.Deadline = deadline
+	}
+	// Без номеров UID действует в пределах письма: collector хранит одну задачу без UID на письмо,
+	// и остальные доставки или счета того же письма потерялись бы. Закрыть такую задачу другим письмом нельзя.
+	if parsed.CalendarUID == "" {
+		parsed.CalendarUID = uid(it, "email", rawEmail.EmailID, parsed.Title, due)
+	}
+	return parsed, nil
+}
+
+var (
+	zonedLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05Z0700"}
+	localLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+)
+
+// parseTime понимает ISO 8601 с зоной, без зоны (время пользователя) и просто дату (весь день)
+func parseTime(value string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, fmt.Errorf("no time")
+	}
+	for _, layout := range zonedLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, false, nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid time %q", value)
+	}
+	return t, true, nil
+}
+
+// uid — ключ, по которому collector сопоставляет повторные письма об одном и том же бронировании или счёте
+func uid(it Item, parts ...string) string {
+	return "schemaorg:" + it.Types()[0] + ":" + joinNonEmpty(":", parts...)
+}
+
+// flightNumber склеивает код авиакомпании и номер рейса, если номер указан без кода
+func flightNumber(flight Item) string {
+	number := flight.String("flightNumber")
+	airline := flight.Item("airline").String("iataCode")
+	if airline != "" && !strings.HasPrefix(number, airline) {
+		return airline + number
+	}
+	return number
+}
+
+func code(airport Item) string {
+	return firstNonEmpty(airport.String("iataCode"), airport.String("name"))
+}
+
+func route(from, to string) string {
+	if from == "" || to == "" {
+		return from + to
+	}
+	return from + " → " + to
+}
+
+// place — название места и адрес одной строкой
+func place(p Item) string {
+	address := p.Item("address")
+	street := address.String("streetAddress")
+	if street == "" {
+		street = address.String("name")
+	}
+	return joinNonEmpty(", ", p.String("name"), street, address.String("addressLocality"))
+}
+
+// amount — сумма из PriceSpecification или MonetaryAmount
+func amount(price Item) string {
+	value := firstNonEmpty(price.String("price"), price.String("value"))
+	if value == "" {
+		return ""
+	}
+	return joinNonEmpty(" ", value, firstNonEmpty(price.String("priceCurrency"), price.String("currency")))
+}
+
+func prefixed(prefix, value string) string {
+	if value == "" {
+		return ""
+	}
+	return prefix + value
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var result []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return strings.Join(result, sep)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
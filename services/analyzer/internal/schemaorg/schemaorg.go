@@ -0,0 +1,258 @@
+package schemaorg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Item — объект schema.org. Свойства хранятся как в JSON-LD: строка, вложенный Item или []any,
+// а тип — без префикса http://schema.org/.
+type Item map[string]any
+
+// Extract находит в HTML объекты schema.org верхнего уровня: блоки JSON-LD и разметку microdata.
+// Ошибки разбора отдельных блоков JSON-LD возвращаются, но не мешают остальным.
+func Extract(text string) ([]Item, []error) {
+	if !strings.Contains(text, "schema.org") {
+		return nil, nil
+	}
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil, []error{fmt.Errorf("schemaorg: %w", err)}
+	}
+
+	var items []Item
+	var errs []error
+	var walk func(n *html.Node, current Item)
+	walk = func(n *html.Node, current Item) {
+		if n.Type == html.ElementNode {
+			if n.Data == "script" && strings.EqualFold(attr(n, "type"), "application/ld+json") {
+				found, err := parseJSONLD(textContent(n))
+				if err != nil {
+					errs = append(errs, err)
+				}
+				items = append(items, found...)
+				return
+			}
+
+			_, scoped := attrOK(n, "itemscope")
+			props := strings.Fields(attr(n, "itemprop"))
+			switch {
+			case scoped:
+				child := Item{"@type": typeNames(strings.Fields(attr(n, "itemtype")))}
+				if current != nil && len(props) > 0 {
+					for _, p := range props {
+						current.add(p, child)
+					}
+				} else {
+					items = append(items, child)
+				}
+				current = child
+			case current != nil && len(props) > 0:
+				value := microdataValue(n)
+				for _, p := range props {
+					current.add(p, value)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, current)
+		}
+	}
+	walk(doc, nil)
+	return items, errs
+}
+
+// Types возвращает типы объекта: у одного объекта их может быть несколько
+func (it Item) Types() []string {
+	switch t := it["@type"].(type) {
+	case string:
+		return typeNames([]string{t})
+	case []string:
+		return t
+	case []any:
+		var names []string
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				names = append(names, typeName(s))
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// Is проверяет, что объект относится к одному из типов
+func (it Item) Is(types ...string) bool {
+	for _, t := range it.Types() {
+		for _, want := range types {
+			if t == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String возвращает первое значение свойства строкой. У вложенного объекта берётся его name.
+func (it Item) String(key string) string {
+	switch v := first(it[key]).(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case Item:
+		return v.String("name")
+	}
+	return ""
+}
+
+// Item возвращает вложенный объект. Строка считается объектом с одним name: так часто пишут место или компанию.
+func (it Item) Item(key string) Item {
+	switch v := first(it[key]).(type) {
+	case Item:
+		return v
+	case string:
+		if v = strings.TrimSpace(v); v != "" {
+			return Item{"name": v}
+		}
+	}
+	return nil
+}
+
+func (it Item) add(key string, value any) {
+	existing, ok := it[key]
+	if !ok {
+		it[key] = value
+		return
+	}
+	if list, ok := existing.([]any); ok {
+		it[key] = append(list, value)
+		return
+	}
+	it[key] = []any{existing, value}
+}
+
+// parseJSONLD разбирает блок JSON-LD: один объект, массив объектов или объект с @graph
+func parseJSONLD(text string) ([]Item, error) {
+	var raw any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &raw); err != nil {
+		return nil, fmt.Errorf("schemaorg: json-ld: %w", err)
+	}
+
+	var items []Item
+	var collect func(v any)
+	collect = func(v any) {
+		switch v := normalize(v).(type) {
+		case []any:
+			for _, el := range v {
+				collect(el)
+			}
+		case Item:
+			if graph, ok := v["@graph"]; ok {
+				collect(graph)
+				return
+			}
+			items = append(items, v)
+		}
+	}
+	collect(raw)
+	return items, nil
+}
+
+// normalize превращает вложенные map[string]any из encoding/json в Item
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		item := make(Item, len(v))
+		for k, el := range v {
+			item[k] = normalize(el)
+		}
+		return item
+	case []any:
+		for i, el := range v {
+			v[i] = normalize(el)
+		}
+		return v
+	}
+	return v
+}
+
+func first(v any) any {
+	if list, ok := v.([]any); ok {
+		if len(list) == 0 {
+			return nil
+		}
+		return list[0]
+	}
+	return v
+}
+
+// microdataValue — значение itemprop по правилам HTML: у ссылок адрес, у meta — content, у time — datetime
+func microdataValue(n *html.Node) string {
+	if v, ok := attrOK(n, "content"); ok {
+		return v
+	}
+	switch n.Data {
+	case "a", "link", "area":
+		return attr(n, "href")
+	case "img", "audio", "video", "source", "iframe", "embed":
+		return attr(n, "src")
+	case "time":
+		if v, ok := attrOK(n, "datetime"); ok {
+			return v
+		}
+	case "data", "meter":
+		return attr(n, "value")
+	}
+	return strings.Join(strings.Fields(textContent(n)), " ")
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func attr(n *html.Node, name string) string {
+	v, _ := attrOK(n, name)
+	return v
+}
+
+func attrOK(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val), true
+		}
+	}
+	return "", false
+}
+
+func typeNames(types []string) []string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, typeName(t))
+	}
+	return names
+}
+
+// typeName отрезает пространство имён: http://schema.org/FlightReservation → FlightReservation
+func typeName(t string) string {
+	t = strings.TrimSpace(t)
+	if i := strings.LastIndexAny(t, "/#:"); i >= 0 {
+		return t[i+1:]
+	}
+	return t
+}
@@ -0,0 +1,213 @@
+package schemaorg
+
+import (
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const flightJSONLD = `<html><head>
+<script type="application/ld+json">
+{
+  "@context": "http://schema.org",
+  "@type": "FlightReservation",
+  "reservationNumber": "RXJ34P",
+  "reservationStatus": "http://schema.org/ReservationConfirmed",
+  "underName": {"@type": "Person", "name": "Eva Green"},
+  "reservationFor": {
+    "@type": "Flight",
+    "flightNumber": "1234",
+    "airline": {"@type": "Airline", "name": "Aeroflot", "iataCode": "SU"},
+    "departureAirport": {"@type": "Airport", "name": "Шереметьево", "iataCode": "SVO"},
+    "departureTerminal": "B",
+    "departureTime": "2025-12-11T10:30:00+03:00",
+    "arrivalAirport": {"@type": "Airport", "name": "Пулково", "iataCode": "LED"},
+    "arrivalTime": "2025-12-11T12:00:00+03:00"
+  }
+}
+</script></head><body><p>Ваш рейс</p></body></html>`
+
+const invoiceMicrodata = `<div itemscope itemtype="http://schema.org/Invoice">
+  <div itemprop="provider" itemscope itemtype="http://schema.org/Organization">
+    <span itemprop="name">Мосэнергосбыт</span>
+  </div>
+  <meta itemprop="accountId" content="7700-123">
+  <meta itemprop="confirmationNumber" content="INV-42">
+  <div itemprop="totalPaymentDue" itemscope itemtype="http://schema.org/PriceSpecification">
+    <meta itemprop="price" content="1530.50">
+    <meta itemprop="priceCurrency" content="RUB">
+  </div>
+  <time itemprop="paymentDueDate" datetime="2025-12-20">20 декабря</time>
+  <link itemprop="paymentStatus" href="http://schema.org/PaymentDue">
+</div>`
+
+func rawEmail(html string) models.RawEmail {
+	return models.RawEmail{UserID: "u1", EmailID: "e1", Subject: "Бронирование", HTML: html}
+}
+
+func TestExtract_JSONLDAndMicrodata(t *testing.T) {
+	items, errs := Extract(flightJSONLD + invoiceMicrodata)
+	require.Empty(t, errs)
+	require.Len(t, items, 2)
+
+	assert.True(t, items[0].Is("FlightReservation"))
+	assert.Equal(t, "SVO", items[0].Item("reservationFor").Item("departureAirport").String("iataCode"))
+
+	assert.Equal(t, []string{"Invoice"}, items[1].Types())
+	assert.Equal(t, "Мосэнергосбыт", items[1].Item("provider").String("name"))
+	assert.Equal(t, "1530.50", items[1].Item("totalPaymentDue").String("price"))
+	assert.Equal(t, "2025-12-20", items[1].String("paymentDueDate"))
+}
+
+func TestExtract_GraphAndBrokenBlock(t *testing.T) {
+	text := `<script type="application/ld+json">{"@context":"https://schema.org","@graph":[` +
+		`{"@type":"ParcelDelivery","trackingNumber":"RA123"},{"@type":"Organization","name":"Shop"}]}</script>` +
+		`<script type="application/ld+json">{"@context":"https://schema.org",</script>`
+
+	items, errs := Extract(text)
+
+	assert.Len(t, errs, 1)
+	require.Len(t, items, 2)
+	assert.Equal(t, "RA123", items[0].String("trackingNumber"))
+}
+
+func TestExtract_NoMarkup(t *testing.T) {
+	items, errs := Extract("<p>Привет</p>")
+	assert.Nil(t, items)
+	assert.Nil(t, errs)
+}
+
+func TestBuild_FlightBecomesEvent(t *testing.T) {
+	items, errs := Build(rawEmail(flightJSONLD), time.UTC, "ru")
+	require.Empty(t, errs)
+	require.Len(t, items, 1)
+
+	parsed := items[0]
+	assert.Empty(t, parsed.Title)
+	require.Len(t, parsed.Events, 1)
+	e := parsed.Events[0]
+	assert.Equal(t, "Рейс SU1234 SVO → LED", e.Title)
+	assert.Equal(t, "Шереметьево, терминал B", e.Location)
+	assert.Equal(t, "2025-12-11T07:30:00Z", e.Start.UTC().Format(time.RFC3339))
+	require.NotNil(t, e.End)
+	assert.Equal(t, 90*time.Minute, e.End.Sub(e.Start))
+	assert.Equal(t, "schemaorg:FlightReservation:RXJ34P:SU1234:SVO", e.UID)
+	assert.Empty(t, e.Status)
+}
+
+func TestBuild_InvoiceBecomesTask(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	items, errs := Build(rawEmail(invoiceMicrodata), moscow, "ru")
+	require.Empty(t, errs)
+	require.Len(t, items, 1)
+
+	parsed := items[0]
+	assert.Equal(t, "Оплатить счёт Мосэнергосбыт", parsed.Title)
+	assert.Equal(t, "Сумма: 1530.50 RUB\nЛицевой счёт: 7700-123", parsed.Description)
+	assert.Equal(t, "2025-12-20T00:00:00+03:00", parsed.Deadline.Format(time.RFC3339))
+	assert.Equal(t, "schemaorg:Invoice:Мосэнергосбыт:INV-42", parsed.CalendarUID)
+	assert.Empty(t, parsed.Status)
+}
+
+func TestBuild_Language(t *testing.T) {
+	items, errs := Build(rawEmail(invoiceMicrodata), time.UTC, "en")
+	require.Empty(t, errs)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Pay invoice Мосэнергосбыт", items[0].Title)
+	assert.Equal(t, "Amount: 1530.50 RUB\nAccount: 7700-123", items[0].Description)
+	// UID не зависит от языка, чтобы смена настройки не создала дубль задачи
+	assert.Equal(t, "schemaorg:Invoice:Мосэнергосбыт:INV-42", items[0].CalendarUID)
+}
+
+func TestBuild_OrderDeliveryAndStatuses(t *testing.T) {
+	text := `<script type="application/ld+json">[
+	{"@context":"http://schema.org","@type":"Order","orderStatus":"http://schema.org/OrderDelivered",
+	 "orderDelivery":{"trackingNumber":"RA123","carrier":"Почта России","expectedArrivalUntil":"2025-12-15T18:00"}},
+	{"@context":"http://schema.org","@type":"LodgingReservation","reservationStatus":"http://schema.org/ReservationCancelled",
+	 "reservationNumber":"H-9","reservationFor":{"@type":"LodgingBusiness","name":"Отель Нева"}},
+	{"@context":"http://schema.org","@type":"EventReservation",
+	 "reservationFor":{"@type":"Event","name":"Концерт","startDate":"2025-12-20"}}
+	]</script>`
+
+	items, errs := Build(rawEmail(text), time.UTC, "ru")
+	require.Empty(t, errs)
+	require.Len(t, items, 1)
+
+	parsed := items[0]
+	assert.Equal(t, "Получить посылку", parsed.Title)
+	assert.Equal(t, "Перевозчик: Почта России\nТрек-номер: RA123", parsed.Description)
+	assert.Equal(t, "schemaorg:ParcelDelivery:RA123", parsed.CalendarUID)
+	assert.Equal(t, models.StatusCompleted, parsed.Status)
+	assert.Equal(t, "2025-12-15T18:00:00Z", parsed.Deadline.Format(time.RFC3339))
+
+	require.Len(t, parsed.Events, 2)
+	assert.Equal(t, "Заселение: Отель Нева", parsed.Events[0].Title)
+	assert.Equal(t, models.StatusCancelled, parsed.Events[0].Status)
+	assert.Equal(t, "Концерт", parsed.Events[1].Title)
+	assert.True(t, parsed.Events[1].AllDay)
+	assert.Empty(t, parsed.Events[1].UID)
+}
+
+func TestBuild_ItemsWithoutNumbers(t *testing.T) {
+	text := `<script type="application/ld+json">[
+	{"@context":"http://schema.org","@type":"Order","orderNumber":"A-1",
+	 "orderDelivery":{"itemShipped":{"name":"Чайник"},"expectedArrivalUntil":"2025-12-15"}},
+	{"@context":"http://schema.org","@type":"Order","orderNumber":"A-2",
+	 "orderDelivery":{"itemShipped":{"name":"Чайник"},"expectedArrivalUntil":"2025-12-16"}},
+	{"@context":"http://schema.org","@type":"Invoice","provider":{"name":"Водоканал"},"paymentDueDate":"2025-12-20"},
+	{"@context":"http://schema.org","@type":"Invoice","provider":{"name":"Газ"},"paymentDueDate":"2025-12-20"}
+	]</script>`
+
+	items, errs := Build(rawEmail(text), time.UTC, "ru")
+	require.Empty(t, errs)
+	require.Len(t, items, 4)
+
+	// Доставки различает номер заказа, счета без номеров — UID в пределах письма
+	assert.Equal(t, "schemaorg:ParcelDelivery:order:A-1", items[0].CalendarUID)
+	assert.Equal(t, "schemaorg:ParcelDelivery:order:A-2", items[1].CalendarUID)
+	assert.Equal(t, "schemaorg:Invoice:email:e1:Оплатить счёт Водоканал:2025-12-20", items[2].CalendarUID)
+	assert.Equal(t, "schemaorg:Invoice:email:e1:Оплатить счёт Газ:2025-12-20", items[3].CalendarUID)
+}
+
+func TestBuild_UnusableMarkup(t *testing.T) {
+	text := `<script type="application/ld+json">{"@context":"http://schema.org","@type":"FlightReservation",` +
+		`"reservationFor":{"flightNumber":"1"}}</script>` +
+		`<div itemscope itemtype="http://schema.org/Organization"><span itemprop="name">Shop</span></div>`
+
+	items, errs := Build(rawEmail(text), time.UTC, "ru")
+
+	assert.Nil(t, items)
+	assert.Len(t, errs, 1)
+}
+
+func TestParseTime(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	cases := []struct {
+		value  string
+		want   string
+		allDay bool
+	}{
+		{"2025-12-11T10:30:00Z", "2025-12-11T10:30:00Z", false},
+		{"2025-12-11T10:30+05:00", "2025-12-11T10:30:00+05:00", false},
+		{"2025-12-11T10:30:00+0500", "2025-12-11T10:30:00+05:00", false},
+		{"2025-12-11T10:30", "2025-12-11T10:30:00+03:00", false},
+		{"2025-12-11", "2025-12-11T00:00:00+03:00", true},
+	}
+	for _, tc := range cases {
+		got, allDay, err := parseTime(tc.value, moscow)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.want, got.Format(time.RFC3339), tc.value)
+		assert.Equal(t, tc.allDay, allDay, tc.value)
+	}
+
+	_, _, err = parseTime("завтра", moscow)
+	assert.Error(t, err)
+}
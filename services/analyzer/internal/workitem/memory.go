@@ -0,0 +1,112 @@
+package workitem
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryBackend хранит состояния в памяти процесса, когда Redis недоступен
+type memoryBackend struct {
+	mu      sync.Mutex
+	hashes  map[string]map[string]string
+	values  map[string]string
+	expires map[string]time.Time
+	now     func() time.Time
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		hashes:  make(map[string]map[string]string),
+		values:  make(map[string]string),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (m *memoryBackend) HSet(_ context.Context, key string, values map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hash := m.hash(key)
+	for field, value := range values {
+		hash[field] = value
+	}
+	return nil
+}
+
+func (m *memoryBackend) HIncrBy(_ context.Context, key, field string, incr int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hash := m.hash(key)
+	current, _ := strconv.ParseInt(hash[field], 10, 64)
+	hash[field] = strconv.FormatInt(current+incr, 10)
+	return nil
+}
+
+func (m *memoryBackend) HGetAll(_ context.Context, key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(key)
+	fields := make(map[string]string, len(m.hashes[key]))
+	for field, value := range m.hashes[key] {
+		fields[field] = value
+	}
+	return fields, nil
+}
+
+func (m *memoryBackend) Expire(_ context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expires[key] = m.now().Add(ttl)
+	for k := range m.expires {
+		m.evict(k)
+	}
+	return nil
+}
+
+func (m *memoryBackend) SetNX(_ context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(key)
+	if _, ok := m.values[key]; ok {
+		return false, nil
+	}
+	m.values[key] = fmt.Sprint(value)
+	if ttl > 0 {
+		m.expires[key] = m.now().Add(ttl)
+	}
+	return true, nil
+}
+
+func (m *memoryBackend) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.hashes, key)
+	delete(m.values, key)
+	delete(m.expires, key)
+	return nil
+}
+
+func (m *memoryBackend) hash(key string) map[string]string {
+	m.evict(key)
+	if m.hashes[key] == nil {
+		m.hashes[key] = make(map[string]string)
+	}
+	return m.hashes[key]
+}
+
+func (m *memoryBackend) evict(key string) {
+	if deadline, ok := m.expires[key]; ok && m.now().After(deadline) {
+		delete(m.hashes, key)
+		delete(m.values, key)
+		delete(m.expires, key)
+	}
+}
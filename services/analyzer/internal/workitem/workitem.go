@@ -0,0 +1,197 @@
+package workitem
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/models"
+	"reminder-hub/pkg/redis"
+)
+
+const keyPrefix = "analyzer:work:"
+
+// Состояния элемента работы — одного письма из пачки
+const (
+	StatusProcessing = "processing"
+	StatusDone       = "done"
+	StatusFailed     = "failed"
+)
+
+var ErrNotFound = errors.New("work item not found")
+
+type Config struct {
+	// Сколько помнить обработанные письма; повторная доставка в этом окне не публикует задачу заново
+	TTL time.Duration `env:"TTL" env-default:"168h"`
+	// Сколько письмо остаётся за взявшим его обработчиком. Должно быть больше самой долгой обработки
+	// с повторами модели: по истечении письмо может взять другой обработчик, если первый упал.
+	LeaseTTL time.Duration `env:"LEASE_TTL" env-default:"10m"`
+}
+
+// Backend — подмножество pkg/redis.Client, которое нужно хранилищу состояний
+type Backend interface {
+	HSet(ctx context.Context, key string, values map[string]string) error
+	HIncrBy(ctx context.Context, key, field string, incr int64) error
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type State struct {
+	EmailID   string    `json:"email_id"`
+	UserID    string    `json:"user_id"`
+	BatchID   string    `json:"batch_id,omitempty"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store хранит состояние обработки каждого письма
+type Store struct {
+	cfg     *Config
+	backend Backend
+	log     *logger.CurrentLogger
+	now     func() time.Time
+}
+
+func New(cfg *Config, backend Backend, log *logger.CurrentLogger) *Store {
+	return &Store{cfg: cfg, backend: backend, log: log, now: time.Now}
+}
+
+// NewStore хранит состояния в Redis, а без него — в памяти процесса
+func NewStore(ctx context.Context, cfg *Config, client *redis.Client, log *logger.CurrentLogger) *Store {
+	if client == nil {
+		log.Warn(ctx, "Work item states are kept in memory without Redis")
+		return New(cfg, newMemoryBackend(), log)
+	}
+	return New(cfg, client, log)
+}
+
+// Completed сообщает, что письмо уже обработано. Если хранилище недоступно, письмо обрабатывается заново:
+// лучше повторить задачу, чем потерять её.
+func (s *Store) Completed(ctx context.Context, emailID string) bool {
+	if s == nil {
+		return false
+	}
+
+	fields, err := s.backend.HGetAll(ctx, key(emailID))
+	if err != nil {
+		s.log.Warn(ctx, "Failed to read work item state", "error", err, "email_id", emailID)
+		return false
+	}
+	return fields["status"] == StatusDone
+}
+
+// Begin забирает письмо в обработку и возвращает номер попытки. false — письмо уже обработано или его
+// прямо сейчас обрабатывает копия того же сообщения: проверка и захват атомарны, поэтому две копии
+// не вызовут модель дважды. Если хранилище недоступно, письмо обрабатывается: лучше повторить задачу,
+// чем потерять её.
+func (s *Store) Begin(ctx context.Context, item models.EmailWorkItem) (int, bool) {
+	if s == nil {
+		return 0, true
+	}
+
+	emailID := item.Email.EmailID
+	claimed, err := s.backend.SetNX(ctx, leaseKey(emailID), s.now().UTC().Format(time.RFC3339), s.cfg.LeaseTTL)
+	if err != nil {
+		s.log.Warn(ctx, "Failed to claim work item", "error", err, "email_id", emailID)
+	} else if !claimed {
+		return 0, false
+	}
+	// Копия могла закончить обработку между проверкой Completed и захватом
+	if s.Completed(ctx, emailID) {
+		s.release(ctx, emailID)
+		return 0, false
+	}
+
+	k := key(emailID)
+	if err := s.backend.HIncrBy(ctx, k, "attempts", 1); err != nil {
+		s.log.Warn(ctx, "Failed to count work item attempt", "error", err, "email_id", item.Email.EmailID)
+	}
+	s.set(ctx, item.Email.EmailID, map[string]string{
+		"user_id":  item.Email.UserID,
+		"batch_id": item.BatchID,
+		"status":   StatusProcessing,
+	})
+
+	fields, err := s.backend.HGetAll(ctx, k)
+	if err != nil {
+		return 0, true
+	}
+	attempts, _ := strconv.Atoi(fields["attempts"])
+	return attempts, true
+}
+
+// Succeed и Fail сохраняют итог попытки и освобождают письмо: статус записывается раньше,
+// чтобы следующий захват уже видел done
+func (s *Store) Succeed(ctx context.Context, emailID string) {
+	if s == nil {
+		return
+	}
+	s.set(ctx, emailID, map[string]string{"status": StatusDone, "last_error": ""})
+	s.release(ctx, emailID)
+}
+
+func (s *Store) Fail(ctx context.Context, emailID string, cause error) {
+	if s == nil {
+		return
+	}
+	s.set(ctx, emailID, map[string]string{"status": StatusFailed, "last_error": cause.Error()})
+	s.release(ctx, emailID)
+}
+
+func (s *Store) release(ctx context.Context, emailID string) {
+	if err := s.backend.Delete(ctx, leaseKey(emailID)); err != nil {
+		s.log.Warn(ctx, "Failed to release work item", "error", err, "email_id", emailID)
+	}
+}
+
+func (s *Store) Get(ctx context.Context, emailID string) (*State, error) {
+	if s == nil {
+		return nil, ErrNotFound
+	}
+
+	fields, err := s.backend.HGetAll(ctx, key(emailID))
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+
+	attempts, _ := strconv.Atoi(fields["attempts"])
+	updatedAt, _ := time.Parse(time.RFC3339, fields["updated_at"])
+	return &State{
+		EmailID:   emailID,
+		UserID:    fields["user_id"],
+		BatchID:   fields["batch_id"],
+		Status:    fields["status"],
+		Attempts:  attempts,
+		LastError: fields["last_error"],
+		UpdatedAt: updatedAt,
+	}, nil
+}
+
+func (s *Store) set(ctx context.Context, emailID string, values map[string]string) {
+	values["updated_at"] = s.now().UTC().Format(time.RFC3339)
+	k := key(emailID)
+	if err := s.backend.HSet(ctx, k, values); err != nil {
+		s.log.Warn(ctx, "Failed to save work item state", "error", err, "email_id", emailID)
+		return
+	}
+	if err := s.backend.Expire(ctx, k, s.cfg.TTL); err != nil {
+		s.log.Warn(ctx, "Failed to set work item TTL", "error", err, "email_id", emailID)
+	}
+}
+
+func key(emailID string) string {
+	return keyPrefix + emailID
+}
+
+func leaseKey(emailID string) string {
+	return keyPrefix + emailID + ":lease"
+}
@@ -0,0 +1,136 @@
+package workitem
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/logger/zaplogger"
+	"reminder-hub/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+)
+
+type simpleLifecycle struct{}
+
+func (s *simpleLifecycle) Append(hook fx.Hook) {}
+
+func testLogger() *logger.CurrentLogger {
+	return logger.NewCurrentLogger(zaplogger.NewLoggerAdapter(&simpleLifecycle{}, "test"))
+}
+
+func testItem(emailID string) models.EmailWorkItem {
+	return models.EmailWorkItem{Email: models.RawEmail{EmailID: emailID, UserID: "user-1"}, BatchID: "batch-1"}
+}
+
+func TestStore_FailThenSucceed(t *testing.T) {
+	store := New(&Config{TTL: time.Hour}, newMemoryBackend(), testLogger())
+	ctx := context.Background()
+
+	attempt, claimed := store.Begin(ctx, testItem("email-1"))
+	assert.True(t, claimed)
+	assert.Equal(t, 1, attempt)
+	store.Fail(ctx, "email-1", errors.New("mistral timeout"))
+	assert.False(t, store.Completed(ctx, "email-1"))
+
+	state, err := store.Get(ctx, "email-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, state.Status)
+	assert.Equal(t, "mistral timeout", state.LastError)
+
+	attempt, claimed = store.Begin(ctx, testItem("email-1"))
+	assert.True(t, claimed)
+	assert.Equal(t, 2, attempt)
+	store.Succeed(ctx, "email-1")
+	assert.True(t, store.Completed(ctx, "email-1"))
+
+	state, err = store.Get(ctx, "email-1")
+	require.NoError(t, err)
+	assert.Equal(t, State{
+		EmailID:   "email-1",
+		UserID:    "user-1",
+		BatchID:   "batch-1",
+		Status:    StatusDone,
+		Attempts:  2,
+		UpdatedAt: state.UpdatedAt,
+	}, *state)
+}
+
+func TestStore_BeginClaimsOnce(t *testing.T) {
+	now := time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC)
+	backend := newMemoryBackend()
+	backend.now = func() time.Time { return now }
+	store := New(&Config{TTL: time.Hour, LeaseTTL: 10 * time.Minute}, backend, testLogger())
+	ctx := context.Background()
+
+	// Копия письма из повторённой пачки не берётся, пока первая в обработке
+	_, claimed := store.Begin(ctx, testItem("email-1"))
+	assert.True(t, claimed)
+	_, claimed = store.Begin(ctx, testItem("email-1"))
+	assert.False(t, claimed)
+
+	// Обработчик упал, не записав итог: после истечения аренды письмо берёт другой
+	now = now.Add(11 * time.Minute)
+	attempt, claimed := store.Begin(ctx, testItem("email-1"))
+	assert.True(t, claimed)
+	assert.Equal(t, 2, attempt)
+
+	// Обработанное письмо больше не берётся
+	store.Succeed(ctx, "email-1")
+	_, claimed = store.Begin(ctx, testItem("email-1"))
+	assert.False(t, claimed)
+}
+
+func TestStore_BeginConcurrentCopies(t *testing.T) {
+	store := New(&Config{TTL: time.Hour, LeaseTTL: time.Minute}, newMemoryBackend(), testLogger())
+	ctx := context.Background()
+
+	var claims atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, claimed := store.Begin(ctx, testItem("email-1")); claimed {
+				claims.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), claims.Load())
+}
+
+func TestStore_ForgetsAfterTTL(t *testing.T) {
+	now := time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC)
+	backend := newMemoryBackend()
+	backend.now = func() time.Time { return now }
+	store := New(&Config{TTL: time.Hour}, backend, testLogger())
+	ctx := context.Background()
+
+	store.Begin(ctx, testItem("email-1"))
+	store.Succeed(ctx, "email-1")
+	now = now.Add(2 * time.Hour)
+
+	assert.False(t, store.Completed(ctx, "email-1"))
+	_, err := store.Get(ctx, "email-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_NilIsNoop(t *testing.T) {
+	var store *Store
+	ctx := context.Background()
+
+	assert.False(t, store.Completed(ctx, "email-1"))
+	attempt, claimed := store.Begin(ctx, testItem("email-1"))
+	assert.True(t, claimed)
+	assert.Equal(t, 0, attempt)
+	store.Succeed(ctx, "email-1")
+	_, err := store.Get(ctx, "email-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -43,6 +43,11 @@ func main() {
 		log.Fatalf("Failed to create collector proxy: %v", err)
 	}
 
+	analyzerProxy, err := proxy.NewServiceProxy(cfg.AnalyzerServiceURL, cfg.InternalToken, cfg.Logger)
+	if err != nil {
+		log.Fatalf("Failed to create analyzer proxy: %v", err)
+	}
+
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(200, map[string]interface{}{
 			"status":  "healthy",
@@ -65,6 +70,17 @@ func main() {
 		reminders := api.Group("/reminders")
 		reminders.Any("", collectorProxy.Proxy)
 		reminders.Any("/*", collectorProxy.Proxy)
+
+		// События (встречи, созвоны) отдаются collector'ом как есть, без переписывания пути
+		events := api.Group("/events")
+		events.Any("", collectorProxy.Proxy)
+		events.Any("/*", collectorProxy.Proxy)
+
+		// Итог обработки письма: ярлык классификатора и почему из письма не появилась задача
+		api.GET("/emails/:email_id/outcome", collectorProxy.Proxy)
+
+		// Превью разбора письма и быстрое добавление задачи: ответ сразу, без очереди
+		api.POST("/analyze", analyzerProxy.Proxy)
 	}
 
 	internal := e.Group("/internal")
@@ -14,6 +14,7 @@ type Config struct {
 	AuthServiceURL      string
 	CoreServiceURL      string
 	CollectorServiceURL string
+	AnalyzerServiceURL  string
 	InternalToken       string
 	JWTSecret           string
 	Logger              *logger.CurrentLogger
@@ -32,6 +33,7 @@ func Load() (*Config, error) {
 		AuthServiceURL:      getEnv("AUTH_SERVICE_URL", "http://auth-service:8081"),
 		CoreServiceURL:      getEnv("CORE_SERVICE_URL", "http://core-service:8082"),
 		CollectorServiceURL: getEnv("COLLECTOR_SERVICE_URL", "http://collector-service:8083"),
+		AnalyzerServiceURL:  getEnv("ANALYZER_SERVICE_URL", "http://analyzer-service:5000"),
 		InternalToken:       getEnv("INTERNAL_API_TOKEN", "gateway-secret-token"),
 		JWTSecret:           getEnv("JWT_SECRET", "your-jwt-secret-key"),
 		Logger:              logger.NewCurrentLogger(adapter),
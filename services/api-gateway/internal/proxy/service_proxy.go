@@ -30,6 +30,8 @@ func NewServiceProxy(targetURL, internalToken string, log *logger.CurrentLogger)
 	serviceType := "core"
 	if strings.Contains(targetURL, "collector") {
 		serviceType = "collector"
+	} else if strings.Contains(targetURL, "analyzer") {
+		serviceType = "analyzer"
 	}
 
 	return &ServiceProxy{
@@ -72,12 +74,17 @@ func (p *ServiceProxy) Proxy(c echo.Context) error {
 
 		// Переписываем путь для reminders -> tasks (только для collector-service)
 		if p.serviceType == "collector" && strings.HasPrefix(requestPath, "/api/v1/reminders") {
-			// Заменяем /api/v1/reminders на /api/v1/tasks
-			// Убираем /reminders из пути
-			req.URL.Path = "/api/v1/tasks"
+			// Заменяем /api/v1/reminders на /api/v1/tasks, сохраняя остаток пути (/:id, /review и т.п.)
+			req.URL.Path = "/api/v1/tasks" + strings.TrimSuffix(strings.TrimPrefix(requestPath, "/api/v1/reminders"), "/")
 			p.logger.Debug(ctx, "Rewritten path for reminders", "from", requestPath, "to", req.URL.Path)
 		}
 
+		// Синхронный разбор письма живёт в analyzer-service под его собственным префиксом
+		if p.serviceType == "analyzer" && strings.HasPrefix(requestPath, "/api/v1/analyze") {
+			req.URL.Path = "/analyzer/v1/analyze"
+			p.logger.Debug(ctx, "Rewritten path for analyze", "from", requestPath, "to", req.URL.Path)
+		}
+
 		// Копируем body из модифицированного запроса (после middleware)
 		// Это важно, если middleware изменил body (например, AutoIMAPMiddleware)
 		if c.Request().Body != nil && (c.Request().Method == http.MethodPost || c.Request().Method == http.MethodPut || c.Request().Method == http.MethodPatch) {
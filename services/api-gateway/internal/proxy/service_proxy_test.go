@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"reminder-hub/pkg/logger"
@@ -33,6 +34,11 @@ func TestNewServiceProxy_ServiceTypeDetection(t *testing.T) {
 	if err != nil || p.serviceType != "collector" {
 		t.Fatalf("collector serviceType=%q err=%v", p.serviceType, err)
 	}
+
+	p, err = NewServiceProxy("http://analyzer-service:5000", "token", log)
+	if err != nil || p.serviceType != "analyzer" {
+		t.Fatalf("analyzer serviceType=%q err=%v", p.serviceType, err)
+	}
 }
 
 func TestAuthProxy_InvalidURL(t *testing.T) {
@@ -90,3 +96,63 @@ func TestServiceProxy_ProxySetsHeaders(t *testing.T) {
 		t.Fatalf("Proxy error: %v", err)
 	}
 }
+
+func TestServiceProxy_RewritesReminderPaths(t *testing.T) {
+	tests := map[string]string{
+		"/api/v1/reminders":             "/api/v1/tasks",
+		"/api/v1/reminders/":            "/api/v1/tasks",
+		"/api/v1/reminders/review":      "/api/v1/tasks/review",
+		"/api/v1/reminders/42/complete": "/api/v1/tasks/42/complete",
+	}
+
+	for path, want := range tests {
+		var got string
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.URL.Path
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		p, err := NewServiceProxy(backend.URL, "internal", newTestLogger())
+		if err != nil {
+			t.Fatalf("NewServiceProxy error: %v", err)
+		}
+		p.serviceType = "collector"
+
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, path, nil), httptest.NewRecorder())
+		if err := p.Proxy(c); err != nil {
+			t.Fatalf("Proxy error: %v", err)
+		}
+		backend.Close()
+
+		if got != want {
+			t.Errorf("%s proxied to %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestServiceProxy_RewritesAnalyzePath(t *testing.T) {
+	var gotPath, gotUser string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotUser = r.URL.Path, r.Header.Get("X-User-ID")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	p, err := NewServiceProxy(backend.URL, "internal", newTestLogger())
+	if err != nil {
+		t.Fatalf("NewServiceProxy error: %v", err)
+	}
+	p.serviceType = "analyzer"
+
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/v1/analyze", strings.NewReader(`{"body":"завтра"}`)), httptest.NewRecorder())
+	c.Set("user_id", "u1")
+	if err := p.Proxy(c); err != nil {
+		t.Fatalf("Proxy error: %v", err)
+	}
+
+	if gotPath != "/analyzer/v1/analyze" || gotUser != "u1" {
+		t.Errorf("proxied to %s as %q, want /analyzer/v1/analyze as u1", gotPath, gotUser)
+	}
+}
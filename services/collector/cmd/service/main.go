@@ -10,6 +10,7 @@ import (
 	"collector/internal/api"
 	"collector/internal/config"
 	"collector/internal/database"
+	"collector/internal/priority"
 	"collector/internal/rabbitmq"
 	"collector/internal/service"
 	"collector/logger"
@@ -69,14 +70,38 @@ func main() {
 	}
 	log.Info().Msg("Migrations completed")
 
-	service := service.NewTaskService(db)
-	rabbit, err := rabbitmq.NewConsumer(cfg.RabbitURL, cfg.QueueName, service.HandleEmailMessage)
+	// Сервис публикует исправления через соединение консьюмера, поэтому создаётся после него;
+	// сообщения начинают обрабатываться только после rabbit.Start
+	var taskService *service.TaskService
+	retryPolicy := rabbitmq.RetryPolicy{MaxAttempts: cfg.MaxAttempts, Delay: cfg.RetryDelay, MaxDelay: cfg.MaxRetryDelay}
+	rabbit, err := rabbitmq.NewConsumer(cfg.RabbitURL, cfg.QueueName, retryPolicy, func(ctx context.Context, body []byte) error {
+		return taskService.HandleEmailMessage(ctx, body)
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to RabbitMQ")
 	}
 	defer rabbit.Close()
 	log.Info().Msg("RabbitMQ connected")
 
+	// Предложения закрыть задачу и итоги обработки писем читаются через то же соединение
+	completions, err := rabbitmq.Subscribe(rabbit.Connection(), "task_completion", cfg.CompletionQueueName, retryPolicy, func(ctx context.Context, body []byte) error {
+		return taskService.HandleCompletionMessage(ctx, body)
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to subscribe to task completions")
+	}
+	defer completions.Close()
+
+	outcomes, err := rabbitmq.Subscribe(rabbit.Connection(), "email_processed", cfg.OutcomeQueueName, retryPolicy, func(ctx context.Context, body []byte) error {
+		return taskService.HandleOutcomeMessage(ctx, body)
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to subscribe to email outcomes")
+	}
+	defer outcomes.Close()
+
+	taskService = service.NewTaskService(db, priority.New(cfg.Priority), rabbitmq.NewPublisher(rabbit.Connection()), cfg.CompletionAutoThreshold)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -85,11 +110,21 @@ func main() {
 			log.Error().Err(err).Msg("RabbitMQ consumer stopped with error")
 		}
 	}()
+	go func() {
+		if err := completions.Start(ctx); err != nil && err != context.Canceled {
+			log.Error().Err(err).Msg("Task completion consumer stopped with error")
+		}
+	}()
+	go func() {
+		if err := outcomes.Start(ctx); err != nil && err != context.Canceled {
+			log.Error().Err(err).Msg("Email outcome consumer stopped with error")
+		}
+	}()
 	log.Info().Msg("RabbitMQ consumer started")
 
 	e := echo.New()
 
-	api.SetupRoutes(e, service, cfg.InternalAPIToken)
+	api.SetupRoutes(e, taskService, rabbitmq.NewDeadLetters(rabbit.Connection()), cfg.InternalAPIToken)
 
 	go func() {
 		if err := e.Start(":" + cfg.ServerPort); err != nil {
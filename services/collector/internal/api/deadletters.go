@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"collector/internal/rabbitmq"
+	"github.com/labstack/echo/v4"
+)
+
+type DeadLetterHandler struct {
+	deadLetters *rabbitmq.DeadLetters
+}
+
+func NewDeadLetterHandler(deadLetters *rabbitmq.DeadLetters) *DeadLetterHandler {
+	return &DeadLetterHandler{deadLetters: deadLetters}
+}
+
+// List возвращает мёртвые письма очереди; limit по умолчанию 50
+func (h *DeadLetterHandler) List(c echo.Context) error {
+	limit := 50
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a positive number"})
+		}
+		limit = parsed
+	}
+
+	letters, err := h.deadLetters.List(c.Param("queue"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+	if letters == nil {
+		letters = []rabbitmq.DeadLetter{}
+	}
+	return c.JSON(http.StatusOK, letters)
+}
+
+func (h *DeadLetterHandler) Get(c echo.Context) error {
+	letter, err := h.deadLetters.Get(c.Param("queue"), c.Param("message_id"))
+	if err != nil {
+		return deadLetterError(c, err)
+	}
+	return c.JSON(http.StatusOK, letter)
+}
+
+// Requeue возвращает одно письмо (или все, если message_id не указан) в очередь консьюмера
+func (h *DeadLetterHandler) Requeue(c echo.Context) error {
+	count, err := h.deadLetters.Requeue(c.Param("queue"), c.Param("message_id"))
+	if err != nil {
+		return deadLetterError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]int{"count": count})
+}
+
+// Purge удаляет одно письмо (или очищает очередь, если message_id не указан)
+func (h *DeadLetterHandler) Purge(c echo.Context) error {
+	count, err := h.deadLetters.Purge(c.Param("queue"), c.Param("message_id"))
+	if err != nil {
+		return deadLetterError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]int{"count": count})
+}
+
+func deadLetterError(c echo.Context, err error) error {
+	if errors.Is(err, rabbitmq.ErrDeadLetterNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Dead letter not found"})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"collector/internal/database"
+	"collector/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type EventHandler struct {
+	service *service.TaskService
+}
+
+func NewEventHandler(service *service.TaskService) *EventHandler {
+	return &EventHandler{service: service}
+}
+
+func (h *EventHandler) GetEvents(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userID := c.Get(ContextKeyUserID).(string)
+
+	events, err := h.service.GetUserEvents(ctx, parseEventFilter(c, userID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+	if events == nil {
+		events = []database.Event{}
+	}
+
+	zone := c.Request().Header.Get(HeaderUserTimezone)
+	for i := range events {
+		service.RenderEventInZone(&events[i], zone)
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
+
+func (h *EventHandler) GetEvent(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	eventID := c.Param("id")
+	userID := c.Get(ContextKeyUserID).(string)
+
+	event, err := h.service.GetEvent(ctx, eventID, userID)
+	if err != nil {
+		return eventError(c, err)
+	}
+
+	service.RenderEventInZone(event, c.Request().Header.Get(HeaderUserTimezone))
+
+	return c.JSON(http.StatusOK, event)
+}
+
+func (h *EventHandler) DeleteEvent(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	eventID := c.Param("id")
+	userID := c.Get(ContextKeyUserID).(string)
+
+	if err := h.service.DeleteEvent(ctx, eventID, userID); err != nil {
+		return eventError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Event deleted successfully"})
+}
+
+func eventError(c echo.Context, err error) error {
+	if errors.Is(err, database.ErrEventNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Event not found"})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+}
+
+// parseEventFilter разбирает status, from/to (RFC3339), limit и offset; некорректные значения игнорируются
+func parseEventFilter(c echo.Context, userID string) database.EventFilter {
+	filter := database.EventFilter{
+		UserID: userID,
+		Limit:  50,
+	}
+
+	if status := c.QueryParam("status"); status != "" {
+		filter.Status = &status
+	}
+
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		if from, err := time.Parse(time.RFC3339, fromStr); err == nil {
+			filter.From = &from
+		}
+	}
+
+	if toStr := c.QueryParam("to"); toStr != "" {
+		if to, err := time.Parse(time.RFC3339, toStr); err == nil {
+			filter.To = &to
+		}
+	}
+
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
+			filter.Limit = limit
+		}
+	}
+
+	if offsetStr := c.QueryParam("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
+			filter.Offset = offset
+		}
+	}
+
+	return filter
+}
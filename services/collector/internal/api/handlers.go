@@ -36,6 +36,8 @@ func (h *TaskHandler) GetTask(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
 	}
 
+	service.RenderInZone(task, c.Request().Header.Get(HeaderUserTimezone))
+
 	return c.JSON(http.StatusOK, task)
 }
 
@@ -51,6 +53,11 @@ func (h *TaskHandler) GetTasks(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
 	}
 
+	zone := c.Request().Header.Get(HeaderUserTimezone)
+	for i := range tasks {
+		service.RenderInZone(&tasks[i], zone)
+	}
+
 	return c.JSON(http.StatusOK, tasks)
 }
 
@@ -112,6 +119,54 @@ func (h *TaskHandler) CompleteTask(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"message": "Task completed successfully"})
 }
 
+// GetReviewQueue — задачи, которые нужно подтвердить, исправить или отклонить
+func (h *TaskHandler) GetReviewQueue(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userID := c.Get(ContextKeyUserID).(string)
+
+	tasks, err := h.service.GetReviewQueue(ctx, userID, parseTaskFilter(c, userID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	zone := c.Request().Header.Get(HeaderUserTimezone)
+	for i := range tasks {
+		service.RenderInZone(&tasks[i], zone)
+	}
+
+	return c.JSON(http.StatusOK, tasks)
+}
+
+func (h *TaskHandler) ReviewTask(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	taskID := c.Param("id")
+	userID := c.Get(ContextKeyUserID).(string)
+
+	var review database.ReviewRequest
+	if err := c.Bind(&review); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(review); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := h.service.ReviewTask(ctx, taskID, userID, review); err != nil {
+		if errors.Is(err, database.ErrTaskNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+		}
+		if errors.Is(err, database.ErrTaskNotInReview) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Task is not waiting for review"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Task reviewed successfully"})
+}
+
 func (h *TaskHandler) GetStats(c echo.Context) error {
 	ctx := c.Request().Context()
 
@@ -125,6 +180,37 @@ func (h *TaskHandler) GetStats(c echo.Context) error {
 	return c.JSON(http.StatusOK, stats)
 }
 
+func (h *TaskHandler) GetTags(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userID := c.Get(ContextKeyUserID).(string)
+
+	tags, err := h.service.GetUserTags(ctx, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+// GetEmailOutcome — ярлык классификатора и итог обработки письма: извлечена задача или письмо пропущено и почему
+func (h *TaskHandler) GetEmailOutcome(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	emailID := c.Param("email_id")
+	userID := c.Get(ContextKeyUserID).(string)
+
+	outcome, err := h.service.GetEmailOutcome(ctx, userID, emailID)
+	if err != nil {
+		if errors.Is(err, database.ErrOutcomeNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Email outcome not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, outcome)
+}
+
 func (h *TaskHandler) HealthCheck(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{
 		"status":  "ok",
@@ -147,6 +233,13 @@ func parseTaskFilter(c echo.Context, userID string) database.TaskFilter {
 		filter.Priority = &priority
 	}
 
+	if category := c.QueryParam("category"); category != "" {
+		filter.Category = &category
+	}
+
+	// ?tag=a&tag=b — задачи, у которых есть все перечисленные теги
+	filter.Tags = c.QueryParams()["tag"]
+
 	if fromStr := c.QueryParam("from_deadline"); fromStr != "" {
 		if from, err := time.Parse(time.RFC3339, fromStr); err == nil {
 			filter.FromDeadline = &from
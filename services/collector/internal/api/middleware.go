@@ -6,7 +6,10 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-const ContextKeyUserID = "user_id"
+const (
+	ContextKeyUserID   = "user_id"
+	HeaderUserTimezone = "X-User-Timezone"
+)
 
 func UserIDAuth(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
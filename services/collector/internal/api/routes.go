@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"collector/internal/api/response"
+	"collector/internal/rabbitmq"
 	"collector/internal/service"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -19,7 +20,7 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 	return cv.validator.Struct(i)
 }
 
-func SetupRoutes(e *echo.Echo, taskService *service.TaskService, internalToken string) {
+func SetupRoutes(e *echo.Echo, taskService *service.TaskService, deadLetters *rabbitmq.DeadLetters, internalToken string) {
 
 	e.Validator = &CustomValidator{validator: validator.New()}
 
@@ -28,6 +29,7 @@ func SetupRoutes(e *echo.Echo, taskService *service.TaskService, internalToken s
 	e.Use(middleware.CORS())
 
 	taskHandler := NewTaskHandler(taskService)
+	eventHandler := NewEventHandler(taskService)
 
 	e.GET("/health", taskHandler.HealthCheck)
 
@@ -41,6 +43,27 @@ func SetupRoutes(e *echo.Echo, taskService *service.TaskService, internalToken s
 		api.DELETE("/tasks/:id", taskHandler.DeleteTask)
 		api.POST("/tasks/:id/complete", taskHandler.CompleteTask)
 		api.GET("/tasks/stats", taskHandler.GetStats)
+		api.GET("/tasks/review", taskHandler.GetReviewQueue)
+		api.GET("/tasks/tags", taskHandler.GetTags)
+		api.POST("/tasks/:id/review", taskHandler.ReviewTask)
+
+		api.GET("/events", eventHandler.GetEvents)
+		api.GET("/events/:id", eventHandler.GetEvent)
+		api.DELETE("/events/:id", eventHandler.DeleteEvent)
+
+		api.GET("/emails/:email_id/outcome", taskHandler.GetEmailOutcome)
+	}
+
+	deadLetterHandler := NewDeadLetterHandler(deadLetters)
+
+	admin := e.Group("/admin", InternalAuth(internalToken))
+	{
+		admin.GET("/dead-letters/:queue", deadLetterHandler.List)
+		admin.GET("/dead-letters/:queue/:message_id", deadLetterHandler.Get)
+		admin.POST("/dead-letters/:queue/requeue", deadLetterHandler.Requeue)
+		admin.POST("/dead-letters/:queue/:message_id/requeue", deadLetterHandler.Requeue)
+		admin.DELETE("/dead-letters/:queue", deadLetterHandler.Purge)
+		admin.DELETE("/dead-letters/:queue/:message_id", deadLetterHandler.Purge)
 	}
 }
 
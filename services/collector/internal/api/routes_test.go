@@ -13,7 +13,7 @@ import (
 func TestSetupRoutes_HealthRegistered(t *testing.T) {
 	e := echo.New()
 	ts := &service.TaskService{}
-	SetupRoutes(e, ts, "token")
+	SetupRoutes(e, ts, nil, "token")
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	rec := httptest.NewRecorder()
@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
+	"collector/internal/priority"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
 )
@@ -15,6 +18,18 @@ type Config struct {
 	ServerPort       string
 	ServerHost       string
 	InternalAPIToken string
+	// Повторы обработки сообщений: после MaxAttempts сообщение уходит в dead_letters
+	MaxAttempts   int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// Веса признаков и пороги, по которым считается приоритет задачи
+	Priority priority.Config
+	// Очередь предложений analyzer закрыть задачу по письму "оплата получена"
+	CompletionQueueName string
+	// Очередь итогов обработки писем: ярлык классификатора и причины пропуска
+	OutcomeQueueName string
+	// Предложение с уверенностью от порога закрывает задачу само, ниже — отправляет её на проверку
+	CompletionAutoThreshold float64
 }
 
 func Load() (*Config, error) {
@@ -31,7 +46,20 @@ func Load() (*Config, error) {
 		ServerPort:       env("SERVER_PORT", "8080"),
 		ServerHost:       env("SERVER_HOST", "0.0.0.0"),
 		InternalAPIToken: env("INTERNAL_API_TOKEN", "gateway-secret-token"),
+		MaxAttempts:      envInt("RABBIT_MAX_ATTEMPTS", 5),
+		RetryDelay:       envDuration("RABBIT_RETRY_DELAY", 5*time.Second),
+		MaxRetryDelay:    envDuration("RABBIT_MAX_RETRY_DELAY", 5*time.Minute),
+
+		CompletionQueueName:     env("RABBIT_COMPLETION_QUEUE_NAME", "task_completion_queue"),
+		OutcomeQueueName:        env("RABBIT_OUTCOME_QUEUE_NAME", "email_processed_queue"),
+		CompletionAutoThreshold: envFloat("COMPLETION_AUTO_THRESHOLD", 0.8),
+	}
+
+	prio, err := priority.DefaultConfig().Parse(os.Getenv("PRIORITY_WEIGHTS"), os.Getenv("PRIORITY_THRESHOLDS"))
+	if err != nil {
+		return nil, err
 	}
+	cfg.Priority = prio
 
 	if cfg.DBURL == "" {
 		return nil, fmt.Errorf("DB_URL is required")
@@ -57,3 +85,24 @@ func env(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func envInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func envFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
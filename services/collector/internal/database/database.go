@@ -3,11 +3,12 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type DB struct {
@@ -23,6 +24,23 @@ type DBer interface {
 	CompleteTask(ctx context.Context, taskID, userID string) error
 	GetTaskStats(ctx context.Context, userID string) (*TaskStats, error)
 	TaskExists(ctx context.Context, emailID, userID string) (bool, error)
+	GetOpenTaskByEmailID(ctx context.Context, userID, emailID string) (*Task, error)
+	SuggestCompletion(ctx context.Context, taskID, userID string, reasons []string) error
+	GetUserTags(ctx context.Context, userID string) ([]Tag, error)
+
+	CreateEvent(ctx context.Context, event *Event) error
+	GetEvent(ctx context.Context, eventID, userID string) (*Event, error)
+	GetUserEvents(ctx context.Context, filter EventFilter) ([]Event, error)
+	DeleteEvent(ctx context.Context, eventID, userID string) error
+	EventsExist(ctx context.Context, emailID, userID string) (bool, error)
+
+	GetTaskByCalendarUID(ctx context.Context, userID, uid string) (*Task, error)
+	UpdateCalendarTask(ctx context.Context, task *Task) error
+	GetEventByUID(ctx context.Context, userID, uid string) (*Event, error)
+	UpdateEvent(ctx context.Context, event *Event) error
+
+	SaveEmailOutcome(ctx context.Context, outcome *EmailOutcome) error
+	GetEmailOutcome(ctx context.Context, userID, emailID string) (*EmailOutcome, error)
 }
 
 func NewDB(url string) (*DB, error) {
@@ -38,26 +56,70 @@ func NewDB(url string) (*DB, error) {
 	return &DB{db}, nil
 }
 
+// CreateTask возвращает ErrTaskExists, если у письма уже есть задача без UID или задача с тем же UID
 func (db *DB) CreateTask(ctx context.Context, task *Task) error {
-	query := `INSERT INTO tasks (id, user_id, email_id, title, description, deadline, status, priority, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())`
+	query := `INSERT INTO tasks (id, user_id, email_id, title, description, deadline, deadline_timezone, status, priority,
+                                 prompt_version, title_confidence, deadline_confidence, review_reasons,
+                                 calendar_uid, calendar_sequence, rrule, category,
+                                 importance_urgent, importance_sender, importance_priority, extracted_title, extracted_deadline,
+                                 created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, NOW(), NOW())`
+
+	var titleConfidence, deadlineConfidence *float64
+	if task.Confidence != nil {
+		titleConfidence, deadlineConfidence = &task.Confidence.Title, &task.Confidence.Deadline
+	}
+
+	var urgent *bool
+	var sender, priority sql.NullString
+	if task.Importance != nil {
+		urgent = &task.Importance.Urgent
+		sender, priority = nullString(task.Importance.SenderRole), nullString(task.Importance.Priority)
+	}
+
+	var extractedTitle *string
+	var extractedDeadline *time.Time
+	if task.Extracted != nil {
+		extractedTitle, extractedDeadline = &task.Extracted.Title, task.Extracted.Deadline
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	_, err := db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		task.ID, task.UserID, task.EmailID, task.Title,
-		task.Description, task.Deadline, task.Status, task.Priority)
-	return err
+		task.Description, task.Deadline, task.DeadlineTimezone, task.Status, task.Priority,
+		task.PromptVersion, titleConfidence, deadlineConfidence, pq.Array(task.ReviewReasons),
+		task.CalendarUID, task.CalendarSequence, task.RRule, task.Category,
+		urgent, sender, priority, extractedTitle, extractedDeadline)
+	if isUniqueViolation(err) {
+		return ErrTaskExists
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := setTaskTags(ctx, tx, task.UserID, task.ID, task.Tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isUniqueViolation — задача с тем же письмом или UID уже есть: её создала копия того же сообщения
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
 
 func (db *DB) GetTask(ctx context.Context, taskID, userID string) (*Task, error) {
-	query := `SELECT id, user_id, email_id, title, description, deadline, status, priority, created_at, updated_at, completed_at
+	query := `SELECT ` + taskColumns + `
               FROM tasks 
               WHERE id = $1 AND user_id = $2`
 
-	var task Task
-	err := db.QueryRowContext(ctx, query, taskID, userID).Scan(
-		&task.ID, &task.UserID, &task.EmailID, &task.Title,
-		&task.Description, &task.Deadline, &task.Status, &task.Priority,
-		&task.CreatedAt, &task.UpdatedAt, &task.CompletedAt)
+	task, err := scanTask(db.QueryRowContext(ctx, query, taskID, userID))
 
 	if err == sql.ErrNoRows {
 		return nil, ErrTaskNotFound
@@ -66,11 +128,11 @@ func (db *DB) GetTask(ctx context.Context, taskID, userID string) (*Task, error)
 		return nil, err
 	}
 
-	return &task, nil
+	return task, nil
 }
 
 func (db *DB) GetUserTasks(ctx context.Context, filter TaskFilter) ([]Task, error) {
-	query := `SELECT id, user_id, email_id, title, description, deadline, status, priority, created_at, updated_at, completed_at
+	query := `SELECT ` + taskColumns + `
               FROM tasks 
               WHERE user_id = $1`
 
@@ -81,6 +143,9 @@ func (db *DB) GetUserTasks(ctx context.Context, filter TaskFilter) ([]Task, erro
 		query += fmt.Sprintf(" AND status = $%d", argPos)
 		args = append(args, *filter.Status)
 		argPos++
+	} else {
+		// Непроверенные и отклонённые задачи видны только в очереди проверки
+		query += fmt.Sprintf(" AND status NOT IN ('%s', '%s')", StatusNeedsReview, StatusDismissed)
 	}
 
 	if filter.Priority != nil {
@@ -101,6 +166,20 @@ func (db *DB) GetUserTasks(ctx context.Context, filter TaskFilter) ([]Task, erro
 		argPos++
 	}
 
+	if filter.Category != nil {
+		query += fmt.Sprintf(" AND category = $%d", argPos)
+		args = append(args, *filter.Category)
+		argPos++
+	}
+
+	if len(filter.Tags) > 0 {
+		query += fmt.Sprintf(` AND id IN (SELECT tt.task_id FROM task_tags tt JOIN tags tg ON tg.id = tt.tag_id
+                                 WHERE tg.user_id = $1 AND tg.name = ANY($%d)
+                                 GROUP BY tt.task_id HAVING COUNT(*) = $%d)`, argPos, argPos+1)
+		args = append(args, pq.Array(filter.Tags), len(filter.Tags))
+		argPos += 2
+	}
+
 	query += " ORDER BY deadline ASC NULLS LAST, created_at DESC"
 
 	if filter.Limit > 0 {
@@ -122,15 +201,11 @@ func (db *DB) GetUserTasks(ctx context.Context, filter TaskFilter) ([]Task, erro
 
 	var tasks []Task
 	for rows.Next() {
-		var task Task
-		err := rows.Scan(
-			&task.ID, &task.UserID, &task.EmailID, &task.Title,
-			&task.Description, &task.Deadline, &task.Status, &task.Priority,
-			&task.CreatedAt, &task.UpdatedAt, &task.CompletedAt)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, task)
+		tasks = append(tasks, *task)
 	}
 
 	return tasks, nil
@@ -177,15 +252,31 @@ func (db *DB) UpdateTask(ctx context.Context, taskID, userID string, update Upda
 		argPos++
 	}
 
-	if len(args) == 0 {
+	if update.Category != nil {
+		setParts = append(setParts, fmt.Sprintf("category = $%d", argPos))
+		args = append(args, nullString(*update.Category))
+		argPos++
+	}
+
+	if update.ClearSuggestedCompletion {
+		setParts = append(setParts, "suggested_completion = NULL")
+	}
+
+	if len(args) == 0 && update.Tags == nil && !update.ClearSuggestedCompletion {
 		return nil
 	}
 
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := fmt.Sprintf("UPDATE tasks SET %s WHERE id = $%d AND user_id = $%d",
 		strings.Join(setParts, ", "), argPos, argPos+1)
 	args = append(args, taskID, userID)
 
-	result, err := db.ExecContext(ctx, query, args...)
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -199,7 +290,12 @@ func (db *DB) UpdateTask(ctx context.Context, taskID, userID string, update Upda
 		return ErrTaskNotFound
 	}
 
-	return nil
+	if update.Tags != nil {
+		if err := setTaskTags(ctx, tx, userID, taskID, *update.Tags); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (db *DB) DeleteTask(ctx context.Context, taskID, userID string) error {
@@ -243,20 +339,23 @@ func (db *DB) CompleteTask(ctx context.Context, taskID, userID string) error {
 }
 
 func (db *DB) GetTaskStats(ctx context.Context, userID string) (*TaskStats, error) {
+	// Задачи на проверке считаются отдельно, отклонённые не считаются вовсе: ни те, ни другие
+	// пользователь ещё не принял как свои дела
 	query := `SELECT
-                COUNT(*) as total,
+                COUNT(CASE WHEN status NOT IN ('needs_review', 'dismissed') THEN 1 END) as total,
                 COUNT(CASE WHEN status = 'pending' THEN 1 END) as pending,
                 COUNT(CASE WHEN status = 'completed' THEN 1 END) as completed,
-                COUNT(CASE WHEN deadline < NOW() AND status != 'completed' THEN 1 END) as overdue,
-                COUNT(CASE WHEN DATE(deadline) = CURRENT_DATE AND status != 'completed' THEN 1 END) as today,
-                COUNT(CASE WHEN deadline >= CURRENT_DATE AND deadline < CURRENT_DATE + INTERVAL '7 days' AND status != 'completed' THEN 1 END) as this_week
+                COUNT(CASE WHEN deadline < NOW() AND status NOT IN ('completed', 'needs_review', 'dismissed') THEN 1 END) as overdue,
+                COUNT(CASE WHEN DATE(deadline) = CURRENT_DATE AND status NOT IN ('completed', 'needs_review', 'dismissed') THEN 1 END) as today,
+                COUNT(CASE WHEN deadline >= CURRENT_DATE AND deadline < CURRENT_DATE + INTERVAL '7 days' AND status NOT IN ('completed', 'needs_review', 'dismissed') THEN 1 END) as this_week,
+                COUNT(CASE WHEN status = 'needs_review' THEN 1 END) as needs_review
               FROM tasks
               WHERE user_id = $1`
 
 	var stats TaskStats
 	err := db.QueryRowContext(ctx, query, userID).Scan(
 		&stats.TotalTasks, &stats.PendingTasks, &stats.CompletedTasks,
-		&stats.OverdueTasks, &stats.TodayTasks, &stats.ThisWeekTasks)
+		&stats.OverdueTasks, &stats.TodayTasks, &stats.ThisWeekTasks, &stats.NeedsReviewTasks)
 
 	if err != nil {
 		return nil, err
@@ -271,3 +370,122 @@ func (db *DB) TaskExists(ctx context.Context, emailID, userID string) (bool, err
 	err := db.QueryRowContext(ctx, query, emailID, userID).Scan(&exists)
 	return exists, err
 }
+
+// GetOpenTaskByEmailID возвращает самую новую незавершённую задачу письма
+func (db *DB) GetOpenTaskByEmailID(ctx context.Context, userID, emailID string) (*Task, error) {
+	query := `SELECT ` + taskColumns + `
+              FROM tasks
+              WHERE user_id = $1 AND email_id = $2 AND status IN ('pending', 'in_progress')
+              ORDER BY created_at DESC
+              LIMIT 1`
+
+	task, err := scanTask(db.QueryRowContext(ctx, query, userID, emailID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// SuggestCompletion помечает задачу как возможно выполненную с причинами, которые увидит пользователь.
+// Статус не меняется: задача остаётся в списке, пока пользователь не решит, закрывать ли её.
+func (db *DB) SuggestCompletion(ctx context.Context, taskID, userID string, reasons []string) error {
+	query := `UPDATE tasks SET suggested_completion = $1, updated_at = NOW()
+              WHERE id = $2 AND user_id = $3`
+
+	result, err := db.ExecContext(ctx, query, pq.Array(reasons), taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+
+	return nil
+}
+
+func (db *DB) GetTaskByCalendarUID(ctx context.Context, userID, uid string) (*Task, error) {
+	query := `SELECT ` + taskColumns + `
+              FROM tasks
+              WHERE user_id = $1 AND calendar_uid = $2`
+
+	task, err := scanTask(db.QueryRowContext(ctx, query, userID, uid))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// UpdateCalendarTask применяет к задаче новую версию VTODO вместе с её SEQUENCE
+func (db *DB) UpdateCalendarTask(ctx context.Context, task *Task) error {
+	query := `UPDATE tasks
+              SET title = $1, description = $2, deadline = $3, deadline_timezone = $4, priority = $5, status = $6,
+                  calendar_sequence = $7, rrule = $8, updated_at = NOW(),
+                  completed_at = CASE WHEN $6 = 'completed' THEN COALESCE(completed_at, NOW()) ELSE completed_at END
+              WHERE id = $9 AND user_id = $10`
+
+	result, err := db.ExecContext(ctx, query,
+		task.Title, task.Description, task.Deadline, task.DeadlineTimezone, task.Priority, task.Status,
+		task.CalendarSequence, task.RRule, task.ID, task.UserID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+
+	return nil
+}
+
+const taskColumns = `id, user_id, email_id, title, description, deadline, deadline_timezone, status, priority, created_at, updated_at, completed_at,
+                     prompt_version, title_confidence, deadline_confidence, review_reasons, suggested_completion, calendar_uid, calendar_sequence, rrule,
+                     category, importance_urgent, importance_sender, importance_priority, extracted_title, extracted_deadline, ` + taskTagsColumn
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row rowScanner) (*Task, error) {
+	var task Task
+	var titleConfidence, deadlineConfidence sql.NullFloat64
+	var urgent sql.NullBool
+	var sender, priority, extractedTitle sql.NullString
+	var extractedDeadline *time.Time
+	err := row.Scan(
+		&task.ID, &task.UserID, &task.EmailID, &task.Title,
+		&task.Description, &task.Deadline, &task.DeadlineTimezone, &task.Status, &task.Priority,
+		&task.CreatedAt, &task.UpdatedAt, &task.CompletedAt, &task.PromptVersion,
+		&titleConfidence, &deadlineConfidence, pq.Array(&task.ReviewReasons), pq.Array(&task.SuggestedCompletion), &task.CalendarUID, &task.CalendarSequence, &task.RRule,
+		&task.Category, &urgent, &sender, &priority, &extractedTitle, &extractedDeadline, pq.Array(&task.Tags))
+	if err != nil {
+		return nil, err
+	}
+
+	if titleConfidence.Valid || deadlineConfidence.Valid {
+		task.Confidence = &Confidence{Title: titleConfidence.Float64, Deadline: deadlineConfidence.Float64}
+	}
+	if urgent.Valid {
+		task.Importance = &Importance{Urgent: urgent.Bool, SenderRole: sender.String, Priority: priority.String}
+	}
+	if extractedTitle.Valid {
+		task.Extracted = &Extraction{Title: extractedTitle.String, Deadline: extractedDeadline}
+	}
+	return &task, nil
+}
@@ -4,4 +4,8 @@ import "errors"
 
 var (
 	ErrTaskNotFound        = errors.New("task not found")
+	ErrTaskNotInReview     = errors.New("task is not waiting for review")
+	ErrEventNotFound       = errors.New("event not found")
+	ErrOutcomeNotFound     = errors.New("email outcome not found")
+	ErrTaskExists          = errors.New("task already exists")
 )
\ No newline at end of file
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const eventColumns = `id, user_id, email_id, title, description, start_at, end_at, all_day, timezone, location, join_url,
+                      participants, status, uid, sequence, created_at, updated_at`
+
+func (db *DB) CreateEvent(ctx context.Context, event *Event) error {
+	query := `INSERT INTO events (id, user_id, email_id, title, description, start_at, end_at, all_day, timezone,
+                                  location, join_url, participants, status, uid, sequence, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), NOW())`
+
+	_, err := db.ExecContext(ctx, query,
+		event.ID, event.UserID, event.EmailID, event.Title, event.Description,
+		event.Start, event.End, event.AllDay, event.Timezone,
+		event.Location, event.JoinURL, pq.Array(event.Participants), event.Status,
+		nullString(event.UID), event.Sequence)
+	return err
+}
+
+// UpdateEvent перезаписывает событие целиком — так применяются обновления и отмены из календаря
+func (db *DB) UpdateEvent(ctx context.Context, event *Event) error {
+	query := `UPDATE events
+              SET title = $1, description = $2, start_at = $3, end_at = $4, all_day = $5, timezone = $6,
+                  location = $7, join_url = $8, participants = $9, status = $10, sequence = $11, updated_at = NOW()
+              WHERE id = $12 AND user_id = $13`
+
+	result, err := db.ExecContext(ctx, query,
+		event.Title, event.Description, event.Start, event.End, event.AllDay, event.Timezone,
+		event.Location, event.JoinURL, pq.Array(event.Participants), event.Status, event.Sequence,
+		event.ID, event.UserID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEventNotFound
+	}
+
+	return nil
+}
+
+func (db *DB) GetEventByUID(ctx context.Context, userID, uid string) (*Event, error) {
+	query := `SELECT ` + eventColumns + `
+              FROM events
+              WHERE user_id = $1 AND uid = $2`
+
+	event, err := scanEvent(db.QueryRowContext(ctx, query, userID, uid))
+	if err == sql.ErrNoRows {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (db *DB) GetEvent(ctx context.Context, eventID, userID string) (*Event, error) {
+	query := `SELECT ` + eventColumns + `
+              FROM events
+              WHERE id = $1 AND user_id = $2`
+
+	event, err := scanEvent(db.QueryRowContext(ctx, query, eventID, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (db *DB) GetUserEvents(ctx context.Context, filter EventFilter) ([]Event, error) {
+	query := `SELECT ` + eventColumns + `
+              FROM events
+              WHERE user_id = $1`
+
+	args := []interface{}{filter.UserID}
+	argPos := 2
+
+	if filter.Status != nil {
+		query += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, *filter.Status)
+		argPos++
+	} else {
+		// Отменённые встречи показываются только по явному запросу
+		query += fmt.Sprintf(" AND status <> '%s'", EventStatusCancelled)
+	}
+
+	// Событие попадает в интервал, если оно хотя бы частично в него заходит
+	if filter.From != nil {
+		query += fmt.Sprintf(" AND COALESCE(end_at, start_at) >= $%d", argPos)
+		args = append(args, *filter.From)
+		argPos++
+	}
+
+	if filter.To != nil {
+		query += fmt.Sprintf(" AND start_at <= $%d", argPos)
+		args = append(args, *filter.To)
+		argPos++
+	}
+
+	query += " ORDER BY start_at ASC"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argPos)
+		args = append(args, filter.Limit)
+		argPos++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argPos)
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+
+	return events, rows.Err()
+}
+
+func (db *DB) DeleteEvent(ctx context.Context, eventID, userID string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM events WHERE id = $1 AND user_id = $2`, eventID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEventNotFound
+	}
+
+	return nil
+}
+
+func (db *DB) EventsExist(ctx context.Context, emailID, userID string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM events WHERE email_id = $1 AND user_id = $2)`
+	err := db.QueryRowContext(ctx, query, emailID, userID).Scan(&exists)
+	return exists, err
+}
+
+func scanEvent(row rowScanner) (*Event, error) {
+	var event Event
+	var description, location, joinURL, uid sql.NullString
+	err := row.Scan(
+		&event.ID, &event.UserID, &event.EmailID, &event.Title, &description,
+		&event.Start, &event.End, &event.AllDay, &event.Timezone, &location, &joinURL,
+		pq.Array(&event.Participants), &event.Status, &uid, &event.Sequence, &event.CreatedAt, &event.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Description, event.Location, event.JoinURL = description.String, location.String, joinURL.String
+	event.UID = uid.String
+	return &event, nil
+}
+
+// nullString сохраняет пустую строку как NULL: уникальный индекс по UID не должен задевать события без него
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS deadline_timezone;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN deadline_timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS prompt_version;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN prompt_version VARCHAR(32);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS review_reasons;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deadline_confidence;
+ALTER TABLE tasks DROP COLUMN IF EXISTS title_confidence;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN title_confidence REAL;
+ALTER TABLE tasks ADD COLUMN deadline_confidence REAL;
+ALTER TABLE tasks ADD COLUMN review_reasons TEXT[];
\ No newline at end of file
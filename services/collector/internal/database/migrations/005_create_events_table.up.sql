@@ -0,0 +1,20 @@
+CREATE TABLE events (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    email_id UUID NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    description TEXT,
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_at TIMESTAMP WITH TIME ZONE,
+    all_day BOOLEAN NOT NULL DEFAULT FALSE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    location TEXT,
+    join_url TEXT,
+    participants TEXT[],
+    status VARCHAR(50) NOT NULL DEFAULT 'scheduled',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_user_start ON events(user_id, start_at);
+CREATE INDEX idx_events_email_user ON events(email_id, user_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_events_user_uid;
+DROP INDEX IF EXISTS idx_tasks_user_calendar_uid;
+ALTER TABLE events DROP COLUMN IF EXISTS sequence;
+ALTER TABLE events DROP COLUMN IF EXISTS uid;
+ALTER TABLE tasks DROP COLUMN IF EXISTS calendar_sequence;
+ALTER TABLE tasks DROP COLUMN IF EXISTS calendar_uid;
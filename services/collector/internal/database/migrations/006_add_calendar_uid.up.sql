@@ -0,0 +1,7 @@
+ALTER TABLE tasks ADD COLUMN calendar_uid TEXT;
+ALTER TABLE tasks ADD COLUMN calendar_sequence INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN uid TEXT;
+ALTER TABLE events ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX idx_tasks_user_calendar_uid ON tasks(user_id, calendar_uid) WHERE calendar_uid IS NOT NULL;
+CREATE UNIQUE INDEX idx_events_user_uid ON events(user_id, uid) WHERE uid IS NOT NULL;
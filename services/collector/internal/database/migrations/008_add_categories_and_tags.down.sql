@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS task_tags;
+DROP TABLE IF EXISTS tags;
+DROP INDEX IF EXISTS idx_tasks_user_category;
+ALTER TABLE tasks DROP COLUMN IF EXISTS category;
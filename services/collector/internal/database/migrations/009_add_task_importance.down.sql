@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS importance_priority;
+ALTER TABLE tasks DROP COLUMN IF EXISTS importance_sender;
+ALTER TABLE tasks DROP COLUMN IF EXISTS importance_urgent;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN importance_urgent BOOLEAN;
+ALTER TABLE tasks ADD COLUMN importance_sender VARCHAR(16);
+ALTER TABLE tasks ADD COLUMN importance_priority VARCHAR(8);
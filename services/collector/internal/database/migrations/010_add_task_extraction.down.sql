@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS extracted_deadline;
+ALTER TABLE tasks DROP COLUMN IF EXISTS extracted_title;
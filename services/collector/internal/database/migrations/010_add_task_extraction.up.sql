@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN extracted_title VARCHAR(500);
+ALTER TABLE tasks ADD COLUMN extracted_deadline TIMESTAMP WITH TIME ZONE;
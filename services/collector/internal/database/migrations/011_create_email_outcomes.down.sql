@@ -0,0 +1 @@
+DROP TABLE IF EXISTS email_outcomes;
\ No newline at end of file
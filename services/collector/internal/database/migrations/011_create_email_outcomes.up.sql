@@ -0,0 +1,9 @@
+CREATE TABLE email_outcomes (
+    user_id UUID NOT NULL,
+    email_id UUID NOT NULL,
+    label VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    reasons TEXT[],
+    processed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, email_id)
+);
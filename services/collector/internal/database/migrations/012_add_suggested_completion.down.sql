@@ -0,0 +1 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS suggested_completion;
\ No newline at end of file
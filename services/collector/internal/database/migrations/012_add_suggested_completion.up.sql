@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN suggested_completion TEXT[];
\ No newline at end of file
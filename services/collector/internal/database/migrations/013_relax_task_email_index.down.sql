@@ -0,0 +1,9 @@
+-- Прежний индекс по письму восстанавливается, только если у каждого письма осталось не больше одной задачи:
+-- задачи из писем с несколькими VTODO откат не удаляет
+DROP INDEX IF EXISTS idx_tasks_user_email;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM tasks GROUP BY email_id, user_id HAVING COUNT(*) > 1) THEN
+        CREATE UNIQUE INDEX idx_tasks_email_user_unique ON tasks(email_id, user_id);
+    END IF;
+END $$;
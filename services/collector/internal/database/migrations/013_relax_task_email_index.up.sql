@@ -0,0 +1,4 @@
+-- Письмо с несколькими VTODO или разметкой schema.org даёт несколько задач с одним email_id: они различаются UID.
+-- Без UID у письма по-прежнему одна задача.
+DROP INDEX IF EXISTS idx_tasks_email_user_unique;
+CREATE UNIQUE INDEX idx_tasks_user_email ON tasks(user_id, email_id) WHERE calendar_uid IS NULL;
@@ -0,0 +1,9 @@
+-- Индекс по всем задачам письма восстанавливается, только если в базе нет серий из нескольких повторений:
+-- откат не удаляет выполненные повторения
+DROP INDEX IF EXISTS idx_tasks_user_email_open;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM tasks WHERE calendar_uid IS NULL GROUP BY user_id, email_id HAVING COUNT(*) > 1) THEN
+        CREATE UNIQUE INDEX idx_tasks_user_email ON tasks(user_id, email_id) WHERE calendar_uid IS NULL;
+    END IF;
+END $$;
@@ -0,0 +1,4 @@
+-- Следующее повторение серии получает email_id выполненной задачи: уникальна только открытая задача письма
+DROP INDEX IF EXISTS idx_tasks_user_email;
+CREATE UNIQUE INDEX idx_tasks_user_email_open ON tasks(user_id, email_id)
+    WHERE calendar_uid IS NULL AND status NOT IN ('completed', 'dismissed');
@@ -4,6 +4,20 @@ import (
 	"time"
 )
 
+// Статусы задач, которыми оперирует сервис, а не только пользователь
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	// Уверенность analyzer ниже порога: задача ждёт решения пользователя
+	StatusNeedsReview = "needs_review"
+	// Пользователь отклонил предложенную задачу или её отменил организатор (VTODO со статусом CANCELLED)
+	StatusDismissed = "dismissed"
+
+	EventStatusScheduled = "scheduled"
+	// Организатор отменил встречу в календаре
+	EventStatusCancelled = "cancelled"
+)
+
 type Task struct {
 	ID          string     `json:"id"`
 	UserID      string     `json:"user_id"`
@@ -11,11 +25,99 @@ type Task struct {
 	Title       string     `json:"title"`
 	Description string     `json:"description"`
 	Deadline    *time.Time `json:"deadline,omitempty"`
-	Status      string     `json:"status"`
-	Priority    string     `json:"priority"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Зона пользователя, в которой был разрешён дедлайн; в ней же он отдаётся клиенту
+	DeadlineTimezone string     `json:"deadline_timezone"`
+	Status           string     `json:"status"`
+	Priority         string     `json:"priority"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	// Версия промпта analyzer, по которой извлечена задача
+	PromptVersion *string     `json:"prompt_version,omitempty"`
+	Confidence    *Confidence `json:"confidence,omitempty"`
+	// Почему задача попала на проверку
+	ReviewReasons []string `json:"review_reasons,omitempty"`
+	// Почему analyzer считает задачу выполненной по новому письму; пусто — такого предложения нет
+	SuggestedCompletion []string `json:"suggested_completion,omitempty"`
+	// UID задачи из календарного приглашения и последний применённый SEQUENCE
+	CalendarUID      *string `json:"calendar_uid,omitempty"`
+	CalendarSequence int     `json:"-"`
+	// Правило повторения RFC 5545 ("FREQ=WEEKLY;BYDAY=MO"); у разовых задач nil
+	RRule *string `json:"rrule,omitempty"`
+	// Категория из таксономии analyzer или выбранная пользователем
+	Category *string  `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Признаки важности из письма; по ним и по дедлайну пересчитывается приоритет
+	Importance *Importance `json:"importance,omitempty"`
+	// Заголовок и срок в том виде, в каком их извлекла модель; nil — задачу создала не модель
+	Extracted *Extraction `json:"-"`
+}
+
+// Extraction — поля задачи до правок пользователя; с ними сравниваются исправления
+type Extraction struct {
+	Title    string
+	Deadline *time.Time
+}
+
+// EmailOutcome — что analyzer сделал с письмом: ярлык классификатора, извлечена задача или письмо пропущено и почему
+type EmailOutcome struct {
+	UserID      string    `json:"user_id"`
+	EmailID     string    `json:"email_id"`
+	Label       string    `json:"label"`
+	Status      string    `json:"status"`
+	Reasons     []string  `json:"reasons,omitempty"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// Tag — тег пользователя и число его задач, видимых в общем списке
+type Tag struct {
+	Name  string `json:"name"`
+	Tasks int    `json:"tasks"`
+}
+
+// Confidence — уверенность analyzer в извлечённых полях, от 0 до 1
+type Confidence struct {
+	Title    float64 `json:"title"`
+	Deadline float64 `json:"deadline"`
+}
+
+// Importance — признаки важности, которые analyzer увидел в письме
+type Importance struct {
+	Urgent     bool   `json:"urgent,omitempty"`
+	SenderRole string `json:"sender_role,omitempty"`
+	Priority   string `json:"priority,omitempty"`
+}
+
+// Event — встреча из письма. В отличие от задачи у неё есть начало и конец, а не срок.
+type Event struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	EmailID     string     `json:"email_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Start       time.Time  `json:"start"`
+	End         *time.Time `json:"end,omitempty"`
+	AllDay      bool       `json:"all_day"`
+	// Зона пользователя, в которой разрешено время; в ней же событие отдаётся клиенту
+	Timezone     string   `json:"timezone"`
+	Location     string   `json:"location,omitempty"`
+	JoinURL      string   `json:"join_url,omitempty"`
+	Participants []string `json:"participants,omitempty"`
+	Status       string   `json:"status"`
+	// UID из календарного приглашения и последний применённый SEQUENCE
+	UID       string    `json:"uid,omitempty"`
+	Sequence  int       `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type EventFilter struct {
+	UserID string
+	Status *string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
 }
 
 type ParsedEmail struct {
@@ -41,6 +143,20 @@ type UpdateTaskRequest struct {
 	Deadline    *time.Time `json:"deadline,omitempty"`
 	Status      *string    `json:"status,omitempty" validate:"omitempty,oneof=pending in_progress completed cancelled archived"`
 	Priority    *string    `json:"priority,omitempty" validate:"omitempty,oneof=low medium high urgent"`
+	// Пустая строка снимает категорию; Tags заменяют все теги задачи, пустой массив — снимает их
+	Category *string   `json:"category,omitempty" validate:"omitempty,max=64"`
+	Tags     *[]string `json:"tags,omitempty" validate:"omitempty,max=20,dive,min=1,max=64"`
+	// Снять предложение закрыть задачу: пользователь по нему уже решил
+	ClearSuggestedCompletion bool `json:"-"`
+}
+
+// ReviewRequest — решение пользователя по задаче из очереди проверки.
+// При edit переданные поля заменяют предложенные analyzer; complete подтверждает, что задачу закрыло письмо.
+type ReviewRequest struct {
+	Action      string     `json:"action" validate:"required,oneof=accept edit dismiss complete"`
+	Title       *string    `json:"title,omitempty" validate:"omitempty,min=1,max=500"`
+	Description *string    `json:"description,omitempty"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
 }
 
 type TaskFilter struct {
@@ -49,8 +165,11 @@ type TaskFilter struct {
 	Priority     *string
 	FromDeadline *time.Time
 	ToDeadline   *time.Time
-	Limit        int
-	Offset       int
+	Category     *string
+	// Задача должна иметь все перечисленные теги
+	Tags   []string
+	Limit  int
+	Offset int
 }
 
 type TaskStats struct {
@@ -60,4 +179,6 @@ type TaskStats struct {
 	OverdueTasks   int `json:"overdue_tasks"`
 	TodayTasks     int `json:"today_tasks"`
 	ThisWeekTasks  int `json:"this_week_tasks"`
+	// Задачи, которые ждут проверки пользователем; в остальные счётчики не входят
+	NeedsReviewTasks int `json:"needs_review_tasks"`
 }
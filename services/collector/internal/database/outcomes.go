@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// SaveEmailOutcome сохраняет итог обработки письма; повторная обработка письма заменяет прежний итог
+func (db *DB) SaveEmailOutcome(ctx context.Context, outcome *EmailOutcome) error {
+	query := `INSERT INTO email_outcomes (user_id, email_id, label, status, reasons, processed_at)
+              VALUES ($1, $2, $3, $4, $5, NOW())
+              ON CONFLICT (user_id, email_id)
+              DO UPDATE SET label = EXCLUDED.label, status = EXCLUDED.status, reasons = EXCLUDED.reasons,
+                            processed_at = EXCLUDED.processed_at`
+
+	_, err := db.ExecContext(ctx, query, outcome.UserID, outcome.EmailID, outcome.Label, outcome.Status, pq.Array(outcome.Reasons))
+	return err
+}
+
+func (db *DB) GetEmailOutcome(ctx context.Context, userID, emailID string) (*EmailOutcome, error) {
+	query := `SELECT user_id, email_id, label, status, reasons, processed_at
+              FROM email_outcomes
+              WHERE user_id = $1 AND email_id = $2`
+
+	var outcome EmailOutcome
+	err := db.QueryRowContext(ctx, query, userID, emailID).Scan(
+		&outcome.UserID, &outcome.EmailID, &outcome.Label, &outcome.Status, pq.Array(&outcome.Reasons), &outcome.ProcessedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOutcomeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &outcome, nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"collector/internal/util"
+)
+
+// Теги задачи одной строкой: имена из task_tags в алфавитном порядке
+const taskTagsColumn = `ARRAY(SELECT tg.name FROM task_tags tt JOIN tags tg ON tg.id = tt.tag_id
+                              WHERE tt.task_id = tasks.id ORDER BY tg.name)`
+
+// setTaskTags заменяет теги задачи. Теги общие для задач пользователя: новый создаётся при первом упоминании.
+func setTaskTags(ctx context.Context, tx *sql.Tx, userID, taskID string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_tags WHERE task_id = $1`, taskID); err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		var tagID string
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO tags (id, user_id, name, created_at) VALUES ($1, $2, $3, NOW())
+             ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+             RETURNING id`,
+			util.GenerateUUID(), userID, name).Scan(&tagID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO task_tags (task_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			taskID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserTags возвращает теги пользователя с числом задач в списке; теги без таких задач не показываются
+func (db *DB) GetUserTags(ctx context.Context, userID string) ([]Tag, error) {
+	query := `SELECT tg.name, COUNT(t.id)
+              FROM tags tg
+              JOIN task_tags tt ON tt.tag_id = tg.id
+              JOIN tasks t ON t.id = tt.task_id
+              WHERE tg.user_id = $1 AND t.status NOT IN ($2, $3)
+              GROUP BY tg.name
+              ORDER BY COUNT(t.id) DESC, tg.name`
+
+	rows, err := db.QueryContext(ctx, query, userID, StatusNeedsReview, StatusDismissed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Tasks); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,172 @@
+package priority
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"collector/internal/database"
+)
+
+// Приоритеты задач от самого высокого
+const (
+	Urgent = "urgent"
+	High   = "high"
+	Medium = "medium"
+	Low    = "low"
+)
+
+// Ключи весов в PRIORITY_WEIGHTS. Вес роли отправителя — "sender_" + роль из analyzer.
+const (
+	DueWithinDay   = "due_1d"
+	DueWithin3Days = "due_3d"
+	DueWithinWeek  = "due_7d"
+	DueLater       = "due_later"
+	NoDeadline     = "no_deadline"
+	UrgentWording  = "urgent"
+	ExplicitHigh   = "explicit_high"
+	ExplicitLow    = "explicit_low"
+	senderPrefix   = "sender_"
+)
+
+// Config — веса признаков и пороги приоритетов. Баллы признаков складываются, задача получает
+// самый высокий приоритет, порог которого набран.
+type Config struct {
+	Weights map[string]float64
+	// Пороги по ключам Urgent, High, Medium; ниже Medium — Low
+	Thresholds map[string]float64
+}
+
+// DefaultConfig без признаков важности повторяет прежнюю шкалу по дедлайну: сутки — urgent,
+// три дня — high, неделя — medium. Срочность в письме сама по себе даёт urgent, задача без срока — low.
+func DefaultConfig() Config {
+	return Config{
+		Weights: map[string]float64{
+			DueWithinDay:               4,
+			DueWithin3Days:             3,
+			DueWithinWeek:              2,
+			DueLater:                   1,
+			NoDeadline:                 0,
+			UrgentWording:              4,
+			ExplicitHigh:               2,
+			ExplicitLow:                -2,
+			senderPrefix + "manager":   1,
+			senderPrefix + "client":    1,
+			senderPrefix + "colleague": 0,
+			senderPrefix + "automated": -1,
+		},
+		Thresholds: map[string]float64{Urgent: 4, High: 3, Medium: 2},
+	}
+}
+
+// Parse накладывает на значения по умолчанию переопределения вида "urgent=3,sender_client=2".
+// Неизвестный ключ — ошибка: опечатка в конфигурации не должна молча менять приоритеты.
+func (c Config) Parse(weights, thresholds string) (Config, error) {
+	out := Config{Weights: copyMap(c.Weights), Thresholds: copyMap(c.Thresholds)}
+	if err := override(out.Weights, weights); err != nil {
+		return Config{}, fmt.Errorf("priority weights: %w", err)
+	}
+	if err := override(out.Thresholds, thresholds); err != nil {
+		return Config{}, fmt.Errorf("priority thresholds: %w", err)
+	}
+	return out, nil
+}
+
+// Scorer считает приоритет задачи. nil и пустой Scorer работают с DefaultConfig.
+type Scorer struct {
+	cfg Config
+}
+
+func New(cfg Config) *Scorer {
+	return &Scorer{cfg: cfg}
+}
+
+// Priority возвращает приоритет по дедлайну и признакам важности. deadline == nil — срока нет;
+// просроченный дедлайн считается как дедлайн в ближайшие сутки.
+func (s *Scorer) Priority(deadline *time.Time, importance *database.Importance, now time.Time) string {
+	cfg := DefaultConfig()
+	if s != nil && s.cfg.Weights != nil {
+		cfg = s.cfg
+	}
+
+	score := cfg.Weights[deadlineKey(deadline, now)]
+	if importance != nil {
+		if importance.Urgent {
+			score += cfg.Weights[UrgentWording]
+		}
+		switch importance.Priority {
+		case High:
+			score += cfg.Weights[ExplicitHigh]
+		case Low:
+			score += cfg.Weights[ExplicitLow]
+		}
+		if importance.SenderRole != "" {
+			score += cfg.Weights[senderPrefix+importance.SenderRole]
+		}
+	}
+
+	switch {
+	case score >= cfg.Thresholds[Urgent]:
+		return Urgent
+	case score >= cfg.Thresholds[High]:
+		return High
+	case score >= cfg.Thresholds[Medium]:
+		return Medium
+	default:
+		return Low
+	}
+}
+
+func deadlineKey(deadline *time.Time, now time.Time) string {
+	if deadline == nil || deadline.IsZero() {
+		return NoDeadline
+	}
+	switch until := deadline.Sub(now); {
+	case until <= 24*time.Hour:
+		return DueWithinDay
+	case until <= 3*24*time.Hour:
+		return DueWithin3Days
+	case until <= 7*24*time.Hour:
+		return DueWithinWeek
+	default:
+		return DueLater
+	}
+}
+
+func override(values map[string]float64, spec string) error {
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, raw, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if _, known := values[key]; !ok || !known {
+			return fmt.Errorf("unknown key %q, expected one of %s", key, strings.Join(keys(values), ", "))
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		values[key] = value
+	}
+	return nil
+}
+
+func copyMap(m map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func keys(m map[string]float64) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
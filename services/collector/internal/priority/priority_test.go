@@ -0,0 +1,62 @@
+package priority
+
+import (
+	"testing"
+	"time"
+
+	"collector/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScorer_DeadlineOnly(t *testing.T) {
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		deadline := now.Add(d)
+		return &deadline
+	}
+
+	var s *Scorer
+	assert.Equal(t, Urgent, s.Priority(at(-time.Hour), nil, now))
+	assert.Equal(t, Urgent, s.Priority(at(12*time.Hour), nil, now))
+	assert.Equal(t, High, s.Priority(at(48*time.Hour), nil, now))
+	assert.Equal(t, Medium, s.Priority(at(5*24*time.Hour), nil, now))
+	assert.Equal(t, Low, s.Priority(at(10*24*time.Hour), nil, now))
+	assert.Equal(t, Low, s.Priority(nil, nil, now))
+	assert.Equal(t, Low, s.Priority(&time.Time{}, nil, now))
+}
+
+func TestScorer_Importance(t *testing.T) {
+	now := time.Now()
+	week := now.Add(6 * 24 * time.Hour)
+	s := New(DefaultConfig())
+
+	// "Прод лежит" без даты
+	assert.Equal(t, Urgent, s.Priority(nil, &database.Importance{Urgent: true}, now))
+	assert.Equal(t, Medium, s.Priority(nil, &database.Importance{Priority: "high"}, now))
+	assert.Equal(t, High, s.Priority(&week, &database.Importance{SenderRole: "manager"}, now))
+	assert.Equal(t, Low, s.Priority(&week, &database.Importance{SenderRole: "automated"}, now))
+	assert.Equal(t, Low, s.Priority(&week, &database.Importance{Priority: "low"}, now))
+	assert.Equal(t, Medium, s.Priority(&week, &database.Importance{SenderRole: "stranger"}, now))
+}
+
+func TestConfig_Parse(t *testing.T) {
+	cfg, err := DefaultConfig().Parse("urgent=2, sender_client=3,", "high=2.5")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, cfg.Weights[UrgentWording])
+	assert.Equal(t, 3.0, cfg.Weights["sender_client"])
+	assert.Equal(t, 2.5, cfg.Thresholds[High])
+	// Значения по умолчанию не меняются
+	assert.Equal(t, 4.0, DefaultConfig().Weights[UrgentWording])
+
+	now := time.Now()
+	assert.Equal(t, High, New(cfg).Priority(nil, &database.Importance{SenderRole: "client"}, now))
+
+	_, err = DefaultConfig().Parse("urgnet=2", "")
+	assert.ErrorContains(t, err, `unknown key "urgnet"`)
+	_, err = DefaultConfig().Parse("urgent", "")
+	assert.Error(t, err)
+	_, err = DefaultConfig().Parse("", "high=x")
+	assert.Error(t, err)
+}
@@ -12,7 +12,10 @@ type Consumer struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
 	queue   string
+	policy  RetryPolicy
 	handler MessageHandler
+	// Соединение открыто в NewConsumer и закрывается вместе с консьюмером
+	ownsConn bool
 }
 
 type MessageHandler func(ctx context.Context, body []byte) error
@@ -25,19 +28,35 @@ type ParsedEmail struct {
 	Deadline    time.Time `json:"deadline"`
 }
 
-func NewConsumer(url, queueName string, handler MessageHandler) (*Consumer, error) {
+// Обменник, в который analyzer публикует задачи из писем
+const parsedEmailsExchange = "parsed_emails"
+
+// NewConsumer открывает своё соединение и читает задачи из очереди queueName
+func NewConsumer(url, queueName string, policy RetryPolicy, handler MessageHandler) (*Consumer, error) {
 	conn, err := amqp.Dial(url)
 	if err != nil {
 		return nil, err
 	}
 
+	consumer, err := Subscribe(conn, parsedEmailsExchange, queueName, policy, handler)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	consumer.ownsConn = true
+	return consumer, nil
+}
+
+// Subscribe читает очередь queueName, привязанную к обменнику exchange, через уже открытое соединение.
+// Ключ маршрутизации совпадает с именем обменника: так публикует сообщения analyzer.
+func Subscribe(conn *amqp.Connection, exchange, queueName string, policy RetryPolicy, handler MessageHandler) (*Consumer, error) {
 	channel, err := conn.Channel()
 	if err != nil {
 		return nil, err
 	}
 
 	err = channel.ExchangeDeclare(
-		"parsed_emails",
+		exchange,
 		"topic",
 		true,
 		false,
@@ -63,8 +82,8 @@ func NewConsumer(url, queueName string, handler MessageHandler) (*Consumer, erro
 
 	err = channel.QueueBind(
 		queue.Name,
-		"parsed_emails",
-		"parsed_emails",
+		exchange,
+		exchange,
 		false,
 		nil,
 	)
@@ -72,6 +91,10 @@ func NewConsumer(url, queueName string, handler MessageHandler) (*Consumer, erro
 		return nil, err
 	}
 
+	if err := declareRetryTopology(channel, queue.Name, policy); err != nil {
+		return nil, err
+	}
+
 	err = channel.Qos(
 		10,
 		0,
@@ -81,12 +104,13 @@ func NewConsumer(url, queueName string, handler MessageHandler) (*Consumer, erro
 		return nil, err
 	}
 
-	log.Info().Msg("RabbitMQ consumer initialized successfully")
+	log.Info().Str("queue", queue.Name).Msg("RabbitMQ consumer initialized successfully")
 
 	return &Consumer{
 		conn:    conn,
 		channel: channel,
 		queue:   queue.Name,
+		policy:  policy,
 		handler: handler,
 	}, nil
 }
@@ -95,7 +119,8 @@ func (c *Consumer) Close() error {
 	if c.channel != nil {
 		c.channel.Close()
 	}
-	if c.conn != nil {
+	// Соединение, полученное через Subscribe, закрывает его владелец
+	if c.conn != nil && c.ownsConn {
 		return c.conn.Close()
 	}
 	return nil
@@ -154,8 +179,33 @@ func (c *Consumer) processMessage(ctx context.Context, msg amqp.Delivery) {
 	if err != nil {
 
 		log.Error().Err(err).Msgf("Failed to process message: %s", messageID)
+		c.retry(ctx, msg, err)
+		return
+	}
 
-		if nackErr := msg.Nack(false, false); nackErr != nil {
+	if ackErr := msg.Ack(false); ackErr != nil {
+		log.Error().Err(ackErr).Msg("Failed to ack message")
+	}
+
+	log.Debug().Msgf("Message processed successfully: %s", messageID)
+}
+
+// retry отправляет сообщение в очередь ожидания с задержкой, а после MaxAttempts попыток — в dead_letters
+func (c *Consumer) retry(ctx context.Context, msg amqp.Delivery, handlerErr error) {
+	attempt := RetryCount(msg.Headers) + 1
+	publishing := failurePublishing(msg, c.queue, attempt, handlerErr)
+
+	var err error
+	if attempt >= c.policy.MaxAttempts {
+		log.Warn().Str("message_id", msg.MessageId).Int("attempts", attempt).Msg("Message moved to dead letters")
+		err = c.channel.PublishWithContext(ctx, DeadLetterExchange, c.queue, false, false, publishing)
+	} else {
+		err = c.channel.PublishWithContext(ctx, "", RetryQueueName(c.queue, c.policy.Backoff(attempt)), false, false, publishing)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to schedule retry, requeueing message")
+		if nackErr := msg.Nack(false, true); nackErr != nil {
 			log.Error().Err(nackErr).Msg("Failed to nack message")
 		}
 		return
@@ -164,6 +214,9 @@ func (c *Consumer) processMessage(ctx context.Context, msg amqp.Delivery) {
 	if ackErr := msg.Ack(false); ackErr != nil {
 		log.Error().Err(ackErr).Msg("Failed to ack message")
 	}
+}
 
-	log.Debug().Msgf("Message processed successfully: %s", messageID)
+// Connection нужна для управления мёртвыми письмами через API
+func (c *Consumer) Connection() *amqp.Connection {
+	return c.conn
 }
@@ -0,0 +1,166 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter — сообщение, исчерпавшее попытки обработки
+type DeadLetter struct {
+	MessageID     string    `json:"message_id"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Queue         string    `json:"queue"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	FailedAt      string    `json:"failed_at,omitempty"`
+	PublishedAt   time.Time `json:"published_at"`
+	Body          string    `json:"body"`
+}
+
+// DeadLetters управляет очередями мёртвых писем: просмотр, возврат в работу и очистка.
+// queue везде — имя исходной очереди консьюмера, например parsed_emails_queue.
+type DeadLetters struct {
+	conn *amqp.Connection
+}
+
+func NewDeadLetters(conn *amqp.Connection) *DeadLetters {
+	return &DeadLetters{conn: conn}
+}
+
+// List возвращает до limit мёртвых писем, не удаляя их из очереди
+func (d *DeadLetters) List(queue string, limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	err := d.scan(queue, func(delivery amqp.Delivery) (bool, error) {
+		letters = append(letters, toDeadLetter(queue, delivery))
+		return limit > 0 && len(letters) >= limit, nil
+	})
+	return letters, err
+}
+
+func (d *DeadLetters) Get(queue, messageID string) (*DeadLetter, error) {
+	var found *DeadLetter
+	err := d.scan(queue, func(delivery amqp.Delivery) (bool, error) {
+		if delivery.MessageId != messageID {
+			return false, nil
+		}
+		letter := toDeadLetter(queue, delivery)
+		found = &letter
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	return found, nil
+}
+
+// Requeue возвращает письмо в исходную очередь со сброшенным счётчиком попыток.
+// Пустой messageID — вернуть все. Возвращает число перемещённых писем.
+func (d *DeadLetters) Requeue(queue, messageID string) (int, error) {
+	ch, err := d.conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	return d.take(ch, queue, messageID, func(delivery amqp.Delivery) error {
+		headers := amqp.Table{}
+		for k, v := range delivery.Headers {
+			headers[k] = v
+		}
+		delete(headers, HeaderRetryCount)
+		headers[HeaderRequeued] = true
+
+		return ch.PublishWithContext(context.Background(), "", queue, false, false, amqp.Publishing{
+			Headers:       headers,
+			ContentType:   delivery.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			MessageId:     delivery.MessageId,
+			CorrelationId: delivery.CorrelationId,
+			Timestamp:     delivery.Timestamp,
+			Body:          delivery.Body,
+		})
+	})
+}
+
+// Purge удаляет письмо из очереди мёртвых писем; пустой messageID — очистить очередь целиком
+func (d *DeadLetters) Purge(queue, messageID string) (int, error) {
+	ch, err := d.conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	if messageID == "" {
+		return ch.QueuePurge(DeadLetterQueueName(queue), false)
+	}
+	return d.take(ch, queue, messageID, func(amqp.Delivery) error { return nil })
+}
+
+// scan читает очередь через basic.get и возвращает все прочитанные сообщения обратно.
+// Сообщения остаются неподтверждёнными до конца обхода, поэтому каждое читается ровно один раз.
+func (d *DeadLetters) scan(queue string, visit func(amqp.Delivery) (stop bool, err error)) error {
+	ch, err := d.conn.Channel()
+	if err != nil {
+		return err
+	}
+	// Закрытие канала возвращает в очередь все неподтверждённые сообщения
+	defer ch.Close()
+
+	for {
+		delivery, ok, err := ch.Get(DeadLetterQueueName(queue), false)
+		if err != nil || !ok {
+			return err
+		}
+		stop, err := visit(delivery)
+		if err != nil || stop {
+			return err
+		}
+	}
+}
+
+// take подтверждает (то есть удаляет) письма с messageID или все письма после успешного apply
+func (d *DeadLetters) take(ch *amqp.Channel, queue, messageID string, apply func(amqp.Delivery) error) (int, error) {
+	taken := 0
+	for {
+		delivery, ok, err := ch.Get(DeadLetterQueueName(queue), false)
+		if err != nil || !ok {
+			if err == nil && messageID != "" && taken == 0 {
+				err = ErrDeadLetterNotFound
+			}
+			return taken, err
+		}
+		if messageID != "" && delivery.MessageId != messageID {
+			continue
+		}
+		if err := apply(delivery); err != nil {
+			return taken, err
+		}
+		if err := delivery.Ack(false); err != nil {
+			return taken, err
+		}
+		taken++
+	}
+}
+
+func toDeadLetter(queue string, delivery amqp.Delivery) DeadLetter {
+	lastError, _ := delivery.Headers[HeaderLastError].(string)
+	failedAt, _ := delivery.Headers[HeaderFailedAt].(string)
+	return DeadLetter{
+		MessageID:     delivery.MessageId,
+		CorrelationID: delivery.CorrelationId,
+		Queue:         queue,
+		Attempts:      RetryCount(delivery.Headers),
+		LastError:     lastError,
+		FailedAt:      failedAt,
+		PublishedAt:   delivery.Timestamp,
+		Body:          string(delivery.Body),
+	}
+}
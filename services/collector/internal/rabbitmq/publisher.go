@@ -0,0 +1,46 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Publisher отправляет события в analyzer. Обменник и ключ маршрутизации совпадают с именем типа сообщения
+// в snake_case: так их объявляют и связывают с очередями консьюмеры analyzer.
+type Publisher struct {
+	conn *amqp.Connection
+}
+
+func NewPublisher(conn *amqp.Connection) *Publisher {
+	return &Publisher{conn: conn}
+}
+
+// Publish сериализует msg в JSON и публикует его в обменник exchange
+func (p *Publisher) Publish(ctx context.Context, exchange string, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	return ch.PublishWithContext(ctx, exchange, exchange, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    uuid.New().String(),
+		Timestamp:    time.Now(),
+		Body:         body,
+	})
+}
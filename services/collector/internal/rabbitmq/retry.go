@@ -0,0 +1,105 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Заголовки и имена совпадают с pkg/rabbitmq analyzer, чтобы мёртвые письма всех сервисов выглядели одинаково
+const (
+	HeaderRetryCount    = "x-retry-count"
+	HeaderLastError     = "x-last-error"
+	HeaderOriginalQueue = "x-original-queue"
+	HeaderFailedAt      = "x-failed-at"
+	// Сообщение вручную возвращено из мёртвых писем
+	HeaderRequeued = "x-requeued"
+
+	DeadLetterExchange = "dead_letters"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+	MaxDelay    time.Duration
+}
+
+// Backoff — задержка перед попыткой номер attempt+1: Delay, 2*Delay, 4*Delay... но не больше MaxDelay
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+func RetryQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, delay)
+}
+
+func DeadLetterQueueName(queue string) string {
+	return queue + ".dead"
+}
+
+func RetryCount(headers amqp.Table) int {
+	switch v := headers[HeaderRetryCount].(type) {
+	case int:
+		return v
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+	return 0
+}
+
+// declareRetryTopology объявляет очереди ожидания (по одной на задержку) и очередь мёртвых писем
+func declareRetryTopology(ch *amqp.Channel, queue string, policy RetryPolicy) error {
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		delay := policy.Backoff(attempt)
+		_, err := ch.QueueDeclare(RetryQueueName(queue, delay), true, false, false, false, amqp.Table{
+			"x-message-ttl":             int64(delay / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := ch.ExchangeDeclare(DeadLetterExchange, "direct", true, false, false, false, nil); err != nil {
+		return err
+	}
+	dead, err := ch.QueueDeclare(DeadLetterQueueName(queue), true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	return ch.QueueBind(dead.Name, queue, DeadLetterExchange, false, nil)
+}
+
+func failurePublishing(msg amqp.Delivery, queue string, attempt int, handlerErr error) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[HeaderRetryCount] = int32(attempt)
+	headers[HeaderLastError] = handlerErr.Error()
+	headers[HeaderOriginalQueue] = queue
+	headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	return amqp.Publishing{
+		Headers:       headers,
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     msg.MessageId,
+		CorrelationId: msg.CorrelationId,
+		Timestamp:     msg.Timestamp,
+		Body:          msg.Body,
+	}
+}
@@ -0,0 +1,41 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, Delay: time.Second, MaxDelay: 10 * time.Second}
+
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 4*time.Second, policy.Backoff(3))
+	assert.Equal(t, 10*time.Second, policy.Backoff(5))
+}
+
+func TestRetryCount(t *testing.T) {
+	assert.Equal(t, 0, RetryCount(nil))
+	assert.Equal(t, 2, RetryCount(amqp.Table{HeaderRetryCount: int32(2)}))
+	assert.Equal(t, 0, RetryCount(amqp.Table{HeaderRetryCount: "2"}))
+}
+
+func TestFailurePublishing_AddsHeaders(t *testing.T) {
+	msg := amqp.Delivery{
+		Headers:   amqp.Table{HeaderRetryCount: int32(1)},
+		MessageId: "message-1",
+		Body:      []byte(`{"user_id":"u1"}`),
+	}
+
+	pub := failurePublishing(msg, "parsed_emails_queue", 2, errors.New("db down"))
+
+	assert.Equal(t, "message-1", pub.MessageId)
+	assert.Equal(t, msg.Body, pub.Body)
+	assert.Equal(t, 2, RetryCount(pub.Headers))
+	assert.Equal(t, "db down", pub.Headers[HeaderLastError])
+	assert.Equal(t, "parsed_emails_queue", pub.Headers[HeaderOriginalQueue])
+	assert.Equal(t, 1, RetryCount(msg.Headers))
+}
@@ -0,0 +1,297 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// Правила с BYSETPOS, BYWEEKNO и повторениями чаще раза в день для сроков задач не встречаются
+var ErrUnsupported = errors.New("recurrence: unsupported rule")
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Rule — разобранное правило RRULE (RFC 5545) без DTSTART: началом серии считается дедлайн задачи
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []Day
+	ByMonth    []int
+	ByMonthDay []int
+	Until      time.Time
+	// Сколько повторений осталось, считая текущее; 0 — без ограничения
+	Count int
+}
+
+// Day — элемент BYDAY: день недели и, для MONTHLY и YEARLY, его номер в месяце (1MO, -1FR)
+type Day struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Parse разбирает правило вида "FREQ=MONTHLY;BYMONTHDAY=5". UNTIL без зоны считается временем loc.
+func Parse(rule string, loc *time.Location) (*Rule, error) {
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("recurrence: invalid part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("interval %d", r.Interval)
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && r.Count < 1 {
+				err = fmt.Errorf("count %d", r.Count)
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(value, loc)
+		case "BYDAY":
+			r.ByDay, err = parseDays(value)
+		case "BYMONTH":
+			r.ByMonth, err = parseInts(value, 1, 12)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(value, -31, 31)
+		case "WKST":
+			// Неделя всегда начинается с понедельника
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupported, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("recurrence: %s: %w", name, err)
+		}
+	}
+
+	switch r.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return nil, errors.New("recurrence: no FREQ")
+	default:
+		return nil, fmt.Errorf("%w: FREQ=%s", ErrUnsupported, r.Freq)
+	}
+	return r, nil
+}
+
+// String собирает правило обратно, например после уменьшения COUNT
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, d := range r.ByDay {
+			code := strings.ToUpper(d.Weekday.String()[:2])
+			if d.N != 0 {
+				code = strconv.Itoa(d.N) + code
+			}
+			days = append(days, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	switch {
+	case !r.Until.IsZero():
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	case r.Count > 0:
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next возвращает первое повторение строго после after для серии, начатой в start. Время суток и зона
+// берутся из start. steps — сколько повторений пройдено от start, включая найденное; ok=false — серия закончилась.
+func (r *Rule) Next(start, after time.Time) (next time.Time, steps int, ok bool) {
+	// Даже ежегодное 29 февраля с интервалом повторяется в пределах восьми интервалов
+	limit := 8 * 366 * r.Interval
+	for i := 1; i <= limit; i++ {
+		day := start.AddDate(0, 0, i)
+		if !r.matches(start, day) {
+			continue
+		}
+		steps++
+		candidate := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+		if r.Count > 0 && steps >= r.Count {
+			return time.Time{}, steps, false
+		}
+		if !r.Until.IsZero() && candidate.After(r.Until) {
+			return time.Time{}, steps, false
+		}
+		if candidate.After(after) {
+			return candidate, steps, true
+		}
+	}
+	return time.Time{}, steps, false
+}
+
+// matches проверяет, что день входит в серию: попадает в шаг INTERVAL от start и во все фильтры BY*.
+// Без BYDAY и BYMONTHDAY повторение наследует день недели, число и месяц start, как в RFC 5545.
+func (r *Rule) matches(start, day time.Time) bool {
+	y1, m1, d1 := start.Date()
+	y2, m2, d2 := day.Date()
+	from := time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC)
+
+	var period int
+	switch r.Freq {
+	case Daily:
+		period = int(to.Sub(from).Hours() / 24)
+	case Weekly:
+		period = int(mondayOf(to).Sub(mondayOf(from)).Hours() / 24 / 7)
+	case Monthly:
+		period = (y2-y1)*12 + int(m2) - int(m1)
+	case Yearly:
+		period = y2 - y1
+	}
+	if period%r.Interval != 0 {
+		return false
+	}
+
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(m2)) {
+		return false
+	}
+	if r.Freq == Yearly && len(r.ByMonth) == 0 && m2 != m1 {
+		return false
+	}
+
+	lastDay := time.Date(y2, m2+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if len(r.ByMonthDay) > 0 {
+		matched := false
+		for _, n := range r.ByMonthDay {
+			if n == d2 || (n < 0 && lastDay+n+1 == d2) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(r.ByDay) > 0 {
+		for _, d := range r.ByDay {
+			if d.Weekday != day.Weekday() {
+				continue
+			}
+			if d.N == 0 || r.Freq == Daily || r.Freq == Weekly ||
+				(d.N > 0 && (d2-1)/7+1 == d.N) || (d.N < 0 && (lastDay-d2)/7+1 == -d.N) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		return true
+	}
+
+	switch r.Freq {
+	case Weekly:
+		return day.Weekday() == start.Weekday()
+	case Monthly, Yearly:
+		return d2 == d1
+	}
+	return true
+}
+
+func mondayOf(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return t, nil
+	}
+	// Дата включается в серию целиком
+	t, err := time.ParseInLocation("20060102", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+func parseDays(value string) ([]Day, error) {
+	var days []Day
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid day %q", item)
+		}
+		weekday, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", item)
+		}
+		day := Day{Weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid day %q", item)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseInts(value string, min, max int) ([]int, error) {
+	var result []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("value %d out of range", n)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ",")
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
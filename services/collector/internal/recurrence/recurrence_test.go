@@ -0,0 +1,96 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func moscow(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	return loc
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=WEEKLY;BYDAY=MO",
+		"FREQ=MONTHLY;BYMONTHDAY=5;COUNT=6",
+		"FREQ=MONTHLY;BYDAY=-1FR",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,FR;UNTIL=20260331T205959Z",
+		"FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=31",
+	} {
+		r, err := Parse(rule, time.UTC)
+		require.NoError(t, err, rule)
+		assert.Equal(t, rule, r.String())
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	for _, rule := range []string{"", "BYDAY=MO", "FREQ=HOURLY", "FREQ=MONTHLY;BYSETPOS=-1;BYDAY=MO,TU", "FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;INTERVAL=0"} {
+		_, err := Parse(rule, time.UTC)
+		assert.Error(t, err, rule)
+	}
+	_, err := Parse("FREQ=SECONDLY", time.UTC)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestNext(t *testing.T) {
+	loc := moscow(t)
+	// Понедельник, 15 декабря 2025, 12:00 по Москве
+	start := time.Date(2025, 12, 15, 12, 0, 0, 0, loc)
+
+	cases := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=WEEKLY;BYDAY=MO", "2025-12-22T12:00:00+03:00"},
+		{"FREQ=WEEKLY", "2025-12-22T12:00:00+03:00"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "2025-12-18T12:00:00+03:00"},
+		{"FREQ=DAILY;INTERVAL=3", "2025-12-18T12:00:00+03:00"},
+		{"FREQ=MONTHLY;BYMONTHDAY=5", "2026-01-05T12:00:00+03:00"},
+		{"FREQ=MONTHLY", "2026-01-15T12:00:00+03:00"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2025-12-31T12:00:00+03:00"},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "2025-12-26T12:00:00+03:00"},
+		{"FREQ=MONTHLY;BYDAY=1MO", "2026-01-05T12:00:00+03:00"},
+		{"FREQ=YEARLY", "2026-12-15T12:00:00+03:00"},
+		{"FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=31", "2026-03-31T12:00:00+03:00"},
+	}
+	for _, tc := range cases {
+		r, err := Parse(tc.rule, loc)
+		require.NoError(t, err, tc.rule)
+		next, steps, ok := r.Next(start, start)
+		require.True(t, ok, tc.rule)
+		assert.Equal(t, tc.want, next.Format(time.RFC3339), tc.rule)
+		assert.Equal(t, 1, steps, tc.rule)
+	}
+}
+
+func TestNext_SkipsPastAndStops(t *testing.T) {
+	loc := moscow(t)
+	start := time.Date(2025, 12, 15, 12, 0, 0, 0, loc)
+
+	// Задачу закрыли через три недели: пропущенные понедельники засчитываются в COUNT
+	r, err := Parse("FREQ=WEEKLY;BYDAY=MO;COUNT=5", loc)
+	require.NoError(t, err)
+	next, steps, ok := r.Next(start, start.AddDate(0, 0, 20))
+	require.True(t, ok)
+	assert.Equal(t, "2026-01-05T12:00:00+03:00", next.Format(time.RFC3339))
+	assert.Equal(t, 3, steps)
+
+	// COUNT=1 — текущее повторение последнее
+	r, err = Parse("FREQ=WEEKLY;COUNT=1", loc)
+	require.NoError(t, err)
+	_, _, ok = r.Next(start, start)
+	assert.False(t, ok)
+
+	// UNTIL без времени включает весь день
+	r, err = Parse("FREQ=WEEKLY;UNTIL=20251222", loc)
+	require.NoError(t, err)
+	_, _, ok = r.Next(start, start)
+	assert.True(t, ok)
+	_, _, ok = r.Next(start, start.AddDate(0, 0, 7))
+	assert.False(t, ok)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"collector/internal/database"
+	"collector/internal/util"
+)
+
+// Статусы календарных приглашений в сообщениях analyzer
+const (
+	calendarCancelled = "cancelled"
+	calendarCompleted = "completed"
+)
+
+// saveEvents сохраняет встречи из письма. События из календаря сопоставляются по UID и обновляются,
+// остальные создаются один раз на письмо.
+func (s *TaskService) saveEvents(ctx context.Context, emailData *parsedEmail) error {
+	var plain []parsedEvent
+	for _, e := range emailData.Events {
+		if e.UID == "" {
+			plain = append(plain, e)
+			continue
+		}
+		if err := s.applyCalendarEvent(ctx, emailData, e); err != nil {
+			return err
+		}
+	}
+	if len(plain) == 0 {
+		return nil
+	}
+
+	exists, err := s.db.EventsExist(ctx, emailData.EmailID, emailData.UserID)
+	if err != nil || exists {
+		return err
+	}
+	for _, e := range plain {
+		if err := s.db.CreateEvent(ctx, newEvent(emailData, e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyCalendarEvent создаёт, обновляет или отменяет событие по UID. Письмо с меньшим SEQUENCE,
+// пришедшее позже обновления, ничего не меняет.
+func (s *TaskService) applyCalendarEvent(ctx context.Context, emailData *parsedEmail, e parsedEvent) error {
+	existing, err := s.db.GetEventByUID(ctx, emailData.UserID, e.UID)
+	if errors.Is(err, database.ErrEventNotFound) {
+		// Отмена встречи, о которой мы не знали, — создавать нечего
+		if e.Status == calendarCancelled {
+			return nil
+		}
+		return s.db.CreateEvent(ctx, newEvent(emailData, e))
+	}
+	if err != nil {
+		return err
+	}
+	if e.Sequence < existing.Sequence {
+		return nil
+	}
+
+	existing.Sequence = e.Sequence
+	if e.Status == calendarCancelled {
+		existing.Status = database.EventStatusCancelled
+		return s.db.UpdateEvent(ctx, existing)
+	}
+
+	updated := newEvent(emailData, e)
+	updated.ID, updated.EmailID, updated.CreatedAt = existing.ID, existing.EmailID, existing.CreatedAt
+	return s.db.UpdateEvent(ctx, updated)
+}
+
+// applyCalendarTask создаёт или обновляет задачу из VTODO. CANCELLED отклоняет задачу, COMPLETED закрывает её
+// и ставит следующее повторение серии.
+func (s *TaskService) applyCalendarTask(ctx context.Context, emailData *parsedEmail) error {
+	existing, err := s.db.GetTaskByCalendarUID(ctx, emailData.UserID, emailData.CalendarUID)
+	if errors.Is(err, database.ErrTaskNotFound) {
+		if emailData.Status == calendarCancelled || emailData.Status == calendarCompleted {
+			return nil
+		}
+		return ignoreExisting(s.db.CreateTask(ctx, s.newTask(emailData)))
+	}
+	if err != nil {
+		return err
+	}
+	if emailData.Sequence < existing.CalendarSequence {
+		return nil
+	}
+
+	existing.Title = emailData.Title
+	existing.Description = emailData.Description
+	// Обновление без срока (счёт оплачен, посылка доставлена) оставляет прежний срок
+	if !emailData.Deadline.IsZero() {
+		existing.Deadline = &emailData.Deadline
+		existing.DeadlineTimezone = emailData.Timezone
+	}
+	existing.Priority = s.determinePriority(existing.Deadline, existing.Importance)
+	existing.CalendarSequence = emailData.Sequence
+	existing.RRule = validRRule(emailData.RRule, existing.DeadlineTimezone)
+	// Следующее повторение считается от задачи до закрытия: scheduleNext пропускает уже закрытые
+	before := *existing
+	switch emailData.Status {
+	case calendarCancelled:
+		existing.Status = database.StatusDismissed
+	case calendarCompleted:
+		existing.Status = database.StatusCompleted
+	}
+	if err := s.db.UpdateCalendarTask(ctx, existing); err != nil {
+		return err
+	}
+	if emailData.Status != calendarCompleted {
+		return nil
+	}
+	return s.scheduleNext(ctx, &before)
+}
+
+// ignoreExisting — задачу уже создала копия того же сообщения, повторять нечего
+func ignoreExisting(err error) error {
+	if errors.Is(err, database.ErrTaskExists) {
+		return nil
+	}
+	return err
+}
+
+func newEvent(emailData *parsedEmail, e parsedEvent) *database.Event {
+	return &database.Event{
+		ID:           util.GenerateUUID(),
+		UserID:       emailData.UserID,
+		EmailID:      emailData.EmailID,
+		Title:        e.Title,
+		Description:  e.Description,
+		Start:        e.Start,
+		End:          e.End,
+		AllDay:       e.AllDay,
+		Timezone:     emailData.Timezone,
+		Location:     e.Location,
+		JoinURL:      e.JoinURL,
+		Participants: e.Participants,
+		Status:       database.EventStatusScheduled,
+		UID:          e.UID,
+		Sequence:     e.Sequence,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+}
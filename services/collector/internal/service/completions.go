@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"collector/internal/database"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultCompletionThreshold — уверенность, с которой задача закрывается без проверки: ответ в той же
+// переписке от того же отправителя
+const DefaultCompletionThreshold = 0.8
+
+// taskCompletion — предложение analyzer закрыть задачу по письму "оплата получена" (models.TaskCompletion)
+type taskCompletion struct {
+	UserID string `json:"user_id"`
+	// Письмо, из которого извлечена задача, и UID задачи, если письмо дало их несколько
+	TaskEmailID     string `json:"task_email_id"`
+	TaskCalendarUID string `json:"task_calendar_uid"`
+	// Письмо, которое её закрывает
+	EmailID    string   `json:"email_id"`
+	Signal     string   `json:"signal"`
+	Phrase     string   `json:"phrase"`
+	Confidence float64  `json:"confidence"`
+	Reasons    []string `json:"reasons"`
+}
+
+// HandleCompletionMessage закрывает задачу, если analyzer уверен, что письмо её закрывает, и иначе
+// помечает её предложением закрыть: задача остаётся открытой и видна в списке. Задачу, которую уже
+// закрыли или удалили, предложение не трогает.
+func (s *TaskService) HandleCompletionMessage(ctx context.Context, body []byte) error {
+	var suggestion taskCompletion
+	if err := json.Unmarshal(body, &suggestion); err != nil {
+		return err
+	}
+
+	task, err := s.completionTask(ctx, suggestion)
+	if errors.Is(err, database.ErrTaskNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if suggestion.Confidence >= s.completionThreshold {
+		if err := s.db.CompleteTask(ctx, task.ID, task.UserID); err != nil {
+			return err
+		}
+		log.Info().Str("task_id", task.ID).Str("email_id", suggestion.EmailID).Float64("confidence", suggestion.Confidence).Msg("Task completed by follow-up email")
+		return s.scheduleNext(ctx, task)
+	}
+
+	if err := s.db.SuggestCompletion(ctx, task.ID, task.UserID, completionReasons(suggestion)); err != nil {
+		return err
+	}
+	log.Info().Str("task_id", task.ID).Str("email_id", suggestion.EmailID).Float64("confidence", suggestion.Confidence).Msg("Possible task completion suggested")
+	return nil
+}
+
+// completionTask находит открытую задачу из предложения: по UID, а без него — единственную открытую задачу письма
+func (s *TaskService) completionTask(ctx context.Context, suggestion taskCompletion) (*database.Task, error) {
+	if suggestion.TaskCalendarUID == "" {
+		return s.db.GetOpenTaskByEmailID(ctx, suggestion.UserID, suggestion.TaskEmailID)
+	}
+
+	task, err := s.db.GetTaskByCalendarUID(ctx, suggestion.UserID, suggestion.TaskCalendarUID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != database.StatusPending && task.Status != "in_progress" {
+		return nil, database.ErrTaskNotFound
+	}
+	return task, nil
+}
+
+// completionReasons объясняет пользователю, почему задачу предложено закрыть
+func completionReasons(suggestion taskCompletion) []string {
+	phrase := suggestion.Phrase
+	if phrase == "" {
+		phrase = suggestion.Signal
+	}
+	reasons := []string{"possible completion: " + strings.ToLower(phrase)}
+	return append(reasons, suggestion.Reasons...)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"collector/internal/database"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Обменник, из которого analyzer забирает исправления: имя типа TaskCorrection в snake_case
+const correctionsExchange = "task_correction"
+
+// Publisher отправляет события в analyzer
+type Publisher interface {
+	Publish(ctx context.Context, exchange string, msg interface{}) error
+}
+
+// taskCorrection — исправление задачи пользователем, как его ждёт analyzer (models.TaskCorrection)
+type taskCorrection struct {
+	UserID      string          `json:"user_id"`
+	EmailID     string          `json:"email_id"`
+	TaskID      string          `json:"task_id"`
+	Original    correctedFields `json:"original"`
+	Corrected   correctedFields `json:"corrected"`
+	Timezone    string          `json:"timezone,omitempty"`
+	CorrectedAt time.Time       `json:"corrected_at"`
+}
+
+type correctedFields struct {
+	Title    string     `json:"title"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// publishCorrection сообщает analyzer, чем задача после правки отличается от извлечённой моделью.
+// title и deadline — новые значения, nil — поле не менялось. Правка уже сохранена, поэтому ошибка
+// публикации только логируется.
+func (s *TaskService) publishCorrection(ctx context.Context, task *database.Task, title *string, deadline *time.Time) {
+	if s.publisher == nil || task == nil || task.Extracted == nil || (title == nil && deadline == nil) {
+		return
+	}
+
+	corrected := correctedFields{Title: task.Title, Deadline: task.Deadline}
+	if title != nil {
+		corrected.Title = *title
+	}
+	if deadline != nil {
+		corrected.Deadline = deadline
+	}
+	original := correctedFields{Title: task.Extracted.Title, Deadline: task.Extracted.Deadline}
+	// Пользователь вернул то, что извлекла модель: учить нечему
+	if corrected.Title == original.Title && sameTime(corrected.Deadline, original.Deadline) {
+		return
+	}
+
+	event := taskCorrection{
+		UserID:      task.UserID,
+		EmailID:     task.EmailID,
+		TaskID:      task.ID,
+		Original:    original,
+		Corrected:   corrected,
+		Timezone:    task.DeadlineTimezone,
+		CorrectedAt: time.Now(),
+	}
+	if err := s.publisher.Publish(ctx, correctionsExchange, event); err != nil {
+		log.Warn().Err(err).Str("task_id", task.ID).Str("email_id", task.EmailID).Msg("Failed to publish task correction")
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"collector/internal/database"
+)
+
+// emailProcessed — итог обработки письма analyzer (models.EmailProcessed)
+type emailProcessed struct {
+	UserID  string   `json:"user_id"`
+	EmailID string   `json:"email_id"`
+	Label   string   `json:"label"`
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons"`
+}
+
+// HandleOutcomeMessage сохраняет ярлык классификатора и итог обработки письма, чтобы было видно,
+// почему из письма не появилась задача
+func (s *TaskService) HandleOutcomeMessage(ctx context.Context, body []byte) error {
+	var processed emailProcessed
+	if err := json.Unmarshal(body, &processed); err != nil {
+		return err
+	}
+
+	return s.db.SaveEmailOutcome(ctx, &database.EmailOutcome{
+		UserID:  processed.UserID,
+		EmailID: processed.EmailID,
+		Label:   processed.Label,
+		Status:  processed.Status,
+		Reasons: processed.Reasons,
+	})
+}
+
+func (s *TaskService) GetEmailOutcome(ctx context.Context, userID, emailID string) (*database.EmailOutcome, error) {
+	return s.db.GetEmailOutcome(ctx, userID, emailID)
+}
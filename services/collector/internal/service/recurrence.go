@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"collector/internal/database"
+	"collector/internal/recurrence"
+	"collector/internal/util"
+)
+
+// scheduleNext создаёт следующую задачу серии после выполнения task. Повторения, срок которых уже прошёл,
+// пропускаются: напоминать о них поздно. Повторное выполнение уже закрытой задачи новую не создаёт.
+func (s *TaskService) scheduleNext(ctx context.Context, task *database.Task) error {
+	if task.RRule == nil || task.Deadline == nil || task.Status == database.StatusCompleted {
+		return nil
+	}
+
+	loc, err := time.LoadLocation(task.DeadlineTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	rule, err := recurrence.Parse(*task.RRule, loc)
+	if err != nil {
+		// Правило проверяется при сохранении задачи, сюда попадает только испорченное вручную
+		return nil
+	}
+
+	start := task.Deadline.In(loc)
+	after := start
+	if now := time.Now(); now.After(after) {
+		after = now
+	}
+	deadline, steps, ok := rule.Next(start, after)
+	if !ok {
+		return nil
+	}
+	if rule.Count > 0 {
+		rule.Count -= steps
+	}
+	rrule := rule.String()
+
+	// Повторное выполнение той же задачи параллельно создаст повторение один раз
+	return ignoreExisting(s.db.CreateTask(ctx, &database.Task{
+		ID:               util.GenerateUUID(),
+		UserID:           task.UserID,
+		EmailID:          task.EmailID,
+		Title:            task.Title,
+		Description:      task.Description,
+		Deadline:         &deadline,
+		DeadlineTimezone: task.DeadlineTimezone,
+		Status:           database.StatusPending,
+		Priority:         s.determinePriority(&deadline, task.Importance),
+		PromptVersion:    task.PromptVersion,
+		RRule:            &rrule,
+		Category:         task.Category,
+		Tags:             task.Tags,
+		Importance:       task.Importance,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}))
+}
+
+// validRRule оставляет правило, только если collector умеет по нему считать следующие повторения
+func validRRule(rule, timezone string) *string {
+	if rule == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	if _, err := recurrence.Parse(rule, loc); err != nil {
+		return nil
+	}
+	return &rule
+}
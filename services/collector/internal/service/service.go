@@ -6,30 +6,95 @@ import (
 	"time"
 
 	"collector/internal/database"
+	"collector/internal/priority"
 	"collector/internal/util"
 )
 
+const DefaultTimezone = "UTC"
+
 type TaskService struct {
-	db database.DBer
+	db       database.DBer
+	priority *priority.Scorer
+	// Исправления пользователей уходят в analyzer; nil — не публикуются
+	publisher Publisher
+	// Уверенность, с которой письмо "оплата получена" закрывает задачу без проверки пользователем
+	completionThreshold float64
+}
+
+// NewTaskService создаёт сервис задач; с scorer == nil приоритет считается по весам по умолчанию,
+// с completionThreshold <= 0 — порог автозавершения по умолчанию
+func NewTaskService(db database.DBer, scorer *priority.Scorer, publisher Publisher, completionThreshold float64) *TaskService {
+	if completionThreshold <= 0 {
+		completionThreshold = DefaultCompletionThreshold
+	}
+	return &TaskService{db: db, priority: scorer, publisher: publisher, completionThreshold: completionThreshold}
+}
+
+// parsedEmail — сообщение analyzer из очереди parsed_emails
+type parsedEmail struct {
+	UserID        string    `json:"user_id"`
+	EmailID       string    `json:"email_id"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Deadline      time.Time `json:"deadline"`
+	Timezone      string    `json:"timezone"`
+	PromptVersion string    `json:"prompt_version"`
+	// Заполнены, только если analyzer не уверен в извлечении
+	Confidence    *database.Confidence `json:"confidence"`
+	Status        string               `json:"status"`
+	ReviewReasons []string             `json:"review_reasons"`
+	// Встречи и созвоны приходят отдельно от дедлайна
+	Events []parsedEvent `json:"events"`
+	// Задача из VTODO календарного приглашения
+	CalendarUID string `json:"calendar_uid"`
+	Sequence    int    `json:"sequence"`
+	// Правило повторения RFC 5545: после выполнения задачи создаётся следующая
+	RRule string `json:"rrule"`
+	// Категория из таксономии analyzer и свободные теги
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	// Признаки важности из текста письма; вместе с дедлайном определяют приоритет
+	Importance *database.Importance `json:"importance"`
 }
 
-func NewTaskService(db database.DBer) *TaskService {
-	return &TaskService{db: db}
+type parsedEvent struct {
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Start        time.Time  `json:"start"`
+	End          *time.Time `json:"end"`
+	AllDay       bool       `json:"all_day"`
+	Location     string     `json:"location"`
+	JoinURL      string     `json:"join_url"`
+	Participants []string   `json:"participants"`
+	UID          string     `json:"uid"`
+	Sequence     int        `json:"sequence"`
+	Status       string     `json:"status"`
 }
 
 func (s *TaskService) HandleEmailMessage(ctx context.Context, body []byte) error {
-	var emailData struct {
-		UserID      string    `json:"user_id"`
-		EmailID     string    `json:"email_id"`
-		Title       string    `json:"title"`
-		Description string    `json:"description"`
-		Deadline    time.Time `json:"deadline"`
-	}
+	var emailData parsedEmail
 
 	if err := json.Unmarshal(body, &emailData); err != nil {
 		return err
 	}
 
+	if emailData.Timezone == "" {
+		emailData.Timezone = DefaultTimezone
+	}
+
+	if err := s.saveEvents(ctx, &emailData); err != nil {
+		return err
+	}
+
+	// Письмо-приглашение без задачи: analyzer присылает пустой title
+	if emailData.Title == "" {
+		return nil
+	}
+
+	if emailData.CalendarUID != "" {
+		return s.applyCalendarTask(ctx, &emailData)
+	}
+
 	exists, err := s.db.TaskExists(ctx, emailData.EmailID, emailData.UserID)
 	if err != nil {
 		return err
@@ -38,36 +103,54 @@ func (s *TaskService) HandleEmailMessage(ctx context.Context, body []byte) error
 		return nil
 	}
 
+	// TaskExists не защищает от копии письма, которая обрабатывается параллельно: дубль отсекает уникальный индекс
+	return ignoreExisting(s.db.CreateTask(ctx, s.newTask(&emailData)))
+}
+
+func (s *TaskService) newTask(emailData *parsedEmail) *database.Task {
 	task := &database.Task{
-		ID:          util.GenerateUUID(),
-		UserID:      emailData.UserID,
-		EmailID:     emailData.EmailID,
-		Title:       emailData.Title,
-		Description: emailData.Description,
-		Deadline:    &emailData.Deadline,
-		Status:      "pending",
-		Priority:    s.determinePriority(emailData.Deadline),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:               util.GenerateUUID(),
+		UserID:           emailData.UserID,
+		EmailID:          emailData.EmailID,
+		Title:            emailData.Title,
+		Description:      emailData.Description,
+		DeadlineTimezone: emailData.Timezone,
+		Status:           database.StatusPending,
+		Importance:       emailData.Importance,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	// Письмо без срока: analyzer присылает нулевой дедлайн
+	if !emailData.Deadline.IsZero() {
+		task.Deadline = &emailData.Deadline
+	}
+	task.Priority = s.determinePriority(task.Deadline, task.Importance)
+	// Версию промпта присылают только задачи, извлечённые моделью: их правки пользователя учат analyzer
+	if emailData.PromptVersion != "" {
+		task.PromptVersion = &emailData.PromptVersion
+		task.Extracted = &database.Extraction{Title: task.Title, Deadline: task.Deadline}
+	}
+	task.Confidence = emailData.Confidence
+	if emailData.Status == database.StatusNeedsReview {
+		task.Status = database.StatusNeedsReview
+		task.ReviewReasons = emailData.ReviewReasons
 	}
 
-	return s.db.CreateTask(ctx, task)
+	if emailData.CalendarUID != "" {
+		task.CalendarUID = &emailData.CalendarUID
+		task.CalendarSequence = emailData.Sequence
+	}
+	task.RRule = validRRule(emailData.RRule, emailData.Timezone)
+	if category := normalizeTag(emailData.Category); category != "" {
+		task.Category = &category
+	}
+	task.Tags = normalizeTags(emailData.Tags)
+	return task
 }
 
-func (s *TaskService) determinePriority(deadline time.Time) string {
-	now := time.Now()
-	daysUntil := int(deadline.Sub(now).Hours() / 24)
-
-	switch {
-	case daysUntil <= 1:
-		return "urgent"
-	case daysUntil <= 3:
-		return "high"
-	case daysUntil <= 7:
-		return "medium"
-	default:
-		return "low"
-	}
+// determinePriority сводит близость дедлайна и признаки важности письма в приоритет задачи
+func (s *TaskService) determinePriority(deadline *time.Time, importance *database.Importance) string {
+	return s.priority.Priority(deadline, importance, time.Now())
 }
 
 func (s *TaskService) GetTask(ctx context.Context, taskID, userID string) (*database.Task, error) {
@@ -76,11 +159,98 @@ func (s *TaskService) GetTask(ctx context.Context, taskID, userID string) (*data
 
 func (s *TaskService) GetUserTasks(ctx context.Context, userID string, filter database.TaskFilter) ([]database.Task, error) {
 	filter.UserID = userID
+	if filter.Category != nil {
+		category := normalizeTag(*filter.Category)
+		filter.Category = &category
+	}
+	if len(filter.Tags) > 0 {
+		filter.Tags = normalizeTags(filter.Tags)
+	}
+	return s.db.GetUserTasks(ctx, filter)
+}
+
+// GetReviewQueue возвращает задачи, которые analyzer извлёк неуверенно и которые ждут решения пользователя
+func (s *TaskService) GetReviewQueue(ctx context.Context, userID string, filter database.TaskFilter) ([]database.Task, error) {
+	status := database.StatusNeedsReview
+	filter.UserID = userID
+	filter.Status = &status
 	return s.db.GetUserTasks(ctx, filter)
 }
 
+// ReviewTask применяет решение пользователя: accept и edit переводят задачу в pending, dismiss — в dismissed,
+// complete — в completed. Задача с предложением закрыть её решается так же, но открытую задачу accept
+// и dismiss не трогают: отклоняется только предложение. Любое решение снимает предложение.
+func (s *TaskService) ReviewTask(ctx context.Context, taskID, userID string, review database.ReviewRequest) error {
+	task, err := s.db.GetTask(ctx, taskID, userID)
+	if err != nil {
+		return err
+	}
+	inReview := task.Status == database.StatusNeedsReview
+	suggested := len(task.SuggestedCompletion) > 0 && task.Status != database.StatusCompleted && task.Status != database.StatusDismissed
+	if !inReview && !suggested {
+		return database.ErrTaskNotInReview
+	}
+
+	status := database.StatusPending
+	if !inReview {
+		status = task.Status
+	}
+	update := database.UpdateTaskRequest{Status: &status, ClearSuggestedCompletion: suggested}
+	switch review.Action {
+	case "edit":
+		update.Title = review.Title
+		update.Description = review.Description
+		if review.Deadline != nil {
+			priority := s.determinePriority(review.Deadline, task.Importance)
+			update.Deadline = review.Deadline
+			update.Priority = &priority
+		}
+	case "dismiss":
+		if inReview {
+			status = database.StatusDismissed
+		}
+	case "complete":
+		status = database.StatusCompleted
+	}
+
+	if err := s.db.UpdateTask(ctx, taskID, userID, update); err != nil {
+		return err
+	}
+	s.publishCorrection(ctx, task, update.Title, update.Deadline)
+	if status != database.StatusCompleted {
+		return nil
+	}
+	return s.scheduleNext(ctx, task)
+}
+
 func (s *TaskService) UpdateTask(ctx context.Context, taskID, userID string, update database.UpdateTaskRequest) error {
-	return s.db.UpdateTask(ctx, taskID, userID, update)
+	if update.Category != nil {
+		category := normalizeTag(*update.Category)
+		update.Category = &category
+	}
+	if update.Tags != nil {
+		tags := normalizeTags(*update.Tags)
+		update.Tags = &tags
+	}
+
+	completed := update.Status != nil && *update.Status == database.StatusCompleted
+	corrected := update.Title != nil || update.Deadline != nil
+	if !completed && !corrected {
+		return s.db.UpdateTask(ctx, taskID, userID, update)
+	}
+
+	task, err := s.db.GetTask(ctx, taskID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.UpdateTask(ctx, taskID, userID, update); err != nil {
+		return err
+	}
+	s.publishCorrection(ctx, task, update.Title, update.Deadline)
+	if !completed {
+		return nil
+	}
+	return s.scheduleNext(ctx, task)
 }
 
 func (s *TaskService) DeleteTask(ctx context.Context, taskID, userID string) error {
@@ -88,7 +258,63 @@ func (s *TaskService) DeleteTask(ctx context.Context, taskID, userID string) err
 }
 
 func (s *TaskService) CompleteTask(ctx context.Context, taskID, userID string) error {
-	return s.db.CompleteTask(ctx, taskID, userID)
+	task, err := s.db.GetTask(ctx, taskID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.CompleteTask(ctx, taskID, userID); err != nil {
+		return err
+	}
+	return s.scheduleNext(ctx, task)
+}
+
+// RenderInZone переводит дедлайн в зону пользователя. Явно переданная зона важнее сохранённой.
+func RenderInZone(task *database.Task, zone string) {
+	if task.Deadline == nil {
+		return
+	}
+	if zone == "" {
+		zone = task.DeadlineTimezone
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return
+	}
+	deadline := task.Deadline.In(loc)
+	task.Deadline = &deadline
+}
+
+func (s *TaskService) GetEvent(ctx context.Context, eventID, userID string) (*database.Event, error) {
+	return s.db.GetEvent(ctx, eventID, userID)
+}
+
+func (s *TaskService) GetUserEvents(ctx context.Context, filter database.EventFilter) ([]database.Event, error) {
+	return s.db.GetUserEvents(ctx, filter)
+}
+
+func (s *TaskService) DeleteEvent(ctx context.Context, eventID, userID string) error {
+	return s.db.DeleteEvent(ctx, eventID, userID)
+}
+
+// RenderEventInZone — то же, что RenderInZone, для начала и конца события
+func RenderEventInZone(event *database.Event, zone string) {
+	if zone == "" {
+		zone = event.Timezone
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return
+	}
+	event.Start = event.Start.In(loc)
+	if event.End != nil {
+		end := event.End.In(loc)
+		event.End = &end
+	}
+}
+
+// GetUserTags возвращает теги пользователя для фильтра списка задач
+func (s *TaskService) GetUserTags(ctx context.Context, userID string) ([]database.Tag, error) {
+	return s.db.GetUserTags(ctx, userID)
 }
 
 func (s *TaskService) GetStats(ctx context.Context, userID string) (*database.TaskStats, error) {
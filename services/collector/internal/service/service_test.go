@@ -66,45 +66,133 @@ func (m *mockDB) TaskExists(ctx context.Context, emailID, userID string) (bool,
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockDB) GetOpenTaskByEmailID(ctx context.Context, userID, emailID string) (*database.Task, error) {
+	args := m.Called(ctx, userID, emailID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*database.Task), args.Error(1)
+}
+
+func (m *mockDB) SuggestCompletion(ctx context.Context, taskID, userID string, reasons []string) error {
+	args := m.Called(ctx, taskID, userID, reasons)
+	return args.Error(0)
+}
+
+func (m *mockDB) SaveEmailOutcome(ctx context.Context, outcome *database.EmailOutcome) error {
+	args := m.Called(ctx, outcome)
+	return args.Error(0)
+}
+
+func (m *mockDB) GetEmailOutcome(ctx context.Context, userID, emailID string) (*database.EmailOutcome, error) {
+	args := m.Called(ctx, userID, emailID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*database.EmailOutcome), args.Error(1)
+}
+
+func (m *mockDB) GetUserTags(ctx context.Context, userID string) ([]database.Tag, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]database.Tag), args.Error(1)
+}
+
+func (m *mockDB) CreateEvent(ctx context.Context, event *database.Event) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *mockDB) GetEvent(ctx context.Context, eventID, userID string) (*database.Event, error) {
+	args := m.Called(ctx, eventID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*database.Event), args.Error(1)
+}
+
+func (m *mockDB) GetUserEvents(ctx context.Context, filter database.EventFilter) ([]database.Event, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]database.Event), args.Error(1)
+}
+
+func (m *mockDB) DeleteEvent(ctx context.Context, eventID, userID string) error {
+	args := m.Called(ctx, eventID, userID)
+	return args.Error(0)
+}
+
+func (m *mockDB) EventsExist(ctx context.Context, emailID, userID string) (bool, error) {
+	args := m.Called(ctx, emailID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockDB) GetTaskByCalendarUID(ctx context.Context, userID, uid string) (*database.Task, error) {
+	args := m.Called(ctx, userID, uid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*database.Task), args.Error(1)
+}
+
+func (m *mockDB) UpdateCalendarTask(ctx context.Context, task *database.Task) error {
+	args := m.Called(ctx, task)
+	return args.Error(0)
+}
+
+func (m *mockDB) GetEventByUID(ctx context.Context, userID, uid string) (*database.Event, error) {
+	args := m.Called(ctx, userID, uid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*database.Event), args.Error(1)
+}
+
+func (m *mockDB) UpdateEvent(ctx context.Context, event *database.Event) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
 func TestTaskService_DeterminePriority_Urgent(t *testing.T) {
-	service := NewTaskService(new(mockDB))
+	service := NewTaskService(new(mockDB), nil, nil, 0)
 	
 	deadline := time.Now().Add(12 * time.Hour) // Меньше 1 дня
-	priority := service.determinePriority(deadline)
+	priority := service.determinePriority(&deadline, nil)
 	
 	assert.Equal(t, "urgent", priority)
 }
 
 func TestTaskService_DeterminePriority_High(t *testing.T) {
-	service := NewTaskService(new(mockDB))
+	service := NewTaskService(new(mockDB), nil, nil, 0)
 	
 	deadline := time.Now().Add(2 * 24 * time.Hour) // 2 дня
-	priority := service.determinePriority(deadline)
+	priority := service.determinePriority(&deadline, nil)
 	
 	assert.Equal(t, "high", priority)
 }
 
 func TestTaskService_DeterminePriority_Medium(t *testing.T) {
-	service := NewTaskService(new(mockDB))
+	service := NewTaskService(new(mockDB), nil, nil, 0)
 	
 	deadline := time.Now().Add(5 * 24 * time.Hour) // 5 дней
-	priority := service.determinePriority(deadline)
+	priority := service.determinePriority(&deadline, nil)
 	
 	assert.Equal(t, "medium", priority)
 }
 
 func TestTaskService_DeterminePriority_Low(t *testing.T) {
-	service := NewTaskService(new(mockDB))
+	service := NewTaskService(new(mockDB), nil, nil, 0)
 	
 	deadline := time.Now().Add(10 * 24 * time.Hour) // 10 дней
-	priority := service.determinePriority(deadline)
+	priority := service.determinePriority(&deadline, nil)
 	
 	assert.Equal(t, "low", priority)
 }
 
 func TestTaskService_HandleEmailMessage_TaskExists(t *testing.T) {
 	mockDB := new(mockDB)
-	service := NewTaskService(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
 	
 	emailData := map[string]interface{}{
 		"user_id":     uuid.New().String(),
@@ -126,8 +214,29 @@ func TestTaskService_HandleEmailMessage_TaskExists(t *testing.T) {
 	mockDB.AssertExpectations(t)
 }
 
+func TestTaskService_HandleEmailMessage_DuplicateInsert(t *testing.T) {
+	mockDB := new(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
+
+	emailData := map[string]interface{}{
+		"user_id":  uuid.New().String(),
+		"email_id": uuid.New().String(),
+		"title":    "Test Task",
+	}
+	body, _ := json.Marshal(emailData)
+
+	// Копия письма успела создать задачу между проверкой и вставкой
+	mockDB.On("TaskExists", mock.Anything, emailData["email_id"].(string), emailData["user_id"].(string)).
+		Return(false, nil)
+	mockDB.On("CreateTask", mock.Anything, mock.AnythingOfType("*database.Task")).
+		Return(database.ErrTaskExists)
+
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+	mockDB.AssertExpectations(t)
+}
+
 func TestTaskService_HandleEmailMessage_InvalidJSON(t *testing.T) {
-	service := NewTaskService(new(mockDB))
+	service := NewTaskService(new(mockDB), nil, nil, 0)
 	
 	invalidBody := []byte("invalid json")
 	
@@ -138,7 +247,7 @@ func TestTaskService_HandleEmailMessage_InvalidJSON(t *testing.T) {
 
 func TestTaskService_GetTask(t *testing.T) {
 	mockDB := new(mockDB)
-	service := NewTaskService(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
 	
 	taskID := uuid.New().String()
 	userID := uuid.New().String()
@@ -158,7 +267,7 @@ func TestTaskService_GetTask(t *testing.T) {
 
 func TestTaskService_GetUserTasks(t *testing.T) {
 	mockDB := new(mockDB)
-	service := NewTaskService(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
 	
 	userID := uuid.New().String()
 	filter := database.TaskFilter{
@@ -180,8 +289,740 @@ func TestTaskService_GetUserTasks(t *testing.T) {
 
 func TestNewTaskService(t *testing.T) {
 	mockDB := new(mockDB)
-	service := NewTaskService(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
 	
 	assert.NotNil(t, service)
 	assert.Equal(t, mockDB, service.db)
 }
+
+func TestRenderInZone(t *testing.T) {
+	deadline := time.Date(2025, 12, 11, 7, 0, 0, 0, time.UTC)
+	task := &database.Task{Deadline: &deadline, DeadlineTimezone: "Europe/Moscow"}
+
+	RenderInZone(task, "")
+	assert.Equal(t, "2025-12-11T10:00:00+03:00", task.Deadline.Format(time.RFC3339))
+
+	RenderInZone(task, "Asia/Tokyo")
+	assert.Equal(t, "2025-12-11T16:00:00+09:00", task.Deadline.Format(time.RFC3339))
+
+	RenderInZone(task, "Mars/Olympus")
+	assert.Equal(t, "2025-12-11T16:00:00+09:00", task.Deadline.Format(time.RFC3339))
+
+	noDeadline := &database.Task{}
+	RenderInZone(noDeadline, "UTC")
+	assert.Nil(t, noDeadline.Deadline)
+}
+
+func TestTaskService_HandleEmailMessage_NeedsReview(t *testing.T) {
+	mockDB := new(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
+
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":        userID,
+		"email_id":       emailID,
+		"title":          "Встреча",
+		"deadline":       time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+		"confidence":     map[string]float64{"title": 0.9, "deadline": 0.3},
+		"status":         "needs_review",
+		"review_reasons": []string{"model is unsure about the deadline"},
+	})
+
+	mockDB.On("TaskExists", mock.Anything, emailID, userID).Return(false, nil)
+	mockDB.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.Status == database.StatusNeedsReview &&
+			*task.Confidence == database.Confidence{Title: 0.9, Deadline: 0.3} &&
+			len(task.ReviewReasons) == 1
+	})).Return(nil)
+
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskService_ReviewTask(t *testing.T) {
+	taskID, userID := uuid.New().String(), uuid.New().String()
+	title := "Созвон с командой"
+	deadline := time.Now().Add(10 * 24 * time.Hour)
+
+	tests := []struct {
+		name   string
+		review database.ReviewRequest
+		check  func(update database.UpdateTaskRequest) bool
+	}{
+		{
+			name:   "accept",
+			review: database.ReviewRequest{Action: "accept"},
+			check: func(u database.UpdateTaskRequest) bool {
+				return *u.Status == database.StatusPending && u.Title == nil
+			},
+		},
+		{
+			name:   "edit",
+			review: database.ReviewRequest{Action: "edit", Title: &title, Deadline: &deadline},
+			check: func(u database.UpdateTaskRequest) bool {
+				return *u.Status == database.StatusPending && *u.Title == title && u.Deadline.Equal(deadline) && *u.Priority == "low"
+			},
+		},
+		{
+			name:   "dismiss",
+			review: database.ReviewRequest{Action: "dismiss"},
+			check: func(u database.UpdateTaskRequest) bool {
+				return *u.Status == database.StatusDismissed
+			},
+		},
+		{
+			name:   "complete",
+			review: database.ReviewRequest{Action: "complete"},
+			check: func(u database.UpdateTaskRequest) bool {
+				return *u.Status == database.StatusCompleted
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(mockDB)
+			service := NewTaskService(mockDB, nil, nil, 0)
+			mockDB.On("GetTask", mock.Anything, taskID, userID).
+				Return(&database.Task{ID: taskID, UserID: userID, Status: database.StatusNeedsReview}, nil)
+			mockDB.On("UpdateTask", mock.Anything, taskID, userID, mock.MatchedBy(tt.check)).Return(nil)
+
+			assert.NoError(t, service.ReviewTask(context.Background(), taskID, userID, tt.review))
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTaskService_ReviewTask_SuggestedCompletion(t *testing.T) {
+	taskID, userID := uuid.New().String(), uuid.New().String()
+
+	// Открытая задача остаётся открытой, пока пользователь не подтвердит, что письмо её закрыло
+	tests := map[string]string{
+		"accept":   "in_progress",
+		"dismiss":  "in_progress",
+		"complete": database.StatusCompleted,
+	}
+	for action, status := range tests {
+		t.Run(action, func(t *testing.T) {
+			mockDB := new(mockDB)
+			service := NewTaskService(mockDB, nil, nil, 0)
+			mockDB.On("GetTask", mock.Anything, taskID, userID).Return(&database.Task{
+				ID: taskID, UserID: userID, Status: "in_progress", SuggestedCompletion: []string{"possible completion: оплата получена"},
+			}, nil)
+			mockDB.On("UpdateTask", mock.Anything, taskID, userID, mock.MatchedBy(func(u database.UpdateTaskRequest) bool {
+				return *u.Status == status && u.ClearSuggestedCompletion
+			})).Return(nil)
+
+			assert.NoError(t, service.ReviewTask(context.Background(), taskID, userID, database.ReviewRequest{Action: action}))
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTaskService_ReviewTask_NotInReview(t *testing.T) {
+	mockDB := new(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
+	taskID, userID := uuid.New().String(), uuid.New().String()
+
+	mockDB.On("GetTask", mock.Anything, taskID, userID).
+		Return(&database.Task{ID: taskID, UserID: userID, Status: database.StatusPending}, nil)
+
+	err := service.ReviewTask(context.Background(), taskID, userID, database.ReviewRequest{Action: "accept"})
+
+	assert.ErrorIs(t, err, database.ErrTaskNotInReview)
+	mockDB.AssertNotCalled(t, "UpdateTask")
+}
+
+func TestTaskService_GetReviewQueue(t *testing.T) {
+	mockDB := new(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
+	userID := uuid.New().String()
+
+	mockDB.On("GetUserTasks", mock.Anything, mock.MatchedBy(func(f database.TaskFilter) bool {
+		return f.UserID == userID && *f.Status == database.StatusNeedsReview
+	})).Return([]database.Task{}, nil)
+
+	_, err := service.GetReviewQueue(context.Background(), userID, database.TaskFilter{Limit: 50})
+
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskService_HandleEmailMessage_InvitationCreatesEventOnly(t *testing.T) {
+	mockDB := new(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
+
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":  userID,
+		"email_id": emailID,
+		"title":    "",
+		"timezone": "Europe/Moscow",
+		"events": []map[string]interface{}{{
+			"title":        "Созвон по релизу",
+			"start":        "2025-12-11T10:00:00+03:00",
+			"end":          "2025-12-11T11:00:00+03:00",
+			"join_url":     "https://zoom.us/j/123",
+			"participants": []string{"anna@example.com"},
+		}},
+	})
+
+	mockDB.On("EventsExist", mock.Anything, emailID, userID).Return(false, nil)
+	mockDB.On("CreateEvent", mock.Anything, mock.MatchedBy(func(event *database.Event) bool {
+		return event.Title == "Созвон по релизу" &&
+			event.Timezone == "Europe/Moscow" &&
+			event.Status == database.EventStatusScheduled &&
+			event.End != nil && event.End.Sub(event.Start) == time.Hour &&
+			event.JoinURL == "https://zoom.us/j/123"
+	})).Return(nil)
+
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "TaskExists", mock.Anything, mock.Anything, mock.Anything)
+	mockDB.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything)
+}
+
+func TestTaskService_HandleEmailMessage_EventsAlreadyStored(t *testing.T) {
+	mockDB := new(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
+
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":  userID,
+		"email_id": emailID,
+		"title":    "Подготовить слайды",
+		"deadline": time.Now().Add(48 * time.Hour).Format(time.RFC3339),
+		"events":   []map[string]interface{}{{"title": "Демо", "start": time.Now().Format(time.RFC3339)}},
+	})
+
+	mockDB.On("EventsExist", mock.Anything, emailID, userID).Return(true, nil)
+	mockDB.On("TaskExists", mock.Anything, emailID, userID).Return(false, nil)
+	mockDB.On("CreateTask", mock.Anything, mock.Anything).Return(nil)
+
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "CreateEvent", mock.Anything, mock.Anything)
+}
+
+func TestRenderEventInZone(t *testing.T) {
+	start := time.Date(2025, 12, 11, 7, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	event := &database.Event{Start: start, End: &end, Timezone: "Europe/Moscow"}
+
+	RenderEventInZone(event, "")
+	assert.Equal(t, "2025-12-11T10:00:00+03:00", event.Start.Format(time.RFC3339))
+	assert.Equal(t, "2025-12-11T11:00:00+03:00", event.End.Format(time.RFC3339))
+}
+
+func TestTaskService_HandleEmailMessage_CalendarEventUpdateAndCancel(t *testing.T) {
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	existing := &database.Event{ID: "event-1", UserID: userID, EmailID: "first-email", UID: "sync@google.com", Sequence: 1}
+	message := func(sequence int, status string) []byte {
+		body, _ := json.Marshal(map[string]interface{}{
+			"user_id":  userID,
+			"email_id": emailID,
+			"events": []map[string]interface{}{{
+				"title": "Созвон", "start": "2025-12-11T12:00:00Z",
+				"uid": "sync@google.com", "sequence": sequence, "status": status,
+			}},
+		})
+		return body
+	}
+
+	mockDB := new(mockDB)
+	service := NewTaskService(mockDB, nil, nil, 0)
+	mockDB.On("GetEventByUID", mock.Anything, userID, "sync@google.com").Return(existing, nil)
+	mockDB.On("UpdateEvent", mock.Anything, mock.MatchedBy(func(event *database.Event) bool {
+		return event.ID == "event-1" && event.EmailID == "first-email" && event.Sequence == 2 &&
+			event.Start.Hour() == 12 && event.Status == database.EventStatusScheduled
+	})).Return(nil).Once()
+
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), message(2, "")))
+	// Устаревшее письмо с меньшим SEQUENCE ничего не меняет
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), message(0, "")))
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "EventsExist", mock.Anything, mock.Anything, mock.Anything)
+
+	mockDB.On("UpdateEvent", mock.Anything, mock.MatchedBy(func(event *database.Event) bool {
+		return event.Status == database.EventStatusCancelled && event.Sequence == 3
+	})).Return(nil).Once()
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), message(3, "cancelled")))
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskService_HandleEmailMessage_CalendarTask(t *testing.T) {
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	body := func(status string) []byte {
+		b, _ := json.Marshal(map[string]interface{}{
+			"user_id":      userID,
+			"email_id":     emailID,
+			"title":        "Сдать отчёт",
+			"deadline":     time.Now().Add(72 * time.Hour).Format(time.RFC3339),
+			"calendar_uid": "report@example.com",
+			"sequence":     1,
+			"status":       status,
+		})
+		return b
+	}
+
+	// Новая задача из VTODO создаётся с UID, без проверки по письму
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	db.On("GetTaskByCalendarUID", mock.Anything, userID, "report@example.com").Return(nil, database.ErrTaskNotFound)
+	db.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.CalendarUID != nil && *task.CalendarUID == "report@example.com" && task.CalendarSequence == 1
+	})).Return(nil)
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body("")))
+	db.AssertNotCalled(t, "TaskExists", mock.Anything, mock.Anything, mock.Anything)
+
+	// Отмена неизвестной задачи ничего не создаёт
+	db = new(mockDB)
+	service = NewTaskService(db, nil, nil, 0)
+	db.On("GetTaskByCalendarUID", mock.Anything, userID, "report@example.com").Return(nil, database.ErrTaskNotFound)
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body("cancelled")))
+	db.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything)
+
+	// COMPLETED закрывает существующую задачу
+	db = new(mockDB)
+	service = NewTaskService(db, nil, nil, 0)
+	uid := "report@example.com"
+	db.On("GetTaskByCalendarUID", mock.Anything, userID, uid).
+		Return(&database.Task{ID: "task-1", UserID: userID, Status: database.StatusPending, CalendarUID: &uid}, nil)
+	db.On("UpdateCalendarTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.ID == "task-1" && task.Status == "completed" && task.CalendarSequence == 1
+	})).Return(nil)
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body("completed")))
+	db.AssertExpectations(t)
+}
+
+func TestTaskService_HandleEmailMessage_TwoItemsFromOneEmail(t *testing.T) {
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+
+	// Письмо с двумя счетами: analyzer публикует две задачи с одним email_id и разными UID
+	for _, uid := range []string{"schemaorg:Invoice:email:" + emailID + ":water", "schemaorg:Invoice:email:" + emailID + ":gas"} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"user_id":      userID,
+			"email_id":     emailID,
+			"title":        "Оплатить счёт",
+			"calendar_uid": uid,
+		})
+		db.On("GetTaskByCalendarUID", mock.Anything, userID, uid).Return(nil, database.ErrTaskNotFound).Once()
+		db.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+			return task.EmailID == emailID && *task.CalendarUID == uid
+		})).Return(nil).Once()
+
+		assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+	}
+	db.AssertExpectations(t)
+	db.AssertNotCalled(t, "TaskExists", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTaskService_HandleEmailMessage_CalendarTaskUpdate(t *testing.T) {
+	userID, uid := uuid.New().String(), "invoice-42"
+	deadline := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	rule := "FREQ=MONTHLY;COUNT=3"
+	existing := func() *database.Task {
+		return &database.Task{
+			ID: "task-1", UserID: userID, Title: "Оплатить счёт", Deadline: &deadline, DeadlineTimezone: "UTC",
+			Status: database.StatusPending, CalendarUID: &uid, RRule: &rule,
+		}
+	}
+	body := func(status string, withRule bool) []byte {
+		message := map[string]interface{}{
+			"user_id":      userID,
+			"email_id":     uuid.New().String(),
+			"title":        "Оплатить счёт",
+			"calendar_uid": uid,
+			"sequence":     2,
+			"status":       status,
+		}
+		if withRule {
+			message["rrule"] = rule
+		}
+		b, _ := json.Marshal(message)
+		return b
+	}
+
+	// Письмо "счёт оплачен" без срока не затирает прежний срок
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	db.On("GetTaskByCalendarUID", mock.Anything, userID, uid).Return(existing(), nil)
+	db.On("UpdateCalendarTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.Deadline != nil && task.Deadline.Equal(deadline) && task.Status == database.StatusPending
+	})).Return(nil)
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body("", false)))
+	db.AssertExpectations(t)
+
+	// CANCELLED убирает задачу из списка
+	db = new(mockDB)
+	service = NewTaskService(db, nil, nil, 0)
+	db.On("GetTaskByCalendarUID", mock.Anything, userID, uid).Return(existing(), nil)
+	db.On("UpdateCalendarTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.Status == database.StatusDismissed
+	})).Return(nil)
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body("cancelled", false)))
+	db.AssertExpectations(t)
+
+	// COMPLETED у повторяющейся задачи ставит следующее повторение
+	db = new(mockDB)
+	service = NewTaskService(db, nil, nil, 0)
+	db.On("GetTaskByCalendarUID", mock.Anything, userID, uid).Return(existing(), nil)
+	db.On("UpdateCalendarTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.Status == database.StatusCompleted
+	})).Return(nil)
+	db.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.ID != "task-1" && task.Deadline.Equal(deadline.AddDate(0, 1, 0)) && *task.RRule == "FREQ=MONTHLY;COUNT=2"
+	})).Return(nil)
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body("completed", true)))
+	db.AssertExpectations(t)
+}
+
+func TestTaskService_CompleteTask_SchedulesNextOccurrence(t *testing.T) {
+	taskID, userID := uuid.New().String(), uuid.New().String()
+	deadline := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	rule := "FREQ=WEEKLY;COUNT=3"
+
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	db.On("GetTask", mock.Anything, taskID, userID).Return(&database.Task{
+		ID: taskID, UserID: userID, Title: "Отчёт", Deadline: &deadline,
+		DeadlineTimezone: "UTC", Status: database.StatusPending, RRule: &rule,
+	}, nil)
+	db.On("CompleteTask", mock.Anything, taskID, userID).Return(nil)
+	db.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.ID != taskID && task.Title == "Отчёт" && task.Status == database.StatusPending &&
+			task.Deadline.Equal(deadline.AddDate(0, 0, 7)) && *task.RRule == "FREQ=WEEKLY;COUNT=2"
+	})).Return(nil)
+
+	assert.NoError(t, service.CompleteTask(context.Background(), taskID, userID))
+	db.AssertExpectations(t)
+}
+
+func TestTaskService_CompleteTask_NextOccurrenceAlreadyCreated(t *testing.T) {
+	taskID, userID := uuid.New().String(), uuid.New().String()
+	deadline := time.Now().Add(24 * time.Hour)
+	rule := "FREQ=DAILY"
+
+	// Задачу закрыли дважды одновременно: повторение уже создано первым запросом
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	db.On("GetTask", mock.Anything, taskID, userID).Return(&database.Task{
+		ID: taskID, UserID: userID, Deadline: &deadline, DeadlineTimezone: "UTC", Status: database.StatusPending, RRule: &rule,
+	}, nil)
+	db.On("CompleteTask", mock.Anything, taskID, userID).Return(nil)
+	db.On("CreateTask", mock.Anything, mock.AnythingOfType("*database.Task")).Return(database.ErrTaskExists)
+
+	assert.NoError(t, service.CompleteTask(context.Background(), taskID, userID))
+	db.AssertExpectations(t)
+}
+
+func TestTaskService_CompleteTask_NoNextOccurrence(t *testing.T) {
+	taskID, userID := uuid.New().String(), uuid.New().String()
+	deadline := time.Now().Add(24 * time.Hour)
+	last := "FREQ=WEEKLY;COUNT=1"
+
+	for name, task := range map[string]*database.Task{
+		"one-off":           {ID: taskID, UserID: userID, Deadline: &deadline, Status: database.StatusPending},
+		"last in series":    {ID: taskID, UserID: userID, Deadline: &deadline, Status: database.StatusPending, RRule: &last},
+		"already completed": {ID: taskID, UserID: userID, Deadline: &deadline, Status: "completed", RRule: &last},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := new(mockDB)
+			service := NewTaskService(db, nil, nil, 0)
+			db.On("GetTask", mock.Anything, taskID, userID).Return(task, nil)
+			db.On("CompleteTask", mock.Anything, taskID, userID).Return(nil)
+
+			assert.NoError(t, service.CompleteTask(context.Background(), taskID, userID))
+			db.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestTaskService_HandleEmailMessage_KeepsOnlySupportedRRule(t *testing.T) {
+	for rule, want := range map[string]bool{"FREQ=MONTHLY;BYMONTHDAY=5": true, "FREQ=MONTHLY;BYSETPOS=-1;BYDAY=FR": false} {
+		db := new(mockDB)
+		service := NewTaskService(db, nil, nil, 0)
+		userID, emailID := uuid.New().String(), uuid.New().String()
+		body, _ := json.Marshal(map[string]interface{}{
+			"user_id":  userID,
+			"email_id": emailID,
+			"title":    "Аренда",
+			"deadline": time.Now().Add(72 * time.Hour).Format(time.RFC3339),
+			"rrule":    rule,
+		})
+
+		db.On("TaskExists", mock.Anything, emailID, userID).Return(false, nil)
+		db.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+			return (task.RRule != nil) == want
+		})).Return(nil)
+
+		assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+		db.AssertExpectations(t)
+	}
+}
+
+func TestTaskService_HandleEmailMessage_CategoryAndTags(t *testing.T) {
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":  userID,
+		"email_id": emailID,
+		"title":    "Оплатить отель",
+		"deadline": time.Now().Add(72 * time.Hour).Format(time.RFC3339),
+		"category": "Travel",
+		"tags":     []string{"#Hotel", "hotel", " Business  Trip ", ""},
+	})
+
+	db.On("TaskExists", mock.Anything, emailID, userID).Return(false, nil)
+	db.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return *task.Category == "travel" && assert.ObjectsAreEqual([]string{"hotel", "business trip"}, task.Tags)
+	})).Return(nil)
+
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+	db.AssertExpectations(t)
+}
+
+func TestTaskService_GetUserTasks_NormalizesCategoryAndTags(t *testing.T) {
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	userID := uuid.New().String()
+	category, want := "Finance", "finance"
+
+	db.On("GetUserTasks", mock.Anything, database.TaskFilter{
+		UserID: userID, Category: &want, Tags: []string{"invoice", "q4"},
+	}).Return([]database.Task{}, nil)
+
+	_, err := service.GetUserTasks(context.Background(), userID, database.TaskFilter{
+		Category: &category, Tags: []string{"#Invoice", "Q4", "invoice"},
+	})
+	assert.NoError(t, err)
+	db.AssertExpectations(t)
+}
+
+func TestTaskService_UpdateTask_ClearsTags(t *testing.T) {
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	taskID, userID := uuid.New().String(), uuid.New().String()
+	tags := []string{"", "#"}
+
+	db.On("UpdateTask", mock.Anything, taskID, userID, mock.MatchedBy(func(update database.UpdateTaskRequest) bool {
+		return update.Tags != nil && len(*update.Tags) == 0
+	})).Return(nil)
+
+	assert.NoError(t, service.UpdateTask(context.Background(), taskID, userID, database.UpdateTaskRequest{Tags: &tags}))
+	db.AssertExpectations(t)
+}
+
+func TestTaskService_HandleEmailMessage_UrgentWithoutDeadline(t *testing.T) {
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":    userID,
+		"email_id":   emailID,
+		"title":      "Поднять прод",
+		"deadline":   time.Time{},
+		"importance": map[string]interface{}{"urgent": true, "sender_role": "manager"},
+	})
+
+	db.On("TaskExists", mock.Anything, emailID, userID).Return(false, nil)
+	db.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.Deadline == nil && task.Priority == "urgent" && task.Importance.SenderRole == "manager"
+	})).Return(nil)
+
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+	db.AssertExpectations(t)
+}
+
+// recordingPublisher запоминает события, отправленные в analyzer
+type recordingPublisher struct {
+	exchanges []string
+	messages  []interface{}
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, exchange string, msg interface{}) error {
+	p.exchanges = append(p.exchanges, exchange)
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+func TestTaskService_UpdateTask_PublishesCorrection(t *testing.T) {
+	db := new(mockDB)
+	publisher := &recordingPublisher{}
+	service := NewTaskService(db, nil, publisher, 0)
+	taskID, userID, emailID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	extracted := time.Date(2026, 11, 28, 15, 0, 0, 0, time.UTC)
+	edited := time.Date(2026, 11, 27, 9, 0, 0, 0, time.UTC)
+
+	// Пользователь уже переименовал задачу, а теперь переносит срок: в исправлении оба поля
+	db.On("GetTask", mock.Anything, taskID, userID).Return(&database.Task{
+		ID: taskID, UserID: userID, EmailID: emailID, Title: "Оплатить хостинг", Deadline: &extracted,
+		DeadlineTimezone: "Europe/Moscow", Status: database.StatusPending,
+		Extracted: &database.Extraction{Title: "Счёт за ноябрь", Deadline: &extracted},
+	}, nil)
+	db.On("UpdateTask", mock.Anything, taskID, userID, mock.Anything).Return(nil)
+
+	assert.NoError(t, service.UpdateTask(context.Background(), taskID, userID, database.UpdateTaskRequest{Deadline: &edited}))
+
+	assert.Equal(t, []string{"task_correction"}, publisher.exchanges)
+	event := publisher.messages[0].(taskCorrection)
+	assert.Equal(t, emailID, event.EmailID)
+	assert.Equal(t, "Счёт за ноябрь", event.Original.Title)
+	assert.True(t, event.Original.Deadline.Equal(extracted))
+	assert.Equal(t, "Оплатить хостинг", event.Corrected.Title)
+	assert.True(t, event.Corrected.Deadline.Equal(edited))
+	assert.Equal(t, "Europe/Moscow", event.Timezone)
+}
+
+func TestTaskService_UpdateTask_NoCorrection(t *testing.T) {
+	title := "Счёт за ноябрь"
+	description := "Новое описание"
+	tests := []struct {
+		name   string
+		task   database.Task
+		update database.UpdateTaskRequest
+	}{
+		{"task not from model", database.Task{Title: "Купить молоко"}, database.UpdateTaskRequest{Title: &title}},
+		{"extracted value restored", database.Task{Title: "Оплатить", Extracted: &database.Extraction{Title: title}}, database.UpdateTaskRequest{Title: &title}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockDB)
+			publisher := &recordingPublisher{}
+			service := NewTaskService(db, nil, publisher, 0)
+			taskID, userID := uuid.New().String(), uuid.New().String()
+
+			db.On("GetTask", mock.Anything, taskID, userID).Return(&tt.task, nil)
+			db.On("UpdateTask", mock.Anything, taskID, userID, tt.update).Return(nil)
+
+			assert.NoError(t, service.UpdateTask(context.Background(), taskID, userID, tt.update))
+			assert.Empty(t, publisher.messages)
+		})
+	}
+
+	// Правка без заголовка и срока не читает задачу и ничего не публикует
+	db := new(mockDB)
+	publisher := &recordingPublisher{}
+	service := NewTaskService(db, nil, publisher, 0)
+	db.On("UpdateTask", mock.Anything, "task-1", "user-1", mock.Anything).Return(nil)
+
+	assert.NoError(t, service.UpdateTask(context.Background(), "task-1", "user-1", database.UpdateTaskRequest{Description: &description}))
+	db.AssertNotCalled(t, "GetTask", mock.Anything, mock.Anything, mock.Anything)
+	assert.Empty(t, publisher.messages)
+}
+
+func TestTaskService_HandleEmailMessage_RemembersExtraction(t *testing.T) {
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	deadline := time.Date(2026, 11, 28, 15, 0, 0, 0, time.UTC)
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":        userID,
+		"email_id":       emailID,
+		"title":          "Счёт за ноябрь",
+		"deadline":       deadline,
+		"prompt_version": "v1",
+	})
+
+	db.On("TaskExists", mock.Anything, emailID, userID).Return(false, nil)
+	db.On("CreateTask", mock.Anything, mock.MatchedBy(func(task *database.Task) bool {
+		return task.Extracted != nil && task.Extracted.Title == "Счёт за ноябрь" && task.Extracted.Deadline.Equal(deadline)
+	})).Return(nil)
+
+	assert.NoError(t, service.HandleEmailMessage(context.Background(), body))
+	db.AssertExpectations(t)
+}
+
+func TestTaskService_HandleCompletionMessage(t *testing.T) {
+	userID, taskEmailID := uuid.New().String(), uuid.New().String()
+	task := &database.Task{ID: uuid.New().String(), UserID: userID, EmailID: taskEmailID, Status: database.StatusPending}
+	message := func(confidence float64) []byte {
+		body, _ := json.Marshal(map[string]interface{}{
+			"user_id":       userID,
+			"task_email_id": taskEmailID,
+			"email_id":      uuid.New().String(),
+			"signal":        "payment",
+			"phrase":        "Оплата получена",
+			"confidence":    confidence,
+			"reasons":       []string{"same thread"},
+		})
+		return body
+	}
+
+	t.Run("confident suggestion completes task", func(t *testing.T) {
+		db := new(mockDB)
+		service := NewTaskService(db, nil, nil, 0)
+		db.On("GetOpenTaskByEmailID", mock.Anything, userID, taskEmailID).Return(task, nil)
+		db.On("CompleteTask", mock.Anything, task.ID, userID).Return(nil)
+
+		assert.NoError(t, service.HandleCompletionMessage(context.Background(), message(0.9)))
+		db.AssertExpectations(t)
+		db.AssertNotCalled(t, "SuggestCompletion")
+	})
+
+	t.Run("uncertain suggestion goes to review", func(t *testing.T) {
+		db := new(mockDB)
+		service := NewTaskService(db, nil, nil, 0.95)
+		db.On("GetOpenTaskByEmailID", mock.Anything, userID, taskEmailID).Return(task, nil)
+		db.On("SuggestCompletion", mock.Anything, task.ID, userID, []string{"possible completion: оплата получена", "same thread"}).Return(nil)
+
+		assert.NoError(t, service.HandleCompletionMessage(context.Background(), message(0.9)))
+		db.AssertExpectations(t)
+		db.AssertNotCalled(t, "CompleteTask")
+	})
+
+	t.Run("task from a multi-task email is found by UID", func(t *testing.T) {
+		db := new(mockDB)
+		service := NewTaskService(db, nil, nil, 0)
+		uid := "booking-sochi"
+		body, _ := json.Marshal(map[string]interface{}{
+			"user_id":           userID,
+			"task_email_id":     taskEmailID,
+			"task_calendar_uid": uid,
+			"email_id":          uuid.New().String(),
+			"signal":            "payment",
+			"confidence":        0.9,
+		})
+		db.On("GetTaskByCalendarUID", mock.Anything, userID, uid).Return(task, nil)
+		db.On("CompleteTask", mock.Anything, task.ID, userID).Return(nil)
+
+		assert.NoError(t, service.HandleCompletionMessage(context.Background(), body))
+		db.AssertExpectations(t)
+		db.AssertNotCalled(t, "GetOpenTaskByEmailID")
+	})
+
+	t.Run("closed task is left alone", func(t *testing.T) {
+		db := new(mockDB)
+		service := NewTaskService(db, nil, nil, 0)
+		db.On("GetOpenTaskByEmailID", mock.Anything, userID, taskEmailID).Return(nil, database.ErrTaskNotFound)
+
+		assert.NoError(t, service.HandleCompletionMessage(context.Background(), message(1)))
+		db.AssertNotCalled(t, "CompleteTask")
+		db.AssertNotCalled(t, "SuggestCompletion")
+	})
+}
+
+func TestTaskService_HandleOutcomeMessage(t *testing.T) {
+	db := new(mockDB)
+	service := NewTaskService(db, nil, nil, 0)
+	userID, emailID := uuid.New().String(), uuid.New().String()
+	body, _ := json.Marshal(map[string]interface{}{
+		"user_id":  userID,
+		"email_id": emailID,
+		"label":    "informational",
+		"status":   "skipped",
+		"reasons":  []string{"list-unsubscribe header", "precedence: bulk"},
+	})
+
+	db.On("SaveEmailOutcome", mock.Anything, mock.MatchedBy(func(o *database.EmailOutcome) bool {
+		return o.UserID == userID && o.EmailID == emailID && o.Label == "informational" && o.Status == "skipped" && len(o.Reasons) == 2
+	})).Return(nil)
+
+	assert.NoError(t, service.HandleOutcomeMessage(context.Background(), body))
+	db.AssertExpectations(t)
+
+	assert.Error(t, service.HandleOutcomeMessage(context.Background(), []byte("{")))
+}
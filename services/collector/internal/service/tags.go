@@ -0,0 +1,25 @@
+package service
+
+import "strings"
+
+// normalizeTag приводит тег к виду, в котором он хранится: нижний регистр, без решётки и лишних пробелов
+func normalizeTag(tag string) string {
+	tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
+
+// normalizeTags нормализует теги и убирает повторы. Пустой список остаётся пустым, а не nil:
+// в UpdateTask он означает «снять все теги».
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
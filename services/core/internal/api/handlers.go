@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,11 +13,18 @@ import (
 	"core/internal/security"
 	"core/internal/util"
 	"reminder-hub/pkg/logger"
+	"reminder-hub/pkg/models"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
+// Зона, в которой analyzer разрешает "завтра в 10", если пользователь её не указал
+const defaultTimezone = "UTC"
+
+// Код ISO 639-1, возможно с регионом ("pt-BR"), или "auto" — задачи на языке письма
+var languagePattern = regexp.MustCompile(`^(` + models.LanguageAuto + `|[a-zA-Z]{2}(-[a-zA-Z]{2})?)$`)
+
 type Handler struct {
 	db        database.DBer
 	encryptor security.Encryptor
@@ -128,6 +136,19 @@ func (h *Handler) createIntegrationRecord(ctx context.Context, req *database.Cre
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate ID")
 	}
 
+	timezone := strings.TrimSpace(req.Timezone)
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid timezone")
+	}
+
+	language := strings.TrimSpace(req.Language)
+	if language != "" && !languagePattern.MatchString(language) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid language")
+	}
+
 	integration := &database.EmailIntegration{
 		ID:           integrationID,
 		UserID:       userID,
@@ -136,6 +157,8 @@ func (h *Handler) createIntegrationRecord(ctx context.Context, req *database.Cre
 		ImapPort:     req.ImapPort,
 		UseSSL:       req.UseSSL,
 		Password:     encryptedPassword,
+		Timezone:     timezone,
+		Language:     language,
 	}
 	return integration, nil
 }
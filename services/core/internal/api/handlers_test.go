@@ -121,6 +121,52 @@ func TestCreateIntegrationRecord_Normalizes(t *testing.T) {
 	}
 }
 
+func TestCreateIntegrationRecord_Timezone(t *testing.T) {
+	log := corelogger.Init("test")
+	h := NewHandler(&mockDB{}, &mockEncryptor{}, log)
+	ctx := context.Background()
+
+	req := &database.CreateIntegrationRequest{EmailAddress: "test@example.com", ImapHost: "imap", ImapPort: 993}
+	integration, err := h.createIntegrationRecord(ctx, req, "user-id", "enc")
+	if err != nil {
+		t.Fatalf("createIntegrationRecord error: %v", err)
+	}
+	if integration.Timezone != "UTC" {
+		t.Fatalf("default timezone = %q, want UTC", integration.Timezone)
+	}
+
+	req.Timezone = "Europe/Moscow"
+	integration, err = h.createIntegrationRecord(ctx, req, "user-id", "enc")
+	if err != nil || integration.Timezone != "Europe/Moscow" {
+		t.Fatalf("timezone = %q err=%v", integration.Timezone, err)
+	}
+
+	req.Timezone = "Mars/Olympus"
+	if _, err := h.createIntegrationRecord(ctx, req, "user-id", "enc"); err == nil {
+		t.Fatal("expected error on unknown timezone")
+	}
+}
+
+func TestCreateIntegrationRecord_Language(t *testing.T) {
+	log := corelogger.Init("test")
+	h := NewHandler(&mockDB{}, &mockEncryptor{}, log)
+	ctx := context.Background()
+
+	req := &database.CreateIntegrationRequest{EmailAddress: "test@example.com", ImapHost: "imap", ImapPort: 993}
+	for _, language := range []string{"", "en", "pt-BR", "auto"} {
+		req.Language = language
+		integration, err := h.createIntegrationRecord(ctx, req, "user-id", "enc")
+		if err != nil || integration.Language != language {
+			t.Fatalf("language = %q err=%v, want %q", integration.Language, err, language)
+		}
+	}
+
+	req.Language = "english"
+	if _, err := h.createIntegrationRecord(ctx, req, "user-id", "enc"); err == nil {
+		t.Fatal("expected error on invalid language")
+	}
+}
+
 func TestBindAndValidate_InvalidBody(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
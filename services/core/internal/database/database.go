@@ -35,16 +35,16 @@ func NewDB(url string) (*DB, error) {
 }
 
 func (db *DB) CreateIntegration(ctx context.Context, integration *EmailIntegration) error {
-	query := `INSERT INTO email_integrations (id, user_id, email_address, imap_host, imap_port, use_ssl, password, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())`
+	query := `INSERT INTO email_integrations (id, user_id, email_address, imap_host, imap_port, use_ssl, password, timezone, language, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())`
 	_, err := db.ExecContext(ctx, query,
 		integration.ID, integration.UserID, integration.EmailAddress,
-		integration.ImapHost, integration.ImapPort, integration.UseSSL, integration.Password)
+		integration.ImapHost, integration.ImapPort, integration.UseSSL, integration.Password, integration.Timezone, integration.Language)
 	return err
 }
 
 func (db *DB) GetUserIntegrations(ctx context.Context, userID string) ([]EmailIntegration, error) {
-	query := `SELECT id, user_id, email_address, imap_host, imap_port, use_ssl, created_at, updated_at, last_sync_at
+	query := `SELECT id, user_id, email_address, imap_host, imap_port, use_ssl, timezone, language, created_at, updated_at, last_sync_at
               FROM email_integrations WHERE user_id = $1`
 	rows, err := db.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -56,7 +56,7 @@ func (db *DB) GetUserIntegrations(ctx context.Context, userID string) ([]EmailIn
 	for rows.Next() {
 		var integration EmailIntegration
 		err := rows.Scan(&integration.ID, &integration.UserID, &integration.EmailAddress,
-			&integration.ImapHost, &integration.ImapPort, &integration.UseSSL,
+			&integration.ImapHost, &integration.ImapPort, &integration.UseSSL, &integration.Timezone, &integration.Language,
 			&integration.CreatedAt, &integration.UpdatedAt, &integration.LastSyncAt)
 		if err != nil {
 			return nil, err
@@ -86,7 +86,7 @@ func (db *DB) DeleteIntegration(ctx context.Context, userID, integrationID strin
 }
 
 func (db *DB) GetIntegrationsForSync(ctx context.Context, limit int) ([]EmailIntegration, error) {
-	query := `SELECT id, user_id, email_address, imap_host, imap_port, use_ssl, password, last_sync_at
+	query := `SELECT id, user_id, email_address, imap_host, imap_port, use_ssl, password, timezone, language, last_sync_at
               FROM email_integrations
               ORDER BY last_sync_at ASC NULLS FIRST
               LIMIT $1`
@@ -101,7 +101,7 @@ func (db *DB) GetIntegrationsForSync(ctx context.Context, limit int) ([]EmailInt
 		var integration EmailIntegration
 		err := rows.Scan(&integration.ID, &integration.UserID, &integration.EmailAddress,
 			&integration.ImapHost, &integration.ImapPort, &integration.UseSSL,
-			&integration.Password, &integration.LastSyncAt)
+			&integration.Password, &integration.Timezone, &integration.Language, &integration.LastSyncAt)
 		if err != nil {
 			return nil, err
 		}
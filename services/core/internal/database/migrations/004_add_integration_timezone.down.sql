@@ -0,0 +1 @@
+ALTER TABLE email_integrations DROP COLUMN IF EXISTS timezone;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE email_integrations ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
\ No newline at end of file
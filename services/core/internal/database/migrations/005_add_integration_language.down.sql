@@ -0,0 +1 @@
+ALTER TABLE email_integrations DROP COLUMN IF EXISTS language;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE email_integrations ADD COLUMN language VARCHAR(16) NOT NULL DEFAULT '';
\ No newline at end of file
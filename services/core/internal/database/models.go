@@ -5,16 +5,19 @@ import (
 )
 
 type EmailIntegration struct {
-	ID           string     `json:"id" validate:"required,uuid"`
-	UserID       string     `json:"user_id" validate:"required,uuid"`
-	EmailAddress string     `json:"email_address" validate:"required,email"`
-	ImapHost     string     `json:"imap_host" validate:"required,hostname"`
-	ImapPort     int        `json:"imap_port" validate:"required,min=1,max=65535"`
-	UseSSL       bool       `json:"use_ssl"`
-	Password     string     `json:"-" validate:"required,min=1"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
-	LastSyncAt   *time.Time `json:"last_sync_at,omitempty"`
+	ID           string `json:"id" validate:"required,uuid"`
+	UserID       string `json:"user_id" validate:"required,uuid"`
+	EmailAddress string `json:"email_address" validate:"required,email"`
+	ImapHost     string `json:"imap_host" validate:"required,hostname"`
+	ImapPort     int    `json:"imap_port" validate:"required,min=1,max=65535"`
+	UseSSL       bool   `json:"use_ssl"`
+	Password     string `json:"-" validate:"required,min=1"`
+	Timezone     string `json:"timezone"`
+	// Язык задач (ISO 639-1) или "auto" — язык письма; пусто — язык analyzer по умолчанию
+	Language   string     `json:"language,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
 }
 
 type EmailRaw struct {
@@ -35,6 +38,8 @@ type CreateIntegrationRequest struct {
 	ImapPort     int    `json:"imap_port" validate:"required,min=1,max=65535"`
 	UseSSL       bool   `json:"use_ssl"`
 	Password     string `json:"password" validate:"required,min=1"`
+	Timezone     string `json:"timezone,omitempty"`
+	Language     string `json:"language,omitempty"`
 }
 
 type CreateIntegrationResponse struct {
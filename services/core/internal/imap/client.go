@@ -3,11 +3,14 @@ package imap
 import (
 	"crypto/tls"
 	"fmt"
+	"io"
+	"net/mail"
 	"strings"
 	"time"
 
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-message"
 	"github.com/emersion/go-message/charset"
 )
 
@@ -15,11 +18,38 @@ func init() { imap.CharsetReader = charset.Reader }
 
 type Client struct{ client *client.Client }
 
+// Заголовки, по которым analyzer отличает рассылки и автоматические письма от писем с задачами,
+// а по In-Reply-To и References связывает ответ с письмом, из которого извлечена задача
+var classificationHeaders = []string{
+	"List-Unsubscribe",
+	"List-Id",
+	"Precedence",
+	"Auto-Submitted",
+	"X-Auto-Response-Suppress",
+	"X-Spam-Flag",
+	"X-Spam-Status",
+	"Reply-To",
+	"In-Reply-To",
+	"References",
+}
+
+// Ограничения на календарные части: приглашение занимает единицы килобайт, больше — не приглашение
+const (
+	maxCalendarParts = 5
+	maxCalendarSize  = 256 << 10
+	// HTML больше этого размера — обычно рассылка с картинками, а не письмо с разметкой
+	maxHTMLSize = 512 << 10
+)
+
 type EmailMessage struct {
 	MessageID string
 	From      string
 	Subject   string
 	BodyText  string
+	BodyHTML  string
+	Headers   map[string]string
+	// Части text/calendar и вложения .ics как есть
+	Calendars []string
 	Date      time.Time
 	Message   *imap.Message
 }
@@ -106,7 +136,12 @@ func parseMessage(msg *imap.Message) (*EmailMessage, error) {
 	}
 
 	if msg.Body != nil {
-		em.BodyText, _ = extractTextBody(msg)
+		// Литералы читаются один раз, поэтому сначала забираем их целиком
+		literals := readLiterals(msg)
+		em.BodyText, _ = extractTextBody(literals)
+		em.Headers = extractHeaders(literals)
+		em.Calendars = extractCalendars(literals)
+		em.BodyHTML = extractHTMLBody(literals)
 	}
 
 	return em, nil
@@ -135,15 +170,21 @@ func formatAddress(addrs []*imap.Address) string {
 	return strings.Join(parts, ", ")
 }
 
-func extractTextBody(msg *imap.Message) (string, error) {
+func readLiterals(msg *imap.Message) []string {
+	var literals []string
 	for _, lit := range msg.Body {
 		buf := make([]byte, lit.Len())
-		n, _ := lit.Read(buf)
+		n, _ := io.ReadFull(lit, buf)
 		if n == 0 {
 			continue
 		}
+		literals = append(literals, string(buf[:n]))
+	}
+	return literals
+}
 
-		content := string(buf[:n])
+func extractTextBody(literals []string) (string, error) {
+	for _, content := range literals {
 		if strings.Contains(strings.ToLower(content), "content-type: text/plain") {
 			parts := strings.SplitN(content, "\r\n\r\n", 2)
 			if len(parts) > 1 {
@@ -153,3 +194,96 @@ func extractTextBody(msg *imap.Message) (string, error) {
 	}
 	return "", fmt.Errorf("no text body")
 }
+
+// extractHeaders достаёт из полного письма только заголовки из classificationHeaders
+func extractHeaders(literals []string) map[string]string {
+	for _, content := range literals {
+		parsed, err := mail.ReadMessage(strings.NewReader(content))
+		if err != nil {
+			continue
+		}
+
+		headers := make(map[string]string)
+		for _, name := range classificationHeaders {
+			if value := parsed.Header.Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if len(headers) > 0 {
+			return headers
+		}
+	}
+	return nil
+}
+
+// extractCalendars достаёт из полного письма части text/calendar и вложенные .ics файлы
+func extractCalendars(literals []string) []string {
+	var calendars []string
+	for _, content := range literals {
+		entity, err := message.Read(strings.NewReader(content))
+		if err != nil && !message.IsUnknownCharset(err) {
+			continue
+		}
+
+		_ = entity.Walk(func(_ []int, part *message.Entity, err error) error {
+			if err != nil || len(calendars) >= maxCalendarParts || !isCalendarPart(part.Header) {
+				return nil
+			}
+			body, err := io.ReadAll(io.LimitReader(part.Body, maxCalendarSize+1))
+			if err != nil || len(body) == 0 || len(body) > maxCalendarSize {
+				return nil
+			}
+			calendars = append(calendars, string(body))
+			return nil
+		})
+		if len(calendars) > 0 {
+			return calendars
+		}
+	}
+	return nil
+}
+
+func isCalendarPart(header message.Header) bool {
+	mediaType, params, _ := header.ContentType()
+	if mediaType == "text/calendar" || mediaType == "application/ics" {
+		return true
+	}
+	_, dispParams, _ := header.ContentDisposition()
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	return strings.HasSuffix(strings.ToLower(filename), ".ics")
+}
+
+// extractHTMLBody возвращает первую HTML-часть письма, не являющуюся вложением, уже декодированной
+func extractHTMLBody(literals []string) string {
+	for _, content := range literals {
+		entity, err := message.Read(strings.NewReader(content))
+		if err != nil && !message.IsUnknownCharset(err) {
+			continue
+		}
+
+		var body string
+		_ = entity.Walk(func(_ []int, part *message.Entity, err error) error {
+			if err != nil || body != "" {
+				return nil
+			}
+			mediaType, _, _ := part.Header.ContentType()
+			disposition, _, _ := part.Header.ContentDisposition()
+			if mediaType != "text/html" || disposition == "attachment" {
+				return nil
+			}
+			data, err := io.ReadAll(io.LimitReader(part.Body, maxHTMLSize+1))
+			if err != nil || len(data) > maxHTMLSize {
+				return nil
+			}
+			body = string(data)
+			return nil
+		})
+		if body != "" {
+			return body
+		}
+	}
+	return ""
+}
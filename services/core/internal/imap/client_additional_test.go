@@ -104,3 +104,98 @@ func TestParseMessage_WithInternalDate(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, internalDate, emailMsg.Date)
 }
+
+func TestExtractHeaders_KeepsClassificationHeaders(t *testing.T) {
+	raw := "From: shop@example.com\r\n" +
+		"Subject: Sale\r\n" +
+		"List-Unsubscribe: <mailto:unsubscribe@example.com>\r\n" +
+		"Precedence: bulk\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Скидки до 50%"
+
+	headers := extractHeaders([]string{raw})
+
+	assert.Equal(t, map[string]string{
+		"List-Unsubscribe": "<mailto:unsubscribe@example.com>",
+		"Precedence":       "bulk",
+	}, headers)
+}
+
+func TestExtractHeaders_NoMessage(t *testing.T) {
+	assert.Nil(t, extractHeaders([]string{"not a message"}))
+	assert.Nil(t, extractHeaders(nil))
+}
+
+func TestExtractCalendars_InlinePartAndAttachment(t *testing.T) {
+	raw := "From: boss@example.com\r\n" +
+		"Subject: Invitation\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Приглашаем на встречу\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/calendar; method=REQUEST; charset=utf-8\r\n" +
+		"\r\n" +
+		"BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nEND:VCALENDAR\r\n" +
+		"--b1\r\n" +
+		"Content-Type: application/octet-stream; name=\"invite.ics\"\r\n" +
+		"Content-Disposition: attachment; filename=\"invite.ics\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"QkVHSU46VkNBTEVOREFSDQpFTkQ6VkNBTEVOREFSDQo=\r\n" +
+		"--b1--\r\n"
+
+	calendars := extractCalendars([]string{raw})
+
+	assert.Equal(t, []string{
+		"BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+	}, calendars)
+}
+
+func TestExtractCalendars_PlainMessage(t *testing.T) {
+	raw := "From: a@example.com\r\nContent-Type: text/plain\r\n\r\nhello"
+	assert.Nil(t, extractCalendars([]string{raw}))
+}
+
+func TestExtractHTMLBody_AlternativePart(t *testing.T) {
+	raw := "From: booking@example.com\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Ваше бронирование\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"<p class=3D\"note\">Ваше бронирование</p>\r\n" +
+		"--b1--\r\n"
+
+	assert.Equal(t, "<p class=\"note\">Ваше бронирование</p>", extractHTMLBody([]string{raw}))
+}
+
+func TestExtractHTMLBody_SkipsAttachment(t *testing.T) {
+	raw := "From: a@example.com\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"hello\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/html\r\n" +
+		"Content-Disposition: attachment; filename=\"report.html\"\r\n" +
+		"\r\n" +
+		"<p>report</p>\r\n" +
+		"--b1--\r\n"
+
+	assert.Empty(t, extractHTMLBody([]string{raw}))
+}
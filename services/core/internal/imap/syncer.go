@@ -14,6 +14,10 @@ import (
 
 const maxBatchSize = 7
 
+// Столько новых писем за одну синхронизацию считается догонянием, а не живой почтой:
+// например, ящик долго не синхронизировался из-за неверного пароля
+const backfillThreshold = 10 * maxBatchSize
+
 type Syncer struct {
 	db        *database.DB
 	rabbit    *rabbitmq.Producer
@@ -71,6 +75,12 @@ func (s *Syncer) SyncIntegration(integration *database.EmailIntegration) error {
 		return nil
 	}
 
+	// Первая синхронизация забирает весь ящик, и analyzer обрабатывает её с низким приоритетом
+	source := models.SourceLive
+	if integration.LastSyncAt == nil || len(msgs) > backfillThreshold {
+		source = models.SourceBackfill
+	}
+
 	var currentBatch *models.RawEmails
 	var processed int
 
@@ -82,7 +92,7 @@ func (s *Syncer) SyncIntegration(integration *database.EmailIntegration) error {
 		}
 		if rabbitMsg != nil {
 			if currentBatch == nil {
-				currentBatch = &models.RawEmails{}
+				currentBatch = &models.RawEmails{Source: source}
 			}
 			currentBatch.RawEmail = append(currentBatch.RawEmail, *rabbitMsg)
 			processed++
@@ -108,7 +118,7 @@ func (s *Syncer) SyncIntegration(integration *database.EmailIntegration) error {
 		return errUpdateLastSync(integration.ID, err)
 	}
 
-	s.log.Info(ctx, "Sync done", "processed", processed)
+	s.log.Info(ctx, "Sync done", "processed", processed, "source", source)
 	return nil
 }
 
@@ -151,6 +161,11 @@ func (s *Syncer) processMessage(ctx context.Context, integration *database.Email
 		Text:      email.BodyText,
 		Date:      email.DateReceived.Format(time.RFC3339),
 		TimeStamp: time.Now().Format(time.RFC3339),
+		Timezone:  integration.Timezone,
+		Language:  integration.Language,
+		Headers:   msg.Headers,
+		Calendars: msg.Calendars,
+		HTML:      msg.BodyHTML,
 	}
 
 	s.log.Info(ctx, "Email processed", "email_id", emailID, "from", msg.From)
@@ -63,6 +63,11 @@ func (p *Producer) PublishEmailBatch(messages *models.RawEmails) error {
 			Text:      msg.Text,
 			Date:      dateReceived,
 			TimeStamp: syncTimestamp,
+			Timezone:  msg.Timezone,
+			Language:  msg.Language,
+			Headers:   msg.Headers,
+			Calendars: msg.Calendars,
+			HTML:      msg.HTML,
 		}
 		rawEmails = append(rawEmails, rawEmail)
 	}
@@ -70,6 +75,7 @@ func (p *Producer) PublishEmailBatch(messages *models.RawEmails) error {
 	// Публикуем в формате RawEmails, который ожидает analyzer-service
 	rawEmailsMessage := &models.RawEmails{
 		RawEmail: rawEmails,
+		Source:   messages.Source,
 	}
 
 	return p.publisher.PublishMessage(rawEmailsMessage)
@@ -152,3 +152,27 @@ func TestNewProducerWithConn(t *testing.T) {
 
 	assert.NotNil(t, NewProducerWithConn)
 }
+
+func TestProducer_PublishEmailBatch_KeepsSource(t *testing.T) {
+	mockPub := new(mockPublisher)
+	producer := &Producer{
+		publisher: mockPub,
+	}
+
+	batch := &models.RawEmails{
+		Source: models.SourceBackfill,
+		RawEmail: []models.RawEmail{
+			{EmailID: "email-1", UserID: "user-1", MessageID: "msg-1"},
+		},
+	}
+
+	mockPub.On("PublishMessage", mock.AnythingOfType("*models.RawEmails")).Return(nil).Run(func(args mock.Arguments) {
+		msg := args.Get(0).(*models.RawEmails)
+		assert.Equal(t, models.SourceBackfill, msg.Source)
+	})
+
+	err := producer.PublishEmailBatch(batch)
+
+	assert.NoError(t, err)
+	mockPub.AssertExpectations(t)
+}